@@ -0,0 +1,279 @@
+package analytics
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Parameters of the centralities
+var (
+	Damping            = 0.85
+	Tolerance          = 1e-10 // L1 change under which the iterations stop
+	MaxIterations      = 100
+	BetweennessSamples = 200 // sources of the betweenness approximation
+	BetweennessSeed    = int64(1)
+)
+
+// Graph is the citation graph with its articles numbered as they are read
+type Graph struct {
+	UIDs     []string
+	ArXivIDs []string
+	Out      [][]int // cited papers
+	In       [][]int // citing articles
+	index    map[string]int
+}
+
+// node returns the number of an article, adding it when it is new
+func (g *Graph) node(uid, arXivID string) int {
+	i, ok := g.index[uid]
+	if !ok {
+		i = len(g.UIDs)
+		g.index[uid] = i
+		g.UIDs = append(g.UIDs, uid)
+		g.ArXivIDs = append(g.ArXivIDs, arXivID)
+		g.Out = append(g.Out, nil)
+		g.In = append(g.In, nil)
+	}
+	if g.ArXivIDs[i] == "" {
+		g.ArXivIDs[i] = arXivID
+	}
+	return i
+}
+
+// LoadGraph reads the citedpapers edges from source page by page
+func LoadGraph(source Source) (*Graph, error) {
+	g := &Graph{index: make(map[string]int)}
+	after := "0x0"
+	for {
+		articles, err := source.ArticlesAfter(after, PageSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(articles) == 0 {
+			break
+		}
+		for _, a := range articles {
+			i := g.node(a.UID, a.ArXivID)
+			seen := make(map[int]bool)
+			for _, cited := range a.CitedPapers {
+				j := g.node(cited.UID, cited.ArXivID)
+				if i == j || seen[j] {
+					continue
+				}
+				seen[j] = true
+				g.Out[i] = append(g.Out[i], j)
+				g.In[j] = append(g.In[j], i)
+			}
+		}
+		after = articles[len(articles)-1].UID
+	}
+	return g, nil
+}
+
+// PageRank returns the PageRank of the articles, summing to 1. The rank of
+// the articles citing nothing is spread over the whole graph.
+func PageRank(g *Graph) []float64 {
+	n := len(g.UIDs)
+	if n == 0 {
+		return nil
+	}
+	rank := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+	next := make([]float64, n)
+	for iteration := 0; iteration < MaxIterations; iteration++ {
+		dangling := 0.0
+		for i, out := range g.Out {
+			if len(out) == 0 {
+				dangling += rank[i]
+			}
+		}
+		base := (1-Damping)/float64(n) + Damping*dangling/float64(n)
+		for i := range next {
+			next[i] = base
+		}
+		for i, out := range g.Out {
+			share := Damping * rank[i] / float64(len(out))
+			for _, j := range out {
+				next[j] += share
+			}
+		}
+		change := 0.0
+		for i := range rank {
+			change += math.Abs(next[i] - rank[i])
+		}
+		rank, next = next, rank
+		if change < Tolerance {
+			break
+		}
+	}
+	return rank
+}
+
+// HITS returns the hub and authority scores of the articles, of unit
+// length. Good hubs cite good authorities.
+func HITS(g *Graph) (hubs, authorities []float64) {
+	n := len(g.UIDs)
+	hubs = make([]float64, n)
+	authorities = make([]float64, n)
+	for i := range hubs {
+		hubs[i] = 1
+	}
+	normalize(hubs)
+	next := make([]float64, n)
+	for iteration := 0; iteration < MaxIterations; iteration++ {
+		for i, in := range g.In {
+			authorities[i] = 0
+			for _, j := range in {
+				authorities[i] += hubs[j]
+			}
+		}
+		normalize(authorities)
+		for i, out := range g.Out {
+			next[i] = 0
+			for _, j := range out {
+				next[i] += authorities[j]
+			}
+		}
+		normalize(next)
+		change := 0.0
+		for i := range hubs {
+			change += math.Abs(next[i] - hubs[i])
+		}
+		hubs, next = next, hubs
+		if change < Tolerance {
+			break
+		}
+	}
+	return hubs, authorities
+}
+
+// normalize scales v to unit length
+func normalize(v []float64) {
+	norm := 0.0
+	for _, x := range v {
+		norm += x * x
+	}
+	norm = math.Sqrt(norm)
+	if norm > 0 {
+		for i := range v {
+			v[i] /= norm
+		}
+	}
+}
+
+// Betweenness approximates the betweenness centrality of the articles with
+// the shortest citation paths from BetweennessSamples random sources,
+// scaled to the whole graph (Brandes' algorithm)
+func Betweenness(g *Graph) []float64 {
+	n := len(g.UIDs)
+	centrality := make([]float64, n)
+	sources := rand.New(rand.NewSource(BetweennessSeed)).Perm(n)
+	if BetweennessSamples < n {
+		sources = sources[:BetweennessSamples]
+	}
+	if len(sources) == 0 {
+		return centrality
+	}
+
+	sigma := make([]float64, n)
+	distance := make([]int, n)
+	delta := make([]float64, n)
+	predecessors := make([][]int, n)
+	for _, s := range sources {
+		for i := range sigma {
+			sigma[i], distance[i], delta[i] = 0, -1, 0
+			predecessors[i] = predecessors[i][:0]
+		}
+		sigma[s], distance[s] = 1, 0
+		queue := []int{s}
+		var stack []int
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			stack = append(stack, v)
+			for _, w := range g.Out[v] {
+				if distance[w] < 0 {
+					distance[w] = distance[v] + 1
+					queue = append(queue, w)
+				}
+				if distance[w] == distance[v]+1 {
+					sigma[w] += sigma[v]
+					predecessors[w] = append(predecessors[w], v)
+				}
+			}
+		}
+		for k := len(stack) - 1; k >= 0; k-- {
+			w := stack[k]
+			for _, v := range predecessors[w] {
+				delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+			}
+			if w != s {
+				centrality[w] += delta[w]
+			}
+		}
+	}
+	scale := float64(n) / float64(len(sources))
+	for i := range centrality {
+		centrality[i] *= scale
+	}
+	return centrality
+}
+
+// Centrality holds the centralities of the articles of a graph
+type Centrality struct {
+	Graph       *Graph
+	PageRank    []float64
+	Hubs        []float64
+	Authorities []float64
+	Betweenness []float64
+}
+
+// ComputeCentrality computes every centrality of g
+func ComputeCentrality(g *Graph) *Centrality {
+	c := &Centrality{Graph: g, PageRank: PageRank(g), Betweenness: Betweenness(g)}
+	c.Hubs, c.Authorities = HITS(g)
+	return c
+}
+
+// centralityValues are the predicates written back. The PageRank is
+// multiplied by the number of articles, so that the mean is 1 whatever the
+// size of the graph.
+type centralityValues struct {
+	UID            string  `json:"uid"`
+	PageRank       float64 `json:"pagerank"`
+	HubScore       float64 `json:"hubscore"`
+	AuthorityScore float64 `json:"authorityscore"`
+	Betweenness    float64 `json:"betweenness"`
+}
+
+// Values returns the predicates to write, in the order of the graph
+func (c *Centrality) Values() []interface{} {
+	n := float64(len(c.Graph.UIDs))
+	values := make([]interface{}, len(c.Graph.UIDs))
+	for i, uid := range c.Graph.UIDs {
+		values[i] = centralityValues{
+			UID:            uid,
+			PageRank:       c.PageRank[i] * n,
+			HubScore:       c.Hubs[i],
+			AuthorityScore: c.Authorities[i],
+			Betweenness:    c.Betweenness[i],
+		}
+	}
+	return values
+}
+
+// Top returns the numbers of the n articles with the highest scores
+func Top(scores []float64, n int) []int {
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+	if len(order) > n {
+		order = order[:n]
+	}
+	return order
+}
@@ -0,0 +1,85 @@
+package analytics
+
+import (
+	"log"
+	"math"
+	"testing"
+
+	"pandor/models"
+)
+
+// citationGraph is a -> c, b -> c, c -> d and d -> a stub without arXiv ID
+func citationGraph() *Graph {
+	source := &pagedSource{articles: []models.Article{
+		{UID: "0x1", ArXivID: "a", CitedPapers: []models.Article{{UID: "0x3"}}},
+		{UID: "0x2", ArXivID: "b", CitedPapers: []models.Article{{UID: "0x3"}, {UID: "0x3"}, {UID: "0x2"}}},
+		{UID: "0x3", ArXivID: "c", CitedPapers: []models.Article{{UID: "0x4", ArXivID: "d"}}},
+	}}
+	g, err := LoadGraph(source)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return g
+}
+
+// Nodes are numbered as they are read, c is read before b
+const (
+	a = iota
+	c
+	b
+	d
+)
+
+func TestLoadGraph(t *testing.T) {
+	g := citationGraph()
+	if len(g.UIDs) != 4 || g.ArXivIDs[d] != "d" || g.ArXivIDs[c] != "c" {
+		log.Fatalf("Wrong nodes %v %v", g.UIDs, g.ArXivIDs)
+	}
+	// Duplicate citations and self-citations are dropped
+	if len(g.Out[b]) != 1 || len(g.In[c]) != 2 || len(g.In[b]) != 0 {
+		log.Fatalf("Wrong edges %v", g.Out)
+	}
+}
+
+func TestPageRank(t *testing.T) {
+	g := citationGraph()
+	rank := PageRank(g)
+	sum := 0.0
+	for _, r := range rank {
+		sum += r
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		log.Fatalf("PageRank should sum to 1, got %f", sum)
+	}
+	if top := Top(rank, 2); !(top[0] == d && top[1] == c) || rank[a] != rank[b] {
+		log.Fatalf("Wrong PageRank %v", rank)
+	}
+}
+
+func TestHITS(t *testing.T) {
+	hubs, authorities := HITS(citationGraph())
+	if Top(authorities, 1)[0] != c || authorities[a] != 0 {
+		log.Fatalf("c should be the best authority %v", authorities)
+	}
+	if hubs[a] != hubs[b] || hubs[a] <= hubs[c] || hubs[d] != 0 {
+		log.Fatalf("a and b should be the best hubs %v", hubs)
+	}
+}
+
+func TestBetweenness(t *testing.T) {
+	// c lies on the paths a -> d and b -> d
+	betweenness := Betweenness(citationGraph())
+	for i, expected := range []float64{a: 0, b: 0, c: 2, d: 0} {
+		if math.Abs(betweenness[i]-expected) > 1e-9 {
+			log.Fatalf("Wrong betweenness %v", betweenness)
+		}
+	}
+
+	// Two samples out of four are scaled by two
+	BetweennessSamples = 2
+	defer func() { BetweennessSamples = 200 }()
+	sampled := Betweenness(citationGraph())
+	if sampled[c] != 0 && sampled[c] != 2 && sampled[c] != 4 {
+		log.Fatalf("Wrong sampled betweenness %v", sampled)
+	}
+}
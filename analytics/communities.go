@@ -0,0 +1,353 @@
+package analytics
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"pandor/models"
+)
+
+// Community detection methods
+const (
+	Louvain          = "louvain"
+	LabelPropagation = "labels"
+)
+
+// CommunityMethods lists the methods accepted by DetectCommunities
+var CommunityMethods = []string{Louvain, LabelPropagation}
+
+// MinCommunitySize is the size under which communities are not stored,
+// authors who never wrote with anyone else are communities of one
+var MinCommunitySize = 2
+
+// CommunitySeed makes the label propagation reproducible
+var CommunitySeed = int64(1)
+
+// CoauthorGraph is the collaboration graph of the authors, numbered as they
+// are read. Its edges are weighted by the number of shared articles and
+// listed in both directions, in increasing order.
+type CoauthorGraph struct {
+	UIDs      []string
+	Names     []string
+	Articles  []int // number of articles of each author
+	Neighbors [][]int
+	Weights   [][]float64
+}
+
+// LoadCoauthorGraph reads the authors of the articles from source page by
+// page
+func LoadCoauthorGraph(source Source) (*CoauthorGraph, error) {
+	g := &CoauthorGraph{}
+	index := make(map[string]int)
+	shared := make(map[[2]int]float64)
+	after := "0x0"
+	for {
+		articles, err := source.ArticlesAfter(after, PageSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(articles) == 0 {
+			break
+		}
+		for _, a := range articles {
+			var authors []int
+			seen := make(map[int]bool)
+			for _, author := range a.Authors {
+				i, ok := index[author.UID]
+				if !ok {
+					i = len(g.UIDs)
+					index[author.UID] = i
+					g.UIDs = append(g.UIDs, author.UID)
+					g.Names = append(g.Names, author.Name)
+					g.Articles = append(g.Articles, 0)
+				}
+				if !seen[i] {
+					seen[i] = true
+					g.Articles[i]++
+					authors = append(authors, i)
+				}
+			}
+			for x := range authors {
+				for y := x + 1; y < len(authors); y++ {
+					i, j := authors[x], authors[y]
+					shared[[2]int{i, j}]++
+					shared[[2]int{j, i}]++
+				}
+			}
+		}
+		after = articles[len(articles)-1].UID
+	}
+
+	g.Neighbors = make([][]int, len(g.UIDs))
+	g.Weights = make([][]float64, len(g.UIDs))
+	for pair := range shared {
+		g.Neighbors[pair[0]] = append(g.Neighbors[pair[0]], pair[1])
+	}
+	for i, neighbors := range g.Neighbors {
+		sort.Ints(neighbors)
+		g.Weights[i] = make([]float64, len(neighbors))
+		for k, j := range neighbors {
+			g.Weights[i][k] = shared[[2]int{i, j}]
+		}
+	}
+	return g, nil
+}
+
+// weightedGraph is an undirected graph whose self-loops hold the internal
+// weight of the aggregated communities of Louvain
+type weightedGraph struct {
+	neighbors [][]int
+	weights   [][]float64
+}
+
+// degree returns the weighted degree of the nodes and their sum
+func (w *weightedGraph) degree() ([]float64, float64) {
+	k := make([]float64, len(w.neighbors))
+	total := 0.0
+	for i, weights := range w.weights {
+		for _, x := range weights {
+			k[i] += x
+		}
+		total += k[i]
+	}
+	return k, total
+}
+
+// localMoves moves each node to the neighboring community which improves
+// the modularity most, until no move improves it. It returns the community
+// of each node and whether a node moved.
+func (w *weightedGraph) localMoves() ([]int, bool) {
+	n := len(w.neighbors)
+	k, m2 := w.degree()
+	community := make([]int, n)
+	total := make([]float64, n) // weighted degree of each community
+	for i := range community {
+		community[i] = i
+		total[i] = k[i]
+	}
+	if m2 == 0 {
+		return community, false
+	}
+
+	moved := false
+	links := make(map[int]float64)
+	for improved := true; improved; {
+		improved = false
+		for i := 0; i < n; i++ {
+			// Weights from i to each neighboring community, in the order
+			// of the neighbors for determinism
+			for c := range links {
+				delete(links, c)
+			}
+			var candidates []int
+			for x, j := range w.neighbors[i] {
+				if j == i {
+					continue
+				}
+				c := community[j]
+				if _, ok := links[c]; !ok {
+					candidates = append(candidates, c)
+				}
+				links[c] += w.weights[i][x]
+			}
+
+			current := community[i]
+			total[current] -= k[i]
+			best, bestGain := current, links[current]-total[current]*k[i]/m2
+			for _, c := range candidates {
+				gain := links[c] - total[c]*k[i]/m2
+				if gain > bestGain+1e-12 {
+					best, bestGain = c, gain
+				}
+			}
+			total[best] += k[i]
+			if best != current {
+				community[i] = best
+				improved, moved = true, true
+			}
+		}
+	}
+	return community, moved
+}
+
+// renumber numbers the communities from 0 in the order of their first node
+func renumber(community []int) ([]int, int) {
+	numbers := make(map[int]int)
+	renumbered := make([]int, len(community))
+	for i, c := range community {
+		number, ok := numbers[c]
+		if !ok {
+			number = len(numbers)
+			numbers[c] = number
+		}
+		renumbered[i] = number
+	}
+	return renumbered, len(numbers)
+}
+
+// aggregate builds the graph of the communities
+func (w *weightedGraph) aggregate(community []int, size int) *weightedGraph {
+	edges := make([]map[int]float64, size)
+	for c := range edges {
+		edges[c] = make(map[int]float64)
+	}
+	for i, neighbors := range w.neighbors {
+		for x, j := range neighbors {
+			edges[community[i]][community[j]] += w.weights[i][x]
+		}
+	}
+	aggregated := &weightedGraph{neighbors: make([][]int, size), weights: make([][]float64, size)}
+	for c, weights := range edges {
+		for d := range weights {
+			aggregated.neighbors[c] = append(aggregated.neighbors[c], d)
+		}
+		sort.Ints(aggregated.neighbors[c])
+		for _, d := range aggregated.neighbors[c] {
+			aggregated.weights[c] = append(aggregated.weights[c], weights[d])
+		}
+	}
+	return aggregated
+}
+
+// DetectLouvain returns the community of each author found by the Louvain
+// method, which moves the nodes between communities while the modularity
+// increases, then repeats on the graph of the communities
+func DetectLouvain(g *CoauthorGraph) []int {
+	w := &weightedGraph{neighbors: g.Neighbors, weights: g.Weights}
+	membership := make([]int, len(g.UIDs))
+	for i := range membership {
+		membership[i] = i
+	}
+	for {
+		community, moved := w.localMoves()
+		if !moved {
+			break
+		}
+		community, size := renumber(community)
+		for i, c := range membership {
+			membership[i] = community[c]
+		}
+		w = w.aggregate(community, size)
+	}
+	membership, _ = renumber(membership)
+	return membership
+}
+
+// DetectLabelPropagation returns the community of each author found by
+// label propagation: every author takes the label weighing most among its
+// coauthors, the smallest one in case of a tie, until the labels are stable
+func DetectLabelPropagation(g *CoauthorGraph) []int {
+	n := len(g.UIDs)
+	labels := make([]int, n)
+	for i := range labels {
+		labels[i] = i
+	}
+	random := rand.New(rand.NewSource(CommunitySeed))
+	weights := make(map[int]float64)
+	for iteration := 0; iteration < MaxIterations; iteration++ {
+		changed := false
+		for _, i := range random.Perm(n) {
+			if len(g.Neighbors[i]) == 0 {
+				continue
+			}
+			for l := range weights {
+				delete(weights, l)
+			}
+			for x, j := range g.Neighbors[i] {
+				weights[labels[j]] += g.Weights[i][x]
+			}
+			best, bestWeight := labels[i], weights[labels[i]]
+			for l, weight := range weights {
+				if weight > bestWeight || (weight == bestWeight && l < best) {
+					best, bestWeight = l, weight
+				}
+			}
+			if best != labels[i] {
+				labels[i] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	labels, _ = renumber(labels)
+	return labels
+}
+
+// DetectCommunities returns the community of each author found by a method
+func DetectCommunities(g *CoauthorGraph, method string) ([]int, error) {
+	switch method {
+	case Louvain:
+		return DetectLouvain(g), nil
+	case LabelPropagation:
+		return DetectLabelPropagation(g), nil
+	}
+	return nil, fmt.Errorf("Unknown community detection method %q", method)
+}
+
+// Modularity measures how much more the authors write within their
+// communities than at random, between -0.5 and 1
+func Modularity(g *CoauthorGraph, community []int) float64 {
+	w := &weightedGraph{neighbors: g.Neighbors, weights: g.Weights}
+	k, m2 := w.degree()
+	if m2 == 0 {
+		return 0
+	}
+	internal := make(map[int]float64)
+	total := make(map[int]float64)
+	for i, neighbors := range g.Neighbors {
+		total[community[i]] += k[i]
+		for x, j := range neighbors {
+			if community[i] == community[j] {
+				internal[community[i]] += g.Weights[i][x]
+			}
+		}
+	}
+	q := 0.0
+	for c, t := range total {
+		q += internal[c]/m2 - (t/m2)*(t/m2)
+	}
+	return q
+}
+
+// Communities groups the authors by community, largest first, dropping the
+// communities smaller than MinCommunitySize. Each one is labeled by the
+// name of its member with the most articles.
+func Communities(g *CoauthorGraph, community []int, method string) []models.Community {
+	members := make(map[int][]int)
+	for i, c := range community {
+		members[c] = append(members[c], i)
+	}
+	var communities []models.Community
+	for _, authors := range members {
+		if len(authors) < MinCommunitySize {
+			continue
+		}
+		sort.Slice(authors, func(x, y int) bool {
+			i, j := authors[x], authors[y]
+			if g.Articles[i] != g.Articles[j] {
+				return g.Articles[i] > g.Articles[j]
+			}
+			return g.Names[i] < g.Names[j]
+		})
+		c := models.Community{
+			Label:  g.Names[authors[0]],
+			Size:   len(authors),
+			Method: method,
+			DType:  []string{"Community"},
+		}
+		for _, i := range authors {
+			c.Members = append(c.Members, models.Author{UID: g.UIDs[i], Name: g.Names[i]})
+		}
+		communities = append(communities, c)
+	}
+	sort.Slice(communities, func(i, j int) bool {
+		if communities[i].Size != communities[j].Size {
+			return communities[i].Size > communities[j].Size
+		}
+		return communities[i].Label < communities[j].Label
+	})
+	return communities
+}
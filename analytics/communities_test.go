@@ -0,0 +1,80 @@
+package analytics
+
+import (
+	"fmt"
+	"log"
+	"testing"
+
+	"pandor/models"
+)
+
+// twoGroups is two groups of four authors who wrote two articles each,
+// joined by an article of Group1_D and Group2_A, and an author alone
+func twoGroups() *CoauthorGraph {
+	group := func(name string) []models.Author {
+		var authors []models.Author
+		for _, initial := range "ABCD" {
+			authors = append(authors, models.Author{UID: fmt.Sprintf("%s%c", name, initial), Name: fmt.Sprintf("%s_%c", name, initial)})
+		}
+		return authors
+	}
+	first, second := group("Group1"), group("Group2")
+	source := &pagedSource{articles: []models.Article{
+		{UID: "0x10", Authors: first},
+		{UID: "0x11", Authors: first},
+		{UID: "0x12", Authors: second},
+		{UID: "0x13", Authors: second},
+		{UID: "0x14", Authors: []models.Author{first[3], second[0], first[3]}},
+		{UID: "0x15", Authors: []models.Author{{UID: "Alone", Name: "Alone_A"}}},
+	}}
+	g, err := LoadCoauthorGraph(source)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return g
+}
+
+func TestLoadCoauthorGraph(t *testing.T) {
+	g := twoGroups()
+	if len(g.UIDs) != 9 || g.Articles[3] != 3 || len(g.Neighbors[8]) != 0 {
+		log.Fatalf("Wrong authors %v %v", g.Names, g.Articles)
+	}
+	// Group1_D wrote two articles with Group1_A and one with Group2_A
+	if fmt.Sprint(g.Neighbors[3], g.Weights[3]) != "[0 1 2 4] [2 2 2 1]" {
+		log.Fatalf("Wrong coauthors %v %v", g.Neighbors[3], g.Weights[3])
+	}
+}
+
+func TestDetectCommunities(t *testing.T) {
+	g := twoGroups()
+	for _, method := range CommunityMethods {
+		community, err := DetectCommunities(g, method)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if fmt.Sprint(community) != "[0 0 0 0 1 1 1 1 2]" {
+			log.Fatalf("Wrong %s communities %v", method, community)
+		}
+		if q := Modularity(g, community); q < 0.4 {
+			log.Fatalf("Low %s modularity %f", method, q)
+		}
+
+		communities := Communities(g, community, method)
+		if len(communities) != 2 || communities[0].Size != 4 || communities[0].Label != "Group1_D" ||
+			communities[1].Label != "Group2_A" || communities[0].Members[0].UID != "Group1D" {
+			log.Fatalf("Wrong %s communities %v", method, communities)
+		}
+		values := communityValues(communities)
+		first := values[0].(models.Community)
+		if first.Members[0].UID != "Group1D" || first.Members[0].Rank != 1 || first.Members[3].Rank != 4 {
+			log.Fatalf("Wrong %s ranks %v", method, first.Members)
+		}
+	}
+
+	if q := Modularity(g, make([]int, len(g.UIDs))); q != 0 {
+		log.Fatalf("A single community should have a modularity of 0, got %f", q)
+	}
+	if _, err := DetectCommunities(g, "kmeans"); err == nil {
+		log.Fatal("Unknown methods should fail")
+	}
+}
@@ -0,0 +1,169 @@
+package analytics
+
+import (
+	"sort"
+	"time"
+
+	"pandor/models"
+)
+
+// PageSize is the number of articles read per query
+var PageSize = 1000
+
+// VelocityYears is the window of the citation velocity, in years
+var VelocityYears = 3
+
+// Source pages through the articles with their authors and cited papers,
+// databases.DgraphStore is one
+type Source interface {
+	ArticlesAfter(after string, first int) ([]models.Article, error)
+}
+
+// ArticleMetrics are the citation metrics of an article
+type ArticleMetrics struct {
+	UID       string
+	ArXivID   string
+	Citations int
+	ByYear    map[int]int // citations by year of the citing articles
+	Velocity  float64     // citations per year over the last VelocityYears
+}
+
+// AuthorMetrics are the citation metrics of an author
+type AuthorMetrics struct {
+	UID       string
+	Name      string
+	Articles  []string // UIDs of the articles of the author
+	Citations int
+	HIndex    int
+	I10Index  int
+}
+
+// Metrics are the citation metrics of the whole graph
+type Metrics struct {
+	Articles map[string]*ArticleMetrics // by UID
+	Authors  map[string]*AuthorMetrics  // by UID
+}
+
+// HIndex returns the largest h such that h of the counts are at least h
+func HIndex(counts []int) int {
+	sorted := append([]int(nil), counts...)
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+	h := 0
+	for h < len(sorted) && sorted[h] >= h+1 {
+		h++
+	}
+	return h
+}
+
+// I10Index returns the number of counts of at least 10
+func I10Index(counts []int) int {
+	i10 := 0
+	for _, c := range counts {
+		if c >= 10 {
+			i10++
+		}
+	}
+	return i10
+}
+
+// Velocity returns the mean number of citations per year over the years
+// years before now, the current year being counted for its elapsed share
+func Velocity(byYear map[int]int, years int, now time.Time) float64 {
+	if years <= 0 {
+		return 0
+	}
+	citations := 0
+	for year := now.Year() - years + 1; year <= now.Year(); year++ {
+		citations += byYear[year]
+	}
+	elapsed := float64(years-1) + float64(now.YearDay())/365
+	return float64(citations) / elapsed
+}
+
+// Compute reads the whole graph from source and computes the metrics at the
+// date now
+func Compute(source Source, now time.Time) (*Metrics, error) {
+	m := &Metrics{
+		Articles: make(map[string]*ArticleMetrics),
+		Authors:  make(map[string]*AuthorMetrics),
+	}
+	article := func(uid, arXivID string) *ArticleMetrics {
+		a, ok := m.Articles[uid]
+		if !ok {
+			a = &ArticleMetrics{UID: uid, ByYear: make(map[int]int)}
+			m.Articles[uid] = a
+		}
+		if a.ArXivID == "" {
+			a.ArXivID = arXivID
+		}
+		return a
+	}
+
+	after := "0x0"
+	for {
+		articles, err := source.ArticlesAfter(after, PageSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(articles) == 0 {
+			break
+		}
+		for _, a := range articles {
+			article(a.UID, a.ArXivID)
+			// An article citing a paper twice counts once
+			seen := make(map[string]bool)
+			for _, cited := range a.CitedPapers {
+				if seen[cited.UID] || cited.UID == a.UID {
+					continue
+				}
+				seen[cited.UID] = true
+				c := article(cited.UID, cited.ArXivID)
+				c.Citations++
+				if !a.SubmissionDate.IsZero() {
+					c.ByYear[a.SubmissionDate.Year()]++
+				}
+			}
+			for _, author := range a.Authors {
+				u, ok := m.Authors[author.UID]
+				if !ok {
+					u = &AuthorMetrics{UID: author.UID, Name: author.Name}
+					m.Authors[author.UID] = u
+				}
+				u.Articles = append(u.Articles, a.UID)
+			}
+		}
+		after = articles[len(articles)-1].UID
+	}
+
+	for _, a := range m.Articles {
+		a.Velocity = Velocity(a.ByYear, VelocityYears, now)
+	}
+	for _, u := range m.Authors {
+		counts := make([]int, len(u.Articles))
+		for i, uid := range u.Articles {
+			counts[i] = m.Articles[uid].Citations
+			u.Citations += counts[i]
+		}
+		u.HIndex = HIndex(counts)
+		u.I10Index = I10Index(counts)
+	}
+	return m, nil
+}
+
+// MostCited returns the n most cited articles, ties by arXiv ID
+func (m *Metrics) MostCited(n int) []*ArticleMetrics {
+	articles := make([]*ArticleMetrics, 0, len(m.Articles))
+	for _, a := range m.Articles {
+		articles = append(articles, a)
+	}
+	sort.Slice(articles, func(i, j int) bool {
+		if articles[i].Citations != articles[j].Citations {
+			return articles[i].Citations > articles[j].Citations
+		}
+		return articles[i].ArXivID < articles[j].ArXivID
+	})
+	if len(articles) > n {
+		articles = articles[:n]
+	}
+	return articles
+}
@@ -0,0 +1,98 @@
+package analytics
+
+import (
+	"log"
+	"math"
+	"testing"
+	"time"
+
+	"pandor/models"
+)
+
+// pagedSource serves articles two at a time
+type pagedSource struct {
+	articles []models.Article
+}
+
+func (s *pagedSource) ArticlesAfter(after string, first int) ([]models.Article, error) {
+	if first > 2 {
+		first = 2
+	}
+	for i, a := range s.articles {
+		if a.UID > after {
+			end := i + first
+			if end > len(s.articles) {
+				end = len(s.articles)
+			}
+			return s.articles[i:end], nil
+		}
+	}
+	return nil, nil
+}
+
+func TestHIndex(t *testing.T) {
+	for _, c := range []struct {
+		counts []int
+		h, i10 int
+	}{
+		{nil, 0, 0},
+		{[]int{0, 0}, 0, 0},
+		{[]int{10, 8, 5, 4, 3}, 4, 1},
+		{[]int{25, 8, 5, 3, 3}, 3, 1},
+		{[]int{1, 1, 1}, 1, 0},
+		{[]int{12, 11, 10}, 3, 3},
+	} {
+		if h := HIndex(c.counts); h != c.h {
+			log.Fatalf("h-index of %v: expected %d, got %d", c.counts, c.h, h)
+		}
+		if i10 := I10Index(c.counts); i10 != c.i10 {
+			log.Fatalf("i10-index of %v: expected %d, got %d", c.counts, c.i10, i10)
+		}
+	}
+}
+
+func TestCompute(t *testing.T) {
+	lewis := models.Author{UID: "0x1", Name: "Lewis_G"}
+	huxor := models.Author{UID: "0x2", Name: "Huxor_A"}
+	date := func(year int) time.Time { return time.Date(year, 6, 1, 0, 0, 0, 0, time.UTC) }
+	cite := func(uids ...string) []models.Article {
+		var cited []models.Article
+		for _, uid := range uids {
+			cited = append(cited, models.Article{UID: uid})
+		}
+		return cited
+	}
+	source := &pagedSource{articles: []models.Article{
+		{UID: "0x10", ArXivID: "a", Authors: []models.Author{lewis}, SubmissionDate: date(2015)},
+		{UID: "0x11", ArXivID: "b", Authors: []models.Author{lewis, huxor}, SubmissionDate: date(2016),
+			CitedPapers: cite("0x10")},
+		{UID: "0x12", ArXivID: "c", Authors: []models.Author{huxor}, SubmissionDate: date(2019),
+			CitedPapers: cite("0x10", "0x11", "0x10")},
+		{UID: "0x13", ArXivID: "d", SubmissionDate: date(2020), CitedPapers: cite("0x10", "0x11", "0x20")},
+	}}
+	PageSize = 3
+
+	m, err := Compute(source, time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if m.Articles["0x10"].Citations != 3 || m.Articles["0x11"].Citations != 2 || m.Articles["0x20"].Citations != 1 {
+		log.Fatalf("Wrong citation counts %+v %+v", m.Articles["0x10"], m.Articles["0x11"])
+	}
+	// 2 citations in 2019 and 2020, over 3 years minus one day
+	if v := m.Articles["0x10"].Velocity; math.Abs(v-2/(2+366.0/365)) > 1e-9 {
+		log.Fatalf("Wrong velocity %f", v)
+	}
+	if l := m.Authors["0x1"]; l.Citations != 5 || l.HIndex != 2 || l.I10Index != 0 {
+		log.Fatalf("Wrong author metrics %+v", l)
+	}
+	if h := m.Authors["0x2"]; h.Citations != 2 || h.HIndex != 1 {
+		log.Fatalf("Wrong author metrics %+v", h)
+	}
+	if top := m.MostCited(2); len(top) != 2 || top[0].ArXivID != "a" || top[1].ArXivID != "b" {
+		log.Fatalf("Wrong most cited articles %v", top)
+	}
+	if values := m.Values(); len(values) != 7 || values[0].(articleValues).UID != "0x10" {
+		log.Fatalf("Wrong values %v", values)
+	}
+}
@@ -0,0 +1,153 @@
+package analytics
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"pandor/databases"
+	"pandor/logger"
+	"pandor/models"
+
+	"github.com/dgraph-io/dgo/v2"
+)
+
+// WriteBatchSize is the number of nodes updated per transaction
+var WriteBatchSize = 1000
+
+// articleValues and authorValues are the predicates written back, zeros
+// included so that stale values are overwritten
+type articleValues struct {
+	UID              string  `json:"uid"`
+	CitationCount    int     `json:"citationcount"`
+	CitationVelocity float64 `json:"citationvelocity"`
+}
+
+type authorValues struct {
+	UID           string `json:"uid"`
+	CitationCount int    `json:"citationcount"`
+	HIndex        int    `json:"hindex"`
+	I10Index      int    `json:"i10index"`
+}
+
+// Values returns the predicates to write, sorted by UID
+func (m *Metrics) Values() []interface{} {
+	var values []interface{}
+	articles := make([]string, 0, len(m.Articles))
+	for uid := range m.Articles {
+		articles = append(articles, uid)
+	}
+	sort.Strings(articles)
+	for _, uid := range articles {
+		a := m.Articles[uid]
+		values = append(values, articleValues{UID: a.UID, CitationCount: a.Citations, CitationVelocity: a.Velocity})
+	}
+	authors := make([]string, 0, len(m.Authors))
+	for uid := range m.Authors {
+		authors = append(authors, uid)
+	}
+	sort.Strings(authors)
+	for _, uid := range authors {
+		u := m.Authors[uid]
+		values = append(values, authorValues{UID: u.UID, CitationCount: u.Citations, HIndex: u.HIndex, I10Index: u.I10Index})
+	}
+	return values
+}
+
+// Write stores the values of the metrics or the centralities on their
+// nodes
+func Write(values []interface{}, dg *dgo.Dgraph) error {
+	for start := 0; start < len(values); start += WriteBatchSize {
+		end := start + WriteBatchSize
+		if end > len(values) {
+			end = len(values)
+		}
+		_, err := databases.SetJSON(values[start:end], dg)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run computes the metrics and the centralities of the graph and writes
+// them back
+func Run(dg *dgo.Dgraph) (*Metrics, *Centrality, error) {
+	start := time.Now()
+	store := databases.NewDgraphStore(dg)
+	m, err := Compute(store, start)
+	if err != nil {
+		return nil, nil, err
+	}
+	g, err := LoadGraph(store)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := ComputeCentrality(g)
+	err = Write(append(m.Values(), c.Values()...), dg)
+	if err != nil {
+		return nil, nil, err
+	}
+	logger.Logger.Info(fmt.Sprintf("Computed the metrics of %d articles and %d authors in %v",
+		len(m.Articles), len(m.Authors), time.Since(start).Round(time.Millisecond)))
+	return m, c, nil
+}
+
+// Schedule runs the analytics now and then every period, a failed run is
+// logged and retried at the next period
+func Schedule(every time.Duration, dg *dgo.Dgraph) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		if _, _, err := Run(dg); err != nil {
+			logger.Logger.Error(fmt.Sprintf("Analytics failed: %v", err))
+		}
+		<-ticker.C
+	}
+}
+
+// communityValues returns the new communities to write, their members
+// keep their order as a rank facet
+func communityValues(communities []models.Community) []interface{} {
+	values := make([]interface{}, len(communities))
+	for i, c := range communities {
+		c.UID = fmt.Sprintf("_:community%d", i)
+		members := make([]models.Author, len(c.Members))
+		for k, member := range c.Members {
+			members[k] = models.Author{UID: member.UID, Rank: k + 1}
+		}
+		c.Members = members
+		values[i] = c
+	}
+	return values
+}
+
+// WriteCommunities replaces the stored communities by new ones in a single
+// transaction
+func WriteCommunities(communities []models.Community, dg *dgo.Dgraph) error {
+	_, err := databases.ReplaceType("Community", nil, communityValues(communities), dg)
+	return err
+}
+
+// RunCommunities detects the communities of the coauthors with a method
+// and stores them, it returns them with their modularity
+func RunCommunities(method string, dg *dgo.Dgraph) ([]models.Community, float64, error) {
+	start := time.Now()
+	g, err := LoadCoauthorGraph(databases.NewDgraphStore(dg))
+	if err != nil {
+		return nil, 0, err
+	}
+	community, err := DetectCommunities(g, method)
+	if err != nil {
+		return nil, 0, err
+	}
+	modularity := Modularity(g, community)
+	communities := Communities(g, community, method)
+	err = WriteCommunities(communities, dg)
+	if err != nil {
+		return nil, 0, err
+	}
+	logger.Logger.Info(fmt.Sprintf("Found %d communities of %d authors, modularity %.3f, in %v",
+		len(communities), len(g.UIDs), modularity, time.Since(start).Round(time.Millisecond)))
+	return communities, modularity, nil
+}
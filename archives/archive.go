@@ -0,0 +1,148 @@
+package archives
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pandor/utils"
+)
+
+// RefPrefix starts every reference returned by an Archive
+const RefPrefix = "sha256:"
+
+// Archive is a content-addressed store of raw responses. Each response is
+// gzipped under a path derived from the SHA-256 of its content, so storing
+// the same page twice costs nothing.
+type Archive struct {
+	Dir string
+}
+
+// New builds an Archive stored in dir
+func New(dir string) *Archive {
+	return &Archive{Dir: dir}
+}
+
+// Ref returns the reference of a content
+func Ref(body []byte) string {
+	sum := sha256.Sum256(body)
+	return RefPrefix + hex.EncodeToString(sum[:])
+}
+
+// Path returns the file holding a reference
+func (a *Archive) Path(ref string) (string, error) {
+	if !strings.HasPrefix(ref, RefPrefix) {
+		return "", fmt.Errorf("Invalid archive reference: %s", ref)
+	}
+	sum := strings.TrimPrefix(ref, RefPrefix)
+	if len(sum) != 2*sha256.Size {
+		return "", fmt.Errorf("Invalid archive reference: %s", ref)
+	}
+	if _, err := hex.DecodeString(sum); err != nil {
+		return "", fmt.Errorf("Invalid archive reference: %s", ref)
+	}
+	return filepath.Join(a.Dir, sum[:2], sum+".gz"), nil
+}
+
+// Exists tells whether a reference is stored in the archive
+func (a *Archive) Exists(ref string) (bool, error) {
+	path, err := a.Path(ref)
+	if err != nil {
+		return false, err
+	}
+	return utils.Exists(path)
+}
+
+// Put stores a content and returns its reference
+func (a *Archive) Put(body []byte) (string, error) {
+	ref := Ref(body)
+	path, err := a.Path(ref)
+	if err != nil {
+		return "", err
+	}
+	if exists, err := utils.Exists(path); exists || err != nil {
+		return ref, err
+	}
+	err = utils.BuildDir(filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+
+	// Write to a temporary file first so that a crash never leaves
+	// a truncated file under a valid reference
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	zw := gzip.NewWriter(tmp)
+	_, err = zw.Write(body)
+	if err == nil {
+		err = zw.Close()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+	return ref, os.Rename(tmp.Name(), path)
+}
+
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (rc readCloser) Close() error {
+	var err error
+	for _, c := range rc.closers {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Open returns a reader over the content of a reference
+func (a *Archive) Open(ref string) (io.ReadCloser, error) {
+	path, err := a.Path(ref)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return readCloser{Reader: zr, closers: []io.Closer{zr, f}}, nil
+}
+
+// Get returns the content of a reference and checks its integrity
+func (a *Archive) Get(ref string) ([]byte, error) {
+	rc, err := a.Open(ref)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	body, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	if Ref(body) != ref {
+		return nil, fmt.Errorf("Corrupted archive entry: %s", ref)
+	}
+	return body, nil
+}
@@ -0,0 +1,49 @@
+package archives
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"testing"
+)
+
+func TestArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pandor")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := New(dir)
+	body := []byte(`<html><div id="abs"><h1 class="title">Title: Test</h1></div></html>`)
+	ref, err := a.Put(body)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if ref != Ref(body) {
+		log.Fatalf("Wrong reference: %s", ref)
+	}
+
+	again, err := a.Put(body)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if again != ref {
+		log.Fatalf("Same content stored under %s and %s", ref, again)
+	}
+
+	stored, err := a.Get(ref)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if string(stored) != string(body) {
+		log.Fatalf("Wrong content: %s", stored)
+	}
+
+	if _, err = a.Get(Ref([]byte("missing"))); err == nil {
+		log.Fatal("A missing reference has been read")
+	}
+	if _, err = a.Path("../../etc/passwd"); err == nil {
+		log.Fatal("An invalid reference has been accepted")
+	}
+}
@@ -0,0 +1,56 @@
+package databases
+
+import "fmt"
+
+// Backends of the Store. The MemoryStore is not one, it is lost with the
+// process.
+const (
+	DgraphBackend = "dgraph"
+	SQLBackend    = "sql"
+)
+
+// Backends lists the backends accepted by OpenStore
+var Backends = []string{DgraphBackend, SQLBackend}
+
+// Backend is the backend opened by OpenStore
+var Backend = DgraphBackend
+
+// SQLDriver and SQLSource open the database of the SQL backend
+var (
+	SQLDriver = "sqlite"
+	SQLSource = "pandor.db"
+)
+
+// OpenStore opens the Store of Backend and returns the function closing it
+func OpenStore() (Store, func() error, error) {
+	switch Backend {
+	case DgraphBackend:
+		d, dg, err := NewClient()
+		if err != nil {
+			return nil, nil, err
+		}
+		return NewDgraphStore(dg), d.Close, nil
+	case SQLBackend:
+		s, err := OpenSQLStore(SQLDriver, SQLSource)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, s.Close, nil
+	}
+	return nil, nil, fmt.Errorf("Unknown backend %q", Backend)
+}
+
+// OpenGraph opens the Graph of Backend, written by the crawler, and returns
+// the function closing it
+func OpenGraph() (Graph, func() error, error) {
+	store, closeStore, err := OpenStore()
+	if err != nil {
+		return nil, nil, err
+	}
+	g, ok := store.(Graph)
+	if !ok {
+		closeStore()
+		return nil, nil, fmt.Errorf("The %s backend cannot be written by the crawler", Backend)
+	}
+	return g, closeStore, nil
+}
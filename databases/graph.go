@@ -0,0 +1,78 @@
+package databases
+
+import (
+	"strings"
+
+	"pandor/models"
+)
+
+// Graph is the part of a database written by the crawler, the full text
+// stage and the reparse: the lookups of the stored nodes and the writes of
+// the articles. DgraphStore, SQLStore and MemoryStore implement it.
+type Graph interface {
+	// AuthorUID gives the UID of the author with a name
+	AuthorUID(name string) (string, error)
+	// CategoryUID gives the UID of the category with a code
+	CategoryUID(code string) (string, error)
+	// ArticleUIDByArXivID gives the UID of the article with an arXiv ID
+	ArticleUIDByArXivID(arXivID string) (string, error)
+	// CrawledArticleUID gives the UID of the article with an arXiv ID whose
+	// page was stored, and not only its ID as a cited paper
+	CrawledArticleUID(arXivID string) (string, error)
+	// AddArticle stores an article, merged with the stored one of the same
+	// arXiv ID, or of the same title when it has no ID, and returns its UID
+	AddArticle(article models.Article) (string, error)
+	// UpdateArticle sets the non-zero fields of a stored article, its UID
+	// has to be known
+	UpdateArticle(article models.Article) error
+	// DeleteEdges removes every value of the given predicates from an
+	// article, such as its authors before they are parsed again
+	DeleteEdges(uid string, predicates []string) error
+}
+
+// AuthorUID gives the UID of the author with a name
+func (s *DgraphStore) AuthorUID(name string) (string, error) {
+	return GetAuthorUID(name, s.DG)
+}
+
+// CategoryUID gives the UID of the category with a code
+func (s *DgraphStore) CategoryUID(code string) (string, error) {
+	return GetCategoryUID(code, s.DG)
+}
+
+// ArticleUIDByArXivID gives the UID of the article with an arXiv ID
+func (s *DgraphStore) ArticleUIDByArXivID(arXivID string) (string, error) {
+	return GetArticleUIDByArXivID(arXivID, s.DG)
+}
+
+// CrawledArticleUID gives the UID of the article with an arXiv ID whose
+// page was stored
+func (s *DgraphStore) CrawledArticleUID(arXivID string) (string, error) {
+	return GetCrawledArticleUID(arXivID, s.DG)
+}
+
+// AddArticle stores an article as AddArticle and returns its UID
+func (s *DgraphStore) AddArticle(article models.Article) (string, error) {
+	article = mergedArticle(article, s.DG)
+	if article.UID == "" {
+		article.UID = "_:article"
+	}
+	uids, err := SetJSON(article, s.DG)
+	if err != nil {
+		return "", err
+	}
+	if blank := strings.TrimPrefix(article.UID, "_:"); blank != article.UID {
+		return uids[blank], nil
+	}
+	return article.UID, nil
+}
+
+// UpdateArticle sets the predicates of a stored article
+func (s *DgraphStore) UpdateArticle(article models.Article) error {
+	return UpdateArticle(article, s.DG)
+}
+
+// DeleteEdges removes every value of the given predicates from a node
+func (s *DgraphStore) DeleteEdges(uid string, predicates []string) error {
+	return DeleteEdges(uid, predicates, s.DG)
+}
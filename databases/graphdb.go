@@ -1,11 +1,13 @@
 package databases
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"pandor/logger"
 	"pandor/models"
+	"strings"
 
 	"github.com/dgraph-io/dgo/v2"
 	"github.com/dgraph-io/dgo/v2/protos/api"
@@ -47,13 +49,11 @@ func LoadSchema(schema string, dg *dgo.Dgraph) error {
 	return err
 }
 
-// AddArticle adds an article to Dgraph
+// AddArticle adds an article to Dgraph. It replaces the stored article of
+// the same arXiv ID, such as a cited paper stored with its ID only, or of
+// the same title when it has no ID.
 func AddArticle(article models.Article, dg *dgo.Dgraph) (*api.Response, error) {
-
-	uid, err := GetArticleUID(article.Title, dg)
-	if err == nil {
-		article.UID = uid
-	}
+	article = mergedArticle(article, dg)
 	mu := &api.Mutation{
 		CommitNow: true,
 	}
@@ -71,6 +71,22 @@ func AddArticle(article models.Article, dg *dgo.Dgraph) (*api.Response, error) {
 	return response, err
 }
 
+// mergedArticle sets the UID of an article to the one of the stored article
+// it replaces, if any
+func mergedArticle(article models.Article, dg *dgo.Dgraph) models.Article {
+	var uid string
+	var err error
+	if article.ArXivID != "" {
+		uid, err = GetArticleUIDByArXivID(article.ArXivID, dg)
+	} else {
+		uid, err = GetArticleUID(article.Title, dg)
+	}
+	if err == nil {
+		article.UID = uid
+	}
+	return article
+}
+
 // QueryWithVars allows to send a query to Dgraph with a var dict
 func QueryWithVars(query string, variables map[string]string, dg *dgo.Dgraph) (api.Response, error) {
 	ctx := context.Background()
@@ -93,62 +109,138 @@ func Query(query string, dg *dgo.Dgraph) (api.Response, error) {
 	return *resp, err
 }
 
-// GetAuthorUID gives the UID of a given author
-func GetAuthorUID(name string, dg *dgo.Dgraph) (string, error) {
-	variables := map[string]string{"$name": name}
-	query := `query GetUID($name: string){
-							getuid(func: eq(name, $name)){
-								uid
-						  }
-						}`
-	resp, err := QueryWithVars(query, variables, dg)
+// getUID gives the UID of the node with a value of a predicate, the error
+// names the kind of node when there is none
+func getUID(predicate, value, kind string, dg *dgo.Dgraph) (string, error) {
+	var node struct {
+		UID string `json:"uid"`
+	}
+	err := FindBy(predicate, value).One(&node, dg)
+	if err == ErrNotFound {
+		return "", fmt.Errorf("No %s Found", kind)
+	}
 	if err != nil {
 		logger.Logger.Fatal(err.Error())
 	}
+	return node.UID, nil
+}
 
-	type Root struct {
-		Authors []models.Author `json:"getuid"`
+// GetAuthorUID gives the UID of a given author
+func GetAuthorUID(name string, dg *dgo.Dgraph) (string, error) {
+	return getUID("name", name, "Author", dg)
+}
+
+// GetArticleUID gives the UID of the article with a given title
+func GetArticleUID(title string, dg *dgo.Dgraph) (string, error) {
+	return getUID("title", title, "Article", dg)
+}
+
+// DeleteEdges removes every value of the given predicates from a node
+func DeleteEdges(uid string, predicates []string, dg *dgo.Dgraph) error {
+	var nquads bytes.Buffer
+	for _, predicate := range predicates {
+		fmt.Fprintf(&nquads, "<%s> <%s> * .\n", uid, predicate)
 	}
+	mu := &api.Mutation{
+		CommitNow: true,
+		DelNquads: nquads.Bytes(),
+	}
+	ctx := context.Background()
+	_, err := dg.NewTxn().Mutate(ctx, mu)
+	return err
+}
 
-	var r Root
-	err = json.Unmarshal(resp.Json, &r)
+// UpdateArticle sets the predicates of an existing article, its UID has
+// to be known
+func UpdateArticle(article models.Article, dg *dgo.Dgraph) error {
+	if article.UID == "" {
+		return fmt.Errorf("No UID for article %s", article.ArXivID)
+	}
+	pb, err := json.Marshal(article)
 	if err != nil {
-		logger.Logger.Fatal(err.Error())
+		return err
 	}
-
-	if len(r.Authors) == 0 {
-		return "", fmt.Errorf("No Author Found")
+	mu := &api.Mutation{
+		CommitNow: true,
+		SetJson:   pb,
 	}
-
-	return r.Authors[0].UID, nil
+	ctx := context.Background()
+	_, err = dg.NewTxn().Mutate(ctx, mu)
+	return err
 }
 
-// GetArticleUID gives the UID of a given author
-func GetArticleUID(title string, dg *dgo.Dgraph) (string, error) {
-	variables := map[string]string{"$title": title}
-	query := `query GetUID($title: string){
-							getuid(func: eq(title, $title)){
-								uid
-						  }
-						}`
-	resp, err := QueryWithVars(query, variables, dg)
+// SetJSON sets the predicates of the nodes encoded in v, in a single
+// transaction, and returns the UIDs of the new blank nodes
+func SetJSON(v interface{}, dg *dgo.Dgraph) (map[string]string, error) {
+	pb, err := json.Marshal(v)
 	if err != nil {
-		logger.Logger.Fatal(err.Error())
+		return nil, err
 	}
-
-	type Root struct {
-		Articles []models.Article `json:"getuid"`
+	mu := &api.Mutation{
+		CommitNow: true,
+		SetJson:   pb,
+	}
+	ctx := context.Background()
+	resp, err := dg.NewTxn().Mutate(ctx, mu)
+	if err != nil {
+		return nil, err
 	}
+	return resp.Uids, nil
+}
 
-	var r Root
-	err = json.Unmarshal(resp.Json, &r)
+// ReplaceType removes every node of a type and the given edges pointing to
+// them, and stores the new values in the same transaction, so that the
+// readers never see the type empty
+func ReplaceType(name string, edges []string, v interface{}, dg *dgo.Dgraph) (map[string]string, error) {
+	pb, err := json.Marshal(v)
 	if err != nil {
-		logger.Logger.Fatal(err.Error())
+		return nil, err
+	}
+	blocks := []string{fmt.Sprintf("nodes as var(func: type(%q))", name)}
+	deletions := []string{"uid(nodes) * * ."}
+	for i, edge := range edges {
+		blocks = append(blocks, fmt.Sprintf("linked%d as var(func: has(<%s>))", i, edge))
+		deletions = append(deletions, fmt.Sprintf("uid(linked%d) <%s> * .", i, edge))
+	}
+	req := &api.Request{
+		Query: fmt.Sprintf("{ %s }", strings.Join(blocks, " ")),
+		// The deletions of a mutation are applied before its values
+		Mutations: []*api.Mutation{{
+			DelNquads: []byte(strings.Join(deletions, "\n")),
+			SetJson:   pb,
+		}},
+		CommitNow: true,
 	}
+	ctx := context.Background()
+	resp, err := dg.NewTxn().Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Uids, nil
+}
 
-	if len(r.Articles) == 0 {
+// GetArticleUIDByArXivID gives the UID of the article with a given arXiv ID
+func GetArticleUIDByArXivID(arXivID string, dg *dgo.Dgraph) (string, error) {
+	return getUID("arxivid", arXivID, "Article", dg)
+}
+
+// GetCrawledArticleUID gives the UID of the article with a given arXiv ID
+// whose page was stored, and not only its ID as a cited paper
+func GetCrawledArticleUID(arXivID string, dg *dgo.Dgraph) (string, error) {
+	var node struct {
+		UID string `json:"uid"`
+	}
+	err := FindBy("arxivid", arXivID).Has("title").One(&node, dg)
+	if err == ErrNotFound {
 		return "", fmt.Errorf("No Article Found")
 	}
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	return node.UID, nil
+}
 
-	return r.Articles[0].UID, nil
+// GetCategoryUID gives the UID of the category with a given code
+func GetCategoryUID(code string, dg *dgo.Dgraph) (string, error) {
+	return getUID("categorycode", code, "Category", dg)
 }
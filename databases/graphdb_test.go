@@ -2,6 +2,7 @@ package databases
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"pandor/logger"
@@ -30,23 +31,9 @@ func Format(resp *api.Response) error {
 	return err
 }
 
-func TestDB(t *testing.T) {
-	d, dg, err := NewClient()
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer d.Close()
-
-	err = DropAll(dg)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	err = LoadSchema(models.Schema, dg)
-	if err != nil {
-		log.Fatal(err)
-	}
-
+// checkGraph runs the writes of the crawler on a graph: an article added
+// again and a cited paper crawled later are merged with the stored ones
+func checkGraph(g Graph) {
 	uidarticle := models.FormatUID("Another Article from the same guy")
 	uidauthor := models.FormatUID("Lewis_G")
 	sub := models.FormatTime("2007-12-29T00:00:00.000Z")
@@ -69,16 +56,26 @@ func TestDB(t *testing.T) {
 				DType: []string{"Author"},
 			},
 		},
-		DType: []string{"Article"},
+		CitedPapers: []models.Article{{UID: models.FormatUID("0801.0002"), ArXivID: "0801.0002"}},
+		DType:       []string{"Article"},
 	}
 
-	_, err = AddArticle(article, dg)
+	_, err := g.AddArticle(article)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	// The cited paper is stored with its ID only until it is crawled
+	stub, err := g.ArticleUIDByArXivID("0801.0002")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err = g.CrawledArticleUID("0801.0002"); err == nil {
+		log.Fatal("The cited paper should not be crawled")
+	}
+
 	uidarticle = models.FormatUID("Globular clusters in the outer halo of M31: the survey")
-	uidauthor, err = GetAuthorUID("Lewis_G", dg)
+	uidauthor, err = g.AuthorUID("Lewis_G")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -117,16 +114,60 @@ func TestDB(t *testing.T) {
 		DType: []string{"Article"},
 	}
 
-	_, err = AddArticle(article, dg)
+	first, err := g.AddArticle(article)
+	if err != nil {
+		log.Fatal(err)
+	}
+	again, err := g.AddArticle(article)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if first != stub || again != stub {
+		log.Fatalf("The crawled article should be merged into %s, not %s and %s", stub, first, again)
+	}
+	crawled, err := g.CrawledArticleUID("0801.0002")
+	if err != nil || crawled != stub {
+		log.Fatalf("Wrong crawled article %s %v", crawled, err)
+	}
+
+	// The edges parsed again replace the stored ones
+	err = g.DeleteEdges(stub, []string{"authors"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = g.UpdateArticle(models.Article{UID: stub, FullTextKey: "fulltext/0801.0002.txt"})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+var withDgraph = flag.Bool("dgraph", false, "run TestDB against the Dgraph on localhost:9080, dropping it")
+
+func TestDB(t *testing.T) {
+	// Without -dgraph the scenario runs on a MemoryStore, a local graph is
+	// never dropped unless asked
+	if !*withDgraph {
+		checkGraph(NewMemoryStore())
+		return
+	}
+	d, dg, err := NewClient()
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer d.Close()
 
-	_, err = AddArticle(article, dg)
+	err = DropAll(dg)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	err = LoadSchema(models.Schema, dg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	checkGraph(NewDgraphStore(dg))
+
 	query := `{
 		test(func: eq(url, "https://export.arxiv.org/find/astro-ph/1/au:+Lewis_G/0/1/0/all/0/1")){
 		uid
@@ -0,0 +1,654 @@
+package databases
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"pandor/models"
+)
+
+// memoryArticle is an article of a MemoryStore, its edges are kept as UIDs
+type memoryArticle struct {
+	models.Article
+	authors    []string
+	categories []string
+	cited      []string
+}
+
+// MemoryStore is an in-memory knowledge graph implementing Store and Graph,
+// for the development and the tests without Dgraph. It is written like the
+// crawler writes Dgraph: AddArticle merges the article with the stored one
+// of the same arXiv ID, or title when it has none, and its authors,
+// categories and cited papers with the stored nodes of the same name, code
+// and arXiv ID.
+type MemoryStore struct {
+	mu          sync.RWMutex
+	next        int
+	articles    map[string]*memoryArticle
+	authors     map[string]*models.Author
+	categories  map[string]*models.Category
+	communities map[string]*models.Community
+
+	// Indexes of the lookups
+	byTitle   map[string]string
+	byArXivID map[string]string
+	byName    map[string]string
+	byCode    map[string]string
+
+	// Reverse edges, from the UID of the target to the UIDs of the sources
+	articlesOf  map[string][]string
+	inCategory  map[string][]string
+	citedBy     map[string][]string
+	communityOf map[string]string
+}
+
+// NewMemoryStore builds an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		articles:    make(map[string]*memoryArticle),
+		authors:     make(map[string]*models.Author),
+		categories:  make(map[string]*models.Category),
+		communities: make(map[string]*models.Community),
+		byTitle:     make(map[string]string),
+		byArXivID:   make(map[string]string),
+		byName:      make(map[string]string),
+		byCode:      make(map[string]string),
+		articlesOf:  make(map[string][]string),
+		inCategory:  make(map[string][]string),
+		citedBy:     make(map[string][]string),
+		communityOf: make(map[string]string),
+	}
+}
+
+// newUID returns an unused UID, in increasing order as in Dgraph
+func (s *MemoryStore) newUID() string {
+	s.next++
+	return fmt.Sprintf("0x%x", s.next)
+}
+
+// uidOrder compares UIDs by their numbers
+func uidOrder(a, b string) bool {
+	x, _ := strconv.ParseUint(strings.TrimPrefix(a, "0x"), 16, 64)
+	y, _ := strconv.ParseUint(strings.TrimPrefix(b, "0x"), 16, 64)
+	return x < y
+}
+
+// mergeFields sets the fields of dst to the non-zero fields of src, except
+// the UID and the edges, as setting a JSON node in Dgraph with omitempty
+func mergeFields(dst, src interface{}) {
+	d := reflect.ValueOf(dst).Elem()
+	v := reflect.ValueOf(src)
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if v.Type().Field(i).Name == "UID" || f.IsZero() {
+			continue
+		}
+		if f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.Struct {
+			continue
+		}
+		d.Field(i).Set(f)
+	}
+}
+
+// appendUnique appends uid unless uids holds it, as a [uid] predicate
+func appendUnique(uids []string, uid string) []string {
+	for _, u := range uids {
+		if u == uid {
+			return uids
+		}
+	}
+	return append(uids, uid)
+}
+
+// known returns uid if it is a stored node and not a blank one
+func known(uid string, exists bool) string {
+	if exists && !strings.HasPrefix(uid, "_:") {
+		return uid
+	}
+	return ""
+}
+
+// addAuthor stores an author, merged with the one of the same UID or name
+func (s *MemoryStore) addAuthor(author models.Author) string {
+	_, exists := s.authors[author.UID]
+	uid := known(author.UID, exists)
+	if uid == "" && author.Name != "" {
+		uid = s.byName[author.Name]
+	}
+	if uid == "" {
+		uid = s.newUID()
+		s.authors[uid] = &models.Author{UID: uid}
+	}
+	mergeFields(s.authors[uid], author)
+	if author.Name != "" {
+		s.byName[author.Name] = uid
+	}
+	return uid
+}
+
+// addCategory stores a category, merged with the one of the same code
+func (s *MemoryStore) addCategory(category models.Category) string {
+	_, exists := s.categories[category.UID]
+	uid := known(category.UID, exists)
+	if uid == "" && category.Code != "" {
+		uid = s.byCode[category.Code]
+	}
+	if uid == "" {
+		uid = s.newUID()
+		s.categories[uid] = &models.Category{UID: uid}
+	}
+	mergeFields(s.categories[uid], category)
+	if category.Code != "" {
+		s.byCode[category.Code] = uid
+	}
+	return uid
+}
+
+// addArticle stores an article, merged with the one of the same UID, arXiv
+// ID, or title when it has no ID, and returns its UID
+func (s *MemoryStore) addArticle(article models.Article) string {
+	_, exists := s.articles[article.UID]
+	uid := known(article.UID, exists)
+	if uid == "" && article.ArXivID != "" {
+		uid = s.byArXivID[article.ArXivID]
+	} else if uid == "" && article.Title != "" {
+		uid = s.byTitle[article.Title]
+	}
+	if uid == "" {
+		uid = s.newUID()
+		s.articles[uid] = &memoryArticle{Article: models.Article{UID: uid}}
+	}
+	a := s.articles[uid]
+	mergeFields(&a.Article, article)
+	if len(article.Sections) > 0 {
+		a.Sections = append([]models.Section(nil), article.Sections...)
+	}
+	if article.Title != "" {
+		s.byTitle[article.Title] = uid
+	}
+	if article.ArXivID != "" {
+		s.byArXivID[article.ArXivID] = uid
+	}
+
+	for _, author := range article.Authors {
+		to := s.addAuthor(author)
+		a.authors = appendUnique(a.authors, to)
+		s.articlesOf[to] = appendUnique(s.articlesOf[to], uid)
+	}
+	for _, category := range article.Categories {
+		to := s.addCategory(category)
+		a.categories = appendUnique(a.categories, to)
+		s.inCategory[to] = appendUnique(s.inCategory[to], uid)
+	}
+	for _, cited := range article.CitedPapers {
+		to := s.addArticle(cited)
+		a.cited = appendUnique(a.cited, to)
+		s.citedBy[to] = appendUnique(s.citedBy[to], uid)
+	}
+	return uid
+}
+
+// AddArticle stores an article with its authors, categories and cited
+// papers, and returns its UID
+func (s *MemoryStore) AddArticle(article models.Article) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addArticle(article), nil
+}
+
+// UpdateArticle sets the non-zero fields of a stored article and adds its
+// edges
+func (s *MemoryStore) UpdateArticle(article models.Article) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.articles[article.UID]; !ok {
+		return fmt.Errorf("No UID for article %s", article.ArXivID)
+	}
+	s.addArticle(article)
+	return nil
+}
+
+// removeUID removes uid from uids
+func removeUID(uids []string, uid string) []string {
+	kept := uids[:0]
+	for _, u := range uids {
+		if u != uid {
+			kept = append(kept, u)
+		}
+	}
+	return kept
+}
+
+// DeleteEdges removes every value of the given predicates from an article,
+// among authors, categories, citedpapers, sections and htmlresponse
+func (s *MemoryStore) DeleteEdges(uid string, predicates []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.articles[uid]
+	if !ok {
+		return ErrNotFound
+	}
+	for _, predicate := range predicates {
+		switch predicate {
+		case "authors":
+			for _, to := range a.authors {
+				s.articlesOf[to] = removeUID(s.articlesOf[to], uid)
+			}
+			a.authors = nil
+		case "categories":
+			for _, to := range a.categories {
+				s.inCategory[to] = removeUID(s.inCategory[to], uid)
+			}
+			a.categories = nil
+		case "citedpapers":
+			for _, to := range a.cited {
+				s.citedBy[to] = removeUID(s.citedBy[to], uid)
+			}
+			a.cited = nil
+		case "sections":
+			a.Sections = nil
+		case "htmlresponse":
+			a.HTMLResponse = ""
+		default:
+			return fmt.Errorf("Unknown predicate %s", predicate)
+		}
+	}
+	return nil
+}
+
+// AddCommunity stores a community, replacing the one its members were in
+func (s *MemoryStore) AddCommunity(community models.Community) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	uid := s.newUID()
+	stored := community
+	stored.UID = uid
+	stored.Members = nil
+	for _, member := range community.Members {
+		to := s.addAuthor(member)
+		stored.Members = append(stored.Members, models.Author{UID: to})
+		s.communityOf[to] = uid
+	}
+	s.communities[uid] = &stored
+	return uid, nil
+}
+
+// lookup returns the UID indexed by a value
+func (s *MemoryStore) lookup(index map[string]string, value string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	uid, ok := index[value]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return uid, nil
+}
+
+// AuthorUID gives the UID of the author with a name
+func (s *MemoryStore) AuthorUID(name string) (string, error) {
+	return s.lookup(s.byName, name)
+}
+
+// ArticleUID gives the UID of the article with a title
+func (s *MemoryStore) ArticleUID(title string) (string, error) {
+	return s.lookup(s.byTitle, title)
+}
+
+// ArticleUIDByArXivID gives the UID of the article with an arXiv ID
+func (s *MemoryStore) ArticleUIDByArXivID(arXivID string) (string, error) {
+	return s.lookup(s.byArXivID, arXivID)
+}
+
+// CrawledArticleUID gives the UID of the article with an arXiv ID whose
+// page was stored, and not only its ID as a cited paper
+func (s *MemoryStore) CrawledArticleUID(arXivID string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	uid, ok := s.byArXivID[arXivID]
+	if !ok || s.articles[uid].Title == "" {
+		return "", ErrNotFound
+	}
+	return uid, nil
+}
+
+// CategoryUID gives the UID of the category with a code
+func (s *MemoryStore) CategoryUID(code string) (string, error) {
+	return s.lookup(s.byCode, code)
+}
+
+// article returns a stored article as the Store returns it, with its
+// authors and categories but not its other edges
+func (s *MemoryStore) article(uid string) models.Article {
+	a := s.articles[uid]
+	article := a.Article
+	article.Sections = nil
+	article.Authors = nil
+	article.Categories = nil
+	for _, to := range a.authors {
+		author := s.authors[to]
+		article.Authors = append(article.Authors, authorMetrics(author))
+	}
+	for _, to := range a.categories {
+		article.Categories = append(article.Categories, *s.categories[to])
+	}
+	return article
+}
+
+// newestFirst returns the articles with the UIDs, the newest first, at
+// most limit after offset, all of them when limit is 0
+func (s *MemoryStore) newestFirst(uids []string, offset, limit int) []models.Article {
+	articles := make([]models.Article, len(uids))
+	for i, uid := range uids {
+		articles[i] = s.article(uid)
+	}
+	sort.SliceStable(articles, func(i, j int) bool {
+		return articles[i].SubmissionDate.After(articles[j].SubmissionDate)
+	})
+	if offset >= len(articles) {
+		return nil
+	}
+	articles = articles[offset:]
+	if limit > 0 && limit < len(articles) {
+		articles = articles[:limit]
+	}
+	return articles
+}
+
+// Article returns the article with an arXiv ID
+func (s *MemoryStore) Article(arXivID string) (models.Article, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	uid, ok := s.byArXivID[arXivID]
+	if !ok {
+		return models.Article{}, ErrNotFound
+	}
+	return s.article(uid), nil
+}
+
+// articleEdge returns a page of the articles linked to an article
+func (s *MemoryStore) articleEdge(arXivID string, edge func(uid string) []string, offset, limit int) ([]models.Article, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	uid, ok := s.byArXivID[arXivID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return s.newestFirst(edge(uid), offset, limit), nil
+}
+
+// Citations returns the articles citing an article
+func (s *MemoryStore) Citations(arXivID string, offset, limit int) ([]models.Article, error) {
+	return s.articleEdge(arXivID, func(uid string) []string { return s.citedBy[uid] }, offset, limit)
+}
+
+// References returns the articles cited by an article
+func (s *MemoryStore) References(arXivID string, offset, limit int) ([]models.Article, error) {
+	return s.articleEdge(arXivID, func(uid string) []string { return s.articles[uid].cited }, offset, limit)
+}
+
+// Author returns the author with a name
+func (s *MemoryStore) Author(name string) (models.Author, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	uid, ok := s.byName[name]
+	if !ok {
+		return models.Author{}, ErrNotFound
+	}
+	return *s.authors[uid], nil
+}
+
+// AuthorArticles returns the articles of an author, newest first
+func (s *MemoryStore) AuthorArticles(name string, offset, limit int) ([]models.Article, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	uid, ok := s.byName[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return s.newestFirst(s.articlesOf[uid], offset, limit), nil
+}
+
+// Coauthors returns the coauthors of an author, most frequent first
+func (s *MemoryStore) Coauthors(name string, offset, limit int) ([]Coauthor, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	uid, ok := s.byName[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	coauthors := CountCoauthors(uid, s.newestFirst(s.articlesOf[uid], 0, 0))
+	return paginate(coauthors, offset, limit), nil
+}
+
+// AuthorCommunity returns the community of an author with its members
+func (s *MemoryStore) AuthorCommunity(name string) (models.Community, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	uid, ok := s.byName[name]
+	if !ok {
+		return models.Community{}, ErrNotFound
+	}
+	c, ok := s.community(uid)
+	if !ok {
+		return models.Community{}, ErrNotFound
+	}
+	return c, nil
+}
+
+// AuthorCommunities returns by UID the communities of the authors
+func (s *MemoryStore) AuthorCommunities(uids []string) (map[string]models.Community, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	communities := make(map[string]models.Community, len(uids))
+	for _, uid := range uids {
+		if c, ok := s.community(uid); ok {
+			communities[uid] = c
+		}
+	}
+	return communities, nil
+}
+
+// community returns the community of the author with uid, with at most
+// MaxLinked members
+func (s *MemoryStore) community(uid string) (models.Community, bool) {
+	community, ok := s.communities[s.communityOf[uid]]
+	if !ok {
+		return models.Community{}, false
+	}
+	c := *community
+	c.Members = nil
+	for _, member := range community.Members {
+		if len(c.Members) == MaxLinked {
+			break
+		}
+		author := s.authors[member.UID]
+		c.Members = append(c.Members, authorMetrics(author))
+	}
+	return c, true
+}
+
+// matches tells whether a stored article passes the filters
+func (s *MemoryStore) matches(a *memoryArticle, filters SearchFilters) bool {
+	if !filters.From.IsZero() && a.SubmissionDate.Before(filters.From) {
+		return false
+	}
+	if !filters.To.IsZero() && a.SubmissionDate.After(filters.To) {
+		return false
+	}
+	if filters.Author != "" {
+		found := false
+		for _, to := range a.authors {
+			found = found || s.authors[to].Name == filters.Author
+		}
+		if !found {
+			return false
+		}
+	}
+	if filters.Category != "" {
+		found := false
+		for _, to := range a.categories {
+			code := s.categories[to].Code
+			found = found || code == filters.Category || strings.HasPrefix(code, filters.Category+".")
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// hasType tells whether a node has a type, as type() in DQL
+func hasType(types []string, name string) bool {
+	for _, t := range types {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// filtered returns the UIDs of the articles passing the filters
+func (s *MemoryStore) filtered(filters SearchFilters) ([]string, error) {
+	if err := validateCategory(filters); err != nil {
+		return nil, err
+	}
+	var uids []string
+	for uid, a := range s.articles {
+		if hasType(a.DType, "Article") && s.matches(a, filters) {
+			uids = append(uids, uid)
+		}
+	}
+	sort.Slice(uids, func(i, j int) bool { return uidOrder(uids[i], uids[j]) })
+	return uids, nil
+}
+
+// Search returns the articles whose title, abstract or sections share a
+// term with the query, ranked as by Dgraph
+func (s *MemoryStore) Search(query string, filters SearchFilters) ([]SearchResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, &ValidationError{Field: "query"}
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	uids, err := s.filtered(filters)
+	if err != nil {
+		return nil, err
+	}
+	terms := SearchTerms(query)
+	hits := func(text string) int { return termHits(terms, text) }
+	var results []SearchResult
+	for _, uid := range uids {
+		a := s.articles[uid]
+		bodyHits := 0
+		for _, section := range a.Sections {
+			if hits(section.Text) > 0 {
+				bodyHits++
+			}
+		}
+		if hits(a.Title)+hits(a.Abstract)+bodyHits == 0 {
+			continue
+		}
+		results = append(results, SearchResult{Article: s.article(uid), BodyHits: bodyHits})
+	}
+	return pageResults(Rank(query, results), filters), nil
+}
+
+// Articles returns the articles matching the filters, newest first
+func (s *MemoryStore) Articles(filters SearchFilters) ([]models.Article, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	uids, err := s.filtered(filters)
+	if err != nil {
+		return nil, err
+	}
+	limit := filters.Limit
+	if limit <= 0 {
+		limit = MaxLinked
+	}
+	return s.newestFirst(uids, filters.Offset, limit), nil
+}
+
+// Category returns the category with a code
+func (s *MemoryStore) Category(code string) (models.Category, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	uid, ok := s.byCode[code]
+	if !ok {
+		return models.Category{}, ErrNotFound
+	}
+	return *s.categories[uid], nil
+}
+
+// Linked returns by UID the articles linked to the nodes with the UIDs
+func (s *MemoryStore) Linked(edge string, uids []string) (map[string][]models.Article, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var edges func(uid string) []string
+	switch edge {
+	case AuthorArticlesEdge:
+		edges = func(uid string) []string { return s.articlesOf[uid] }
+	case CategoryArticlesEdge:
+		edges = func(uid string) []string { return s.inCategory[uid] }
+	case CitationsEdge:
+		edges = func(uid string) []string { return s.citedBy[uid] }
+	case ReferencesEdge:
+		edges = func(uid string) []string {
+			if a, ok := s.articles[uid]; ok {
+				return a.cited
+			}
+			return nil
+		}
+	default:
+		return nil, fmt.Errorf("Unknown edge %q", edge)
+	}
+	linked := make(map[string][]models.Article, len(uids))
+	for _, uid := range uids {
+		if to := edges(uid); len(to) > 0 {
+			linked[uid] = s.newestFirst(to, 0, MaxLinked)
+		}
+	}
+	return linked, nil
+}
+
+// ArticlesAfter returns the articles following the UID after, at most
+// first, with the UIDs and arXiv IDs of their cited papers
+func (s *MemoryStore) ArticlesAfter(after string, first int) ([]models.Article, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var uids []string
+	for uid := range s.articles {
+		if uidOrder(after, uid) {
+			uids = append(uids, uid)
+		}
+	}
+	sort.Slice(uids, func(i, j int) bool { return uidOrder(uids[i], uids[j]) })
+	if first < len(uids) {
+		uids = uids[:first]
+	}
+	articles := make([]models.Article, len(uids))
+	for i, uid := range uids {
+		articles[i] = s.article(uid)
+		for _, to := range s.articles[uid].cited {
+			cited := s.articles[to]
+			articles[i].CitedPapers = append(articles[i].CitedPapers, models.Article{UID: cited.UID, ArXivID: cited.ArXivID})
+		}
+	}
+	return articles, nil
+}
+
+// authorMetrics returns an author with its name and metrics but not its
+// edges, as nested in an article or a community
+func authorMetrics(author *models.Author) models.Author {
+	return models.Author{
+		UID:           author.UID,
+		Name:          author.Name,
+		URL:           author.URL,
+		CitationCount: author.CitationCount,
+		HIndex:        author.HIndex,
+		I10Index:      author.I10Index,
+	}
+}
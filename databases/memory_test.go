@@ -0,0 +1,179 @@
+package databases
+
+import (
+	"log"
+	"testing"
+
+	"pandor/models"
+)
+
+// The MemoryStore and the SQLStore are written like Dgraph
+var (
+	_ Graph  = (*DgraphStore)(nil)
+	_ Store  = (*MemoryStore)(nil)
+	_ Writer = (*MemoryStore)(nil)
+	_ Graph  = (*MemoryStore)(nil)
+	_ Store  = (*SQLStore)(nil)
+	_ Writer = (*SQLStore)(nil)
+	_ Graph  = (*SQLStore)(nil)
+)
+
+// storeGraph stores two articles of Lewis_G, the newest citing the oldest
+func storeGraph(s Writer) {
+	lewis := models.Author{
+		UID:           models.FormatUID("Lewis_G"),
+		Name:          "Lewis_G",
+		URL:           "https://export.arxiv.org/find/astro-ph/1/au:+Lewis_G/0/1/0/all/0/1",
+		CitationCount: 12,
+		HIndex:        3,
+		I10Index:      1,
+		DType:         []string{"Author"},
+	}
+	add := func(article models.Article) {
+		if _, err := s.AddArticle(article); err != nil {
+			log.Fatal(err)
+		}
+	}
+	add(models.Article{
+		UID:            models.FormatUID("Globular clusters in the outer halo of M31: the survey"),
+		ArXivID:        "0801.0002",
+		Title:          "Globular clusters in the outer halo of M31: the survey",
+		Abstract:       "We report the discovery of 40 new globular clusters in the halo of M31.",
+		SubmissionDate: models.FormatTime("2007-12-28T00:00:00.000Z"),
+		Authors:        []models.Author{lewis},
+		Categories:     []models.Category{{Code: "astro-ph.GA", Name: "Astrophysics of Galaxies", DType: []string{"Category"}}},
+		DType:          []string{"Article"},
+	})
+	add(models.Article{
+		UID:            models.FormatUID("Another Article from the same guy"),
+		ArXivID:        "0801.0003",
+		Title:          "Another Article from the same guy",
+		Abstract:       "Cluster Stuff",
+		SubmissionDate: models.FormatTime("2007-12-29T00:00:00.000Z"),
+		Authors: []models.Author{lewis, {
+			Name:  "Huxor_A",
+			DType: []string{"Author"},
+		}},
+		CitedPapers: []models.Article{{ArXivID: "0801.0002"}, {ArXivID: "0712.0001"}},
+		DType:       []string{"Article"},
+	})
+}
+
+// checkStore checks the lookups and the reverse edges of a store written
+// by storeGraph
+func checkStore(s interface {
+	Store
+	Writer
+	ArticleUID(title string) (string, error)
+	AuthorUID(name string) (string, error)
+	ArticlesAfter(after string, first int) ([]models.Article, error)
+}) {
+	storeGraph(s)
+
+	// Adding an article again merges it with the one of the same title
+	uid, err := s.ArticleUID("Globular clusters in the outer halo of M31: the survey")
+	if err != nil {
+		log.Fatal(err)
+	}
+	again, err := s.AddArticle(models.Article{
+		Title:     "Globular clusters in the outer halo of M31: the survey",
+		CrawledAt: models.FormatTime("2020-03-08T08:44:03.484Z"),
+		Authors:   []models.Author{{Name: "Lewis_G"}},
+	})
+	if err != nil || again != uid {
+		log.Fatalf("The article should be merged into %s, not %s", uid, again)
+	}
+	article, err := s.Article("0801.0002")
+	if err != nil || article.Abstract == "" || article.CrawledAt.IsZero() || len(article.Authors) != 1 {
+		log.Fatalf("Wrong merged article %+v %v", article, err)
+	}
+	// The authors of an article come with their metrics
+	if a := article.Authors[0]; a.CitationCount != 12 || a.HIndex != 3 || a.I10Index != 1 {
+		log.Fatalf("Wrong metrics of the author %+v", a)
+	}
+	if _, err = s.AuthorUID("Nobody"); err != ErrNotFound {
+		log.Fatalf("An unknown author should not be found, got %v", err)
+	}
+
+	// Reverse edges
+	articles, err := s.AuthorArticles("Lewis_G", 0, 10)
+	if err != nil || len(articles) != 2 || articles[0].ArXivID != "0801.0003" {
+		log.Fatalf("Wrong articles of the author %v %v", articles, err)
+	}
+	citations, err := s.Citations("0801.0002", 0, 10)
+	if err != nil || len(citations) != 1 || citations[0].ArXivID != "0801.0003" {
+		log.Fatalf("Wrong citations %v %v", citations, err)
+	}
+	references, err := s.References("0801.0003", 0, 10)
+	if err != nil || len(references) != 2 {
+		log.Fatalf("Wrong references %v %v", references, err)
+	}
+	coauthors, err := s.Coauthors("Lewis_G", 0, 10)
+	if err != nil || len(coauthors) != 1 || coauthors[0].Name != "Huxor_A" || coauthors[0].Shared != 1 {
+		log.Fatalf("Wrong coauthors %v %v", coauthors, err)
+	}
+	author, err := s.Author("Lewis_G")
+	if err != nil {
+		log.Fatal(err)
+	}
+	linked, err := s.Linked(AuthorArticlesEdge, []string{author.UID})
+	if err != nil || len(linked[author.UID]) != 2 {
+		log.Fatalf("Wrong linked articles %v %v", linked, err)
+	}
+	if _, err = s.Linked("title", nil); err == nil {
+		log.Fatal("Unknown edges should be rejected")
+	}
+
+	// The cited paper which was not crawled is not an article yet
+	all, err := s.Articles(SearchFilters{})
+	if err != nil || len(all) != 2 {
+		log.Fatalf("Wrong articles %v %v", all, err)
+	}
+	galaxies, err := s.Articles(SearchFilters{Category: "astro-ph"})
+	if err != nil || len(galaxies) != 1 || galaxies[0].Categories[0].Code != "astro-ph.GA" {
+		log.Fatalf("Wrong articles of the category %v %v", galaxies, err)
+	}
+	results, err := s.Search("globular clusters", SearchFilters{Author: "Lewis_G"})
+	if err != nil || len(results) != 2 || results[0].ArXivID != "0801.0002" {
+		log.Fatalf("Wrong search results %v %v", results, err)
+	}
+	// The page is cut once all the matches are ranked, with its edges
+	results, err = s.Search("globular clusters", SearchFilters{Offset: 1, Limit: 1})
+	if err != nil || len(results) != 1 || results[0].ArXivID != "0801.0003" || len(results[0].Authors) != 2 {
+		log.Fatalf("Wrong page of search results %v %v", results, err)
+	}
+	// The terms match whole words, port is only found in report
+	results, err = s.Search("port", SearchFilters{})
+	if err != nil || len(results) != 0 {
+		log.Fatalf("A term inside a word should not match %v %v", results, err)
+	}
+
+	page, err := s.ArticlesAfter("0x0", 100)
+	if err != nil || len(page) != 3 {
+		log.Fatalf("Wrong page %v %v", page, err)
+	}
+	for _, a := range page {
+		if a.ArXivID == "0801.0003" && len(a.CitedPapers) != 2 {
+			log.Fatalf("Wrong cited papers %v", a.CitedPapers)
+		}
+	}
+
+	_, err = s.AddCommunity(models.Community{Label: "Lewis_G", Size: 2, Members: []models.Author{{Name: "Lewis_G"}, {Name: "Huxor_A"}}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	community, err := s.AuthorCommunity("Huxor_A")
+	if err != nil || community.Label != "Lewis_G" || len(community.Members) != 2 || community.Members[0].Name != "Lewis_G" {
+		log.Fatalf("Wrong community %+v %v", community, err)
+	}
+	// The communities of many authors are loaded at once
+	communities, err := s.AuthorCommunities([]string{community.Members[0].UID, community.Members[1].UID, "0xfffff"})
+	if err != nil || len(communities) != 2 || communities[community.Members[1].UID].Label != "Lewis_G" ||
+		communities[community.Members[1].UID].Members[0].Name != "Lewis_G" {
+		log.Fatalf("Wrong communities %+v %v", communities, err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	checkStore(NewMemoryStore())
+}
@@ -0,0 +1,203 @@
+package databases
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v2"
+)
+
+// Builder builds a DQL query on the nodes matching a root function, such as
+// the ones with a given value of a predicate. The values are passed as
+// variables, the predicates and the fields are written as is.
+type Builder struct {
+	function  string
+	variables map[string]string
+	fields    []string
+	edges     []string
+	filters   []string
+	first     int
+	offset    int
+	after     string
+	order     string
+}
+
+// FindBy queries the nodes with a value of a predicate, such as the
+// article with an arXiv ID
+func FindBy(predicate, value string) *Builder {
+	b := &Builder{variables: make(map[string]string)}
+	b.function = fmt.Sprintf("eq(%s, %s)", predicate, b.variable(value))
+	return b
+}
+
+// FindType queries the nodes of a type
+func FindType(name string) *Builder {
+	return &Builder{function: fmt.Sprintf("type(%s)", name), variables: make(map[string]string)}
+}
+
+// variable adds a value to the variables and returns its name
+func (b *Builder) variable(value string) string {
+	name := fmt.Sprintf("$v%d", len(b.variables))
+	b.variables[name] = value
+	return name
+}
+
+// Fields adds fields to return, uid when none is given. A field can be a
+// nested block such as "authors { uid name }".
+func (b *Builder) Fields(fields ...string) *Builder {
+	b.fields = append(b.fields, fields...)
+	return b
+}
+
+// Expand adds edges to return with all the predicates of their nodes
+func (b *Builder) Expand(edges ...string) *Builder {
+	b.edges = append(b.edges, edges...)
+	return b
+}
+
+// Filter keeps the nodes with a value of a predicate
+func (b *Builder) Filter(predicate, value string) *Builder {
+	b.filters = append(b.filters, fmt.Sprintf("eq(%s, %s)", predicate, b.variable(value)))
+	return b
+}
+
+// Has keeps the nodes which have a predicate
+func (b *Builder) Has(predicate string) *Builder {
+	b.filters = append(b.filters, fmt.Sprintf("has(%s)", predicate))
+	return b
+}
+
+// Page returns at most first nodes after skipping offset ones, first 0
+// returns them all
+func (b *Builder) Page(first, offset int) *Builder {
+	b.first, b.offset = first, offset
+	return b
+}
+
+// After returns the nodes after a UID, to paginate over many nodes
+func (b *Builder) After(uid string) *Builder {
+	b.after = uid
+	return b
+}
+
+// OrderAsc sorts the nodes by a predicate, the smallest first
+func (b *Builder) OrderAsc(predicate string) *Builder {
+	b.order = "orderasc: " + predicate
+	return b
+}
+
+// OrderDesc sorts the nodes by a predicate, the largest first
+func (b *Builder) OrderDesc(predicate string) *Builder {
+	b.order = "orderdesc: " + predicate
+	return b
+}
+
+// String returns the query, its nodes are in a block named nodes
+func (b *Builder) String() string {
+	var declarations []string
+	for i := 0; i < len(b.variables); i++ {
+		declarations = append(declarations, fmt.Sprintf("$v%d: string", i))
+	}
+	arguments := []string{"func: " + b.function}
+	if b.order != "" {
+		arguments = append(arguments, b.order)
+	}
+	if b.first > 0 {
+		arguments = append(arguments, fmt.Sprintf("first: %d", b.first))
+	}
+	if b.offset > 0 {
+		arguments = append(arguments, fmt.Sprintf("offset: %d", b.offset))
+	}
+	if b.after != "" {
+		arguments = append(arguments, "after: "+b.after)
+	}
+
+	var q strings.Builder
+	if len(declarations) > 0 {
+		fmt.Fprintf(&q, "query Nodes(%s)", strings.Join(declarations, ", "))
+	}
+	q.WriteString("{\n")
+	fmt.Fprintf(&q, "\tnodes(%s)", strings.Join(arguments, ", "))
+	if len(b.filters) > 0 {
+		fmt.Fprintf(&q, " @filter(%s)", strings.Join(b.filters, " AND "))
+	}
+	q.WriteString("{\n")
+	fields := b.fields
+	if len(fields) == 0 {
+		fields = []string{"uid"}
+	}
+	for _, field := range fields {
+		fmt.Fprintf(&q, "\t\t%s\n", field)
+	}
+	for _, edge := range b.edges {
+		fmt.Fprintf(&q, "\t\t%s { uid expand(_all_) }\n", edge)
+	}
+	q.WriteString("\t}\n}")
+	return q.String()
+}
+
+// Variables returns the values of the variables of the query
+func (b *Builder) Variables() map[string]string {
+	return b.variables
+}
+
+// nodes runs the query and returns the JSON of its nodes
+func (b *Builder) nodes(dg *dgo.Dgraph) ([]json.RawMessage, error) {
+	resp, err := dg.NewReadOnlyTxn().QueryWithVars(context.Background(), b.String(), b.variables)
+	if err != nil {
+		return nil, err
+	}
+	return decodeNodes(resp.Json)
+}
+
+// decodeNodes returns the JSON of the nodes of a query result
+func decodeNodes(body []byte) ([]json.RawMessage, error) {
+	var r struct {
+		Nodes []json.RawMessage `json:"nodes"`
+	}
+	err := json.Unmarshal(body, &r)
+	return r.Nodes, err
+}
+
+// All decodes the nodes into v, a pointer to a slice such as
+// *[]models.Article
+func (b *Builder) All(v interface{}, dg *dgo.Dgraph) error {
+	nodes, err := b.nodes(dg)
+	if err != nil {
+		return err
+	}
+	return decodeAll(nodes, v)
+}
+
+func decodeAll(nodes []json.RawMessage, v interface{}) error {
+	if nodes == nil {
+		nodes = []json.RawMessage{}
+	}
+	body, err := json.Marshal(nodes)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// One decodes the first node into v, a pointer such as *models.Article. It
+// returns ErrNotFound when no node matches.
+func (b *Builder) One(v interface{}, dg *dgo.Dgraph) error {
+	if b.first == 0 {
+		b.first = 1
+	}
+	nodes, err := b.nodes(dg)
+	if err != nil {
+		return err
+	}
+	return decodeOne(nodes, v)
+}
+
+func decodeOne(nodes []json.RawMessage, v interface{}) error {
+	if len(nodes) == 0 {
+		return ErrNotFound
+	}
+	return json.Unmarshal(nodes[0], v)
+}
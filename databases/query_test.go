@@ -0,0 +1,65 @@
+package databases
+
+import (
+	"log"
+	"strings"
+	"testing"
+
+	"pandor/models"
+)
+
+func TestBuilder(t *testing.T) {
+	b := FindBy("name", "Lewis_G").
+		Fields("uid", "name").
+		Expand("~authors").
+		Filter("url", "https://arxiv.org/a/lewis_g_1").
+		Has("hindex").
+		OrderDesc("citationcount").
+		Page(10, 20)
+	dql := b.String()
+	for _, expected := range []string{
+		"query Nodes($v0: string, $v1: string)",
+		"nodes(func: eq(name, $v0), orderdesc: citationcount, first: 10, offset: 20) @filter(eq(url, $v1) AND has(hindex))",
+		"~authors { uid expand(_all_) }",
+	} {
+		if !strings.Contains(dql, expected) {
+			log.Fatalf("Missing %s in the query:\n%s", expected, dql)
+		}
+	}
+	variables := b.Variables()
+	if variables["$v0"] != "Lewis_G" || variables["$v1"] != "https://arxiv.org/a/lewis_g_1" {
+		log.Fatalf("Wrong variables %v", variables)
+	}
+
+	dql = FindType("Article").After("0x2a").String()
+	if !strings.HasPrefix(dql, "{\n\tnodes(func: type(Article), after: 0x2a){\n\t\tuid\n") {
+		log.Fatalf("Wrong query without variables:\n%s", dql)
+	}
+}
+
+func TestDecodeNodes(t *testing.T) {
+	nodes, err := decodeNodes([]byte(`{"nodes": [{"uid": "0x1", "arxivid": "0801.0001"}, {"uid": "0x2"}]}`))
+	if err != nil {
+		log.Fatal(err)
+	}
+	var articles []models.Article
+	if err = decodeAll(nodes, &articles); err != nil || len(articles) != 2 || articles[0].ArXivID != "0801.0001" {
+		log.Fatalf("Wrong articles %v %v", articles, err)
+	}
+	var article models.Article
+	if err = decodeOne(nodes, &article); err != nil || article.UID != "0x1" {
+		log.Fatalf("Wrong article %v %v", article, err)
+	}
+
+	nodes, err = decodeNodes([]byte(`{"nodes": []}`))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = decodeOne(nodes, &article); err != ErrNotFound {
+		log.Fatalf("No node should be not found, got %v", err)
+	}
+	articles = nil
+	if err = decodeAll(nodes, &articles); err != nil || articles == nil || len(articles) != 0 {
+		log.Fatalf("No node should give an empty slice, got %v %v", articles, err)
+	}
+}
@@ -0,0 +1,298 @@
+package databases
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"pandor/models"
+
+	"github.com/dgraph-io/dgo/v2"
+)
+
+// Weights of the fields in the ranking
+var (
+	TitleWeight    = 3.0
+	AbstractWeight = 1.0
+	BodyWeight     = 0.5
+	PageRankWeight = 0.2
+)
+
+// SearchFilters restricts the results of a search
+type SearchFilters struct {
+	From     time.Time // submitted on or after, ignored when zero
+	To       time.Time // submitted on or before, ignored when zero
+	Category string    // such as astro-ph.GA, or astro-ph for all its subclasses
+	Author   string    // name as stored, such as Lewis_G
+	Offset   int
+	Limit    int // all the results when zero
+}
+
+// SearchResult is an article matching a search with its score
+type SearchResult struct {
+	models.Article
+	BodyHits int     `json:"bodyhits"`
+	Score    float64 `json:"score"`
+}
+
+// categoryPattern validates the category of the filters, which is written
+// in the query as a regular expression
+var categoryPattern = regexp.MustCompile(`^[a-zA-Z\-]+(\.[A-Za-z\-]+)?$`)
+
+// ValidationError is returned by the stores for an invalid search, such as
+// an empty query or a malformed category
+type ValidationError struct {
+	Field string // query or category
+	Value string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("Empty search %s", e.Field)
+	}
+	return fmt.Sprintf("Invalid %s %q", e.Field, e.Value)
+}
+
+// validateCategory checks the category of the filters, if any
+func validateCategory(filters SearchFilters) error {
+	if filters.Category != "" && !categoryPattern.MatchString(filters.Category) {
+		return &ValidationError{Field: "category", Value: filters.Category}
+	}
+	return nil
+}
+
+// filterClauses holds the parts of a DQL query implementing SearchFilters
+type filterClauses struct {
+	variables    map[string]string
+	declarations []string
+	blocks       []string
+	conditions   []string
+}
+
+// newFilterClauses translates the date, author and category filters. Only
+// the filters which are set appear in the query, Dgraph rejects unused
+// variables.
+func newFilterClauses(filters SearchFilters) (*filterClauses, error) {
+	c := &filterClauses{
+		variables:  map[string]string{},
+		conditions: []string{"type(Article)"},
+	}
+	if !filters.From.IsZero() {
+		c.variables["$from"] = filters.From.Format(time.RFC3339)
+		c.declarations = append(c.declarations, "$from: string")
+		c.conditions = append(c.conditions, "ge(submissiondate, $from)")
+	}
+	if !filters.To.IsZero() {
+		c.variables["$to"] = filters.To.Format(time.RFC3339)
+		c.declarations = append(c.declarations, "$to: string")
+		c.conditions = append(c.conditions, "le(submissiondate, $to)")
+	}
+	if filters.Author != "" {
+		c.variables["$author"] = filters.Author
+		c.declarations = append(c.declarations, "$author: string")
+		c.blocks = append(c.blocks, `var(func: eq(name, $author)) { byAuthor as ~authors }`)
+		c.conditions = append(c.conditions, "uid(byAuthor)")
+	}
+	if filters.Category != "" {
+		if err := validateCategory(filters); err != nil {
+			return nil, err
+		}
+		c.blocks = append(c.blocks, fmt.Sprintf(
+			`var(func: regexp(categorycode, /^%s(\..+)?$/)) { byCategory as ~categories }`,
+			regexp.QuoteMeta(filters.Category)))
+		c.conditions = append(c.conditions, "uid(byCategory)")
+	}
+	return c, nil
+}
+
+// searchQuery builds the DQL query of a search and its variables
+func searchQuery(query string, filters SearchFilters) (string, map[string]string, error) {
+	c, err := newFilterClauses(filters)
+	if err != nil {
+		return "", nil, err
+	}
+	c.variables["$query"] = query
+	declarations := append([]string{"$query: string"}, c.declarations...)
+	blocks := append([]string{
+		`var(func: anyoftext(title, $query)) { byTitle as uid }`,
+		`var(func: anyoftext(abstract, $query)) { byAbstract as uid }`,
+		`var(func: anyoftext(sectiontext, $query)) { byBody as ~sections }`,
+	}, c.blocks...)
+
+	// Every match is returned to be ranked, without its edges
+	dql := fmt.Sprintf(`query Search(%s) {
+		%s
+		search(func: uid(byTitle, byAbstract, byBody)) @filter(%s) {
+			uid
+			arxivid
+			title
+			abstract
+			submissiondate
+			pagerank
+			bodyhits: count(sections @filter(anyoftext(sectiontext, $query)))
+		}
+	}`, strings.Join(declarations, ", "), strings.Join(blocks, "\n\t\t"),
+		strings.Join(c.conditions, " AND "))
+	return dql, c.variables, nil
+}
+
+// Search returns the articles whose title, abstract or extracted sections
+// match the query, from the best ranked to the worst. All the matches are
+// ranked, the authors and the categories are only loaded for the page.
+func Search(query string, filters SearchFilters, dg *dgo.Dgraph) ([]SearchResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, &ValidationError{Field: "query"}
+	}
+	dql, variables, err := searchQuery(query, filters)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := dg.NewReadOnlyTxn().QueryWithVars(context.Background(), dql, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	type Root struct {
+		Results []SearchResult `json:"search"`
+	}
+	var r Root
+	err = json.Unmarshal(resp.Json, &r)
+	if err != nil {
+		return nil, err
+	}
+
+	results := pageResults(Rank(query, r.Results), filters)
+	return results, searchEdges(results, dg)
+}
+
+// pageResults returns the page of the ranked results set by the offset
+// and the limit of the filters
+func pageResults(results []SearchResult, filters SearchFilters) []SearchResult {
+	if filters.Offset >= len(results) {
+		return nil
+	}
+	results = results[filters.Offset:]
+	if filters.Limit > 0 && filters.Limit < len(results) {
+		results = results[:filters.Limit]
+	}
+	return results
+}
+
+// searchEdges sets the authors and the categories of a page of results
+func searchEdges(results []SearchResult, dg *dgo.Dgraph) error {
+	if len(results) == 0 {
+		return nil
+	}
+	uids := make([]string, len(results))
+	for i, r := range results {
+		uids[i] = r.UID
+	}
+	query := fmt.Sprintf(`{
+		nodes(func: uid(%s)) {
+			uid
+			authors { name }
+			categories { categorycode categoryname }
+		}
+	}`, strings.Join(uids, ", "))
+	resp, err := dg.NewReadOnlyTxn().Query(context.Background(), query)
+	if err != nil {
+		return err
+	}
+	nodes, err := decodeNodes(resp.Json)
+	if err != nil {
+		return err
+	}
+	var articles []models.Article
+	if err = decodeAll(nodes, &articles); err != nil {
+		return err
+	}
+	byUID := make(map[string]models.Article)
+	for _, a := range articles {
+		byUID[a.UID] = a
+	}
+	for i := range results {
+		results[i].Authors = byUID[results[i].UID].Authors
+		results[i].Categories = byUID[results[i].UID].Categories
+	}
+	return nil
+}
+
+// SearchTerms splits a query in lower case words
+func SearchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// termFrequency counts the words of text matching term. Dgraph stems the
+// fulltext index, so longer terms also match the words they prefix, such
+// as galaxy and galaxies.
+func termFrequency(term string, words []string) int {
+	stem := term
+	if len(stem) > 4 {
+		stem = stem[:len(stem)-1]
+	}
+	n := 0
+	for _, w := range words {
+		if w == term || (len(term) > 3 && strings.HasPrefix(w, stem)) {
+			n++
+		}
+	}
+	return n
+}
+
+// termHits counts the words of text matching the terms
+func termHits(terms []string, text string) int {
+	words, n := SearchTerms(text), 0
+	for _, term := range terms {
+		n += termFrequency(term, words)
+	}
+	return n
+}
+
+// Rank scores the results against the query and sorts them. Each term
+// counts for the logarithm of its frequency in the title and the abstract,
+// the sections add the logarithm of their number of hits, and the score is
+// scaled by the share of the terms found and boosted by the logarithm of
+// the PageRank.
+func Rank(query string, results []SearchResult) []SearchResult {
+	terms := SearchTerms(query)
+	for i := range results {
+		title := SearchTerms(results[i].Title)
+		abstract := SearchTerms(results[i].Abstract)
+		score, found := 0.0, 0
+		for _, term := range terms {
+			inTitle := termFrequency(term, title)
+			inAbstract := termFrequency(term, abstract)
+			if inTitle+inAbstract > 0 {
+				found++
+			}
+			score += TitleWeight*math.Log1p(float64(inTitle)) +
+				AbstractWeight*math.Log1p(float64(inAbstract))
+		}
+		score += BodyWeight * math.Log1p(float64(results[i].BodyHits))
+		if len(terms) > 0 {
+			score *= float64(1+found) / float64(1+len(terms))
+		}
+		score *= 1 + PageRankWeight*math.Log1p(results[i].PageRank)
+		results[i].Score = score
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if !a.SubmissionDate.Equal(b.SubmissionDate) {
+			return a.SubmissionDate.After(b.SubmissionDate)
+		}
+		return a.ArXivID < b.ArXivID
+	})
+	return results
+}
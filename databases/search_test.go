@@ -0,0 +1,87 @@
+package databases
+
+import (
+	"log"
+	"pandor/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRank(t *testing.T) {
+	results := []SearchResult{
+		{Article: models.Article{ArXivID: "1", Title: "Stellar streams", Abstract: "We study dwarf galaxies."}},
+		{Article: models.Article{ArXivID: "2", Title: "Dwarf galaxies in clusters", Abstract: "Dwarf galaxies are faint."}},
+		{Article: models.Article{ArXivID: "3", Title: "Cosmic rays"}, BodyHits: 2},
+	}
+	ranked := Rank("dwarf galaxy", results)
+	order := []string{}
+	for _, r := range ranked {
+		order = append(order, r.ArXivID)
+	}
+	if strings.Join(order, ",") != "2,1,3" {
+		log.Fatalf("Wrong ranking %v", order)
+	}
+	if ranked[2].Score <= 0 {
+		log.Fatalf("Body hits should score, got %f", ranked[2].Score)
+	}
+}
+
+func TestRankTies(t *testing.T) {
+	older := models.FormatTime("2007-01-01T00:00:00.000Z")
+	newer := models.FormatTime("2008-01-01T00:00:00.000Z")
+	results := []SearchResult{
+		{Article: models.Article{ArXivID: "1", Title: "Quasars", SubmissionDate: older}},
+		{Article: models.Article{ArXivID: "2", Title: "Quasars", SubmissionDate: newer}},
+	}
+	ranked := Rank("quasars", results)
+	if ranked[0].ArXivID != "2" {
+		log.Fatalf("The newest article should come first, got %s", ranked[0].ArXivID)
+	}
+}
+
+func TestSearchQuery(t *testing.T) {
+	dql, variables, err := searchQuery("dwarf", SearchFilters{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if strings.Contains(dql, "$from") || strings.Contains(dql, "byAuthor") || len(variables) != 1 {
+		log.Fatalf("Unset filters should not appear in the query:\n%s", dql)
+	}
+	if strings.Contains(dql, "first:") || strings.Contains(dql, "authors") {
+		log.Fatalf("Every match should be ranked, without its edges:\n%s", dql)
+	}
+
+	dql, variables, err = searchQuery("dwarf", SearchFilters{
+		From:     time.Date(2008, 1, 1, 0, 0, 0, 0, time.UTC),
+		Category: "astro-ph",
+		Author:   "Lewis_G",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, expected := range []string{"ge(submissiondate, $from)", "uid(byAuthor)", `/^astro-ph(\..+)?$/`} {
+		if !strings.Contains(dql, expected) {
+			log.Fatalf("Missing %s in the query:\n%s", expected, dql)
+		}
+	}
+	if variables["$from"] != "2008-01-01T00:00:00Z" || variables["$author"] != "Lewis_G" {
+		log.Fatalf("Wrong variables %v", variables)
+	}
+
+	_, _, err = searchQuery("dwarf", SearchFilters{Category: "astro-ph/ )"})
+	if err == nil {
+		log.Fatal("Invalid categories should be rejected")
+	}
+}
+
+func TestRankPageRank(t *testing.T) {
+	results := []SearchResult{
+		{Article: models.Article{ArXivID: "1", Title: "Quasars", PageRank: 0.5}},
+		{Article: models.Article{ArXivID: "2", Title: "Quasars", PageRank: 8}},
+	}
+	ranked := Rank("quasars", results)
+	if ranked[0].ArXivID != "2" || ranked[0].Score <= ranked[1].Score {
+		log.Fatalf("The article of highest PageRank should come first, got %s", ranked[0].ArXivID)
+	}
+}
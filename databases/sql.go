@@ -0,0 +1,1166 @@
+package databases
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"pandor/models"
+
+	// Pure-Go PostgreSQL driver, registered as pgx
+	_ "github.com/jackc/pgx/v4/stdlib"
+	// Pure-Go SQLite driver, registered as sqlite
+	_ "modernc.org/sqlite"
+)
+
+// sqlTables create the tables of the SQLStore. The statements are valid
+// for SQLite and PostgreSQL, except the type of the identifiers, IDENTIFIER.
+var sqlTables = []string{
+	`CREATE TABLE IF NOT EXISTS articles (
+		id IDENTIFIER,
+		arxivid TEXT UNIQUE,
+		title TEXT NOT NULL DEFAULT '',
+		abstract TEXT NOT NULL DEFAULT '',
+		submissiondate TEXT NOT NULL DEFAULT '',
+		crawledat TEXT NOT NULL DEFAULT '',
+		pdfurl TEXT NOT NULL DEFAULT '',
+		otherformaturl TEXT NOT NULL DEFAULT '',
+		metaurl TEXT NOT NULL DEFAULT '',
+		archiveref TEXT NOT NULL DEFAULT '',
+		pdfkey TEXT NOT NULL DEFAULT '',
+		pdfchecksum TEXT NOT NULL DEFAULT '',
+		fulltextkey TEXT NOT NULL DEFAULT '',
+		sourcekey TEXT NOT NULL DEFAULT '',
+		citationcount INTEGER NOT NULL DEFAULT 0,
+		citationvelocity DOUBLE PRECISION NOT NULL DEFAULT 0,
+		pagerank DOUBLE PRECISION NOT NULL DEFAULT 0,
+		article BOOLEAN NOT NULL DEFAULT FALSE
+	)`,
+	`CREATE INDEX IF NOT EXISTS articles_title ON articles (title)`,
+	`CREATE INDEX IF NOT EXISTS articles_submissiondate ON articles (submissiondate)`,
+	`CREATE TABLE IF NOT EXISTS authors (
+		id IDENTIFIER,
+		name TEXT NOT NULL UNIQUE,
+		url TEXT NOT NULL DEFAULT '',
+		citationcount INTEGER NOT NULL DEFAULT 0,
+		hindex INTEGER NOT NULL DEFAULT 0,
+		i10index INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE TABLE IF NOT EXISTS categories (
+		id IDENTIFIER,
+		code TEXT NOT NULL UNIQUE,
+		name TEXT NOT NULL DEFAULT ''
+	)`,
+	`CREATE TABLE IF NOT EXISTS sections (
+		article_id BIGINT NOT NULL REFERENCES articles (id),
+		position INTEGER NOT NULL,
+		name TEXT NOT NULL DEFAULT '',
+		text TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (article_id, position)
+	)`,
+	`CREATE TABLE IF NOT EXISTS article_authors (
+		article_id BIGINT NOT NULL REFERENCES articles (id),
+		author_id BIGINT NOT NULL REFERENCES authors (id),
+		position INTEGER NOT NULL,
+		PRIMARY KEY (article_id, author_id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS article_authors_author ON article_authors (author_id)`,
+	`CREATE TABLE IF NOT EXISTS article_categories (
+		article_id BIGINT NOT NULL REFERENCES articles (id),
+		category_id BIGINT NOT NULL REFERENCES categories (id),
+		PRIMARY KEY (article_id, category_id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS article_categories_category ON article_categories (category_id)`,
+	`CREATE TABLE IF NOT EXISTS citations (
+		citing_id BIGINT NOT NULL REFERENCES articles (id),
+		cited_id BIGINT NOT NULL REFERENCES articles (id),
+		PRIMARY KEY (citing_id, cited_id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS citations_cited ON citations (cited_id)`,
+	`CREATE TABLE IF NOT EXISTS communities (
+		id IDENTIFIER,
+		label TEXT NOT NULL DEFAULT '',
+		size INTEGER NOT NULL DEFAULT 0,
+		method TEXT NOT NULL DEFAULT ''
+	)`,
+	`CREATE TABLE IF NOT EXISTS community_members (
+		community_id BIGINT NOT NULL REFERENCES communities (id),
+		author_id BIGINT NOT NULL UNIQUE REFERENCES authors (id),
+		position INTEGER NOT NULL
+	)`,
+}
+
+// sqlIdentifiers are the types of the identifiers by driver
+var sqlIdentifiers = map[string]string{
+	"sqlite": "INTEGER PRIMARY KEY",
+	"pgx":    "BIGSERIAL PRIMARY KEY",
+}
+
+// sqlTime is the layout of the dates, in UTC and of fixed width so that
+// they sort as strings
+const sqlTime = "2006-01-02T15:04:05.000000000Z"
+
+func formatSQLTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(sqlTime)
+}
+
+func parseSQLTime(s string) time.Time {
+	t, _ := time.Parse(sqlTime, s)
+	return t
+}
+
+// sqlUID formats an identifier as a UID, so that the consumers of the Store
+// see the same UIDs as with Dgraph
+func sqlUID(id int64) string {
+	return fmt.Sprintf("0x%x", id)
+}
+
+// sqlID parses a UID written by sqlUID
+func sqlID(uid string) (int64, bool) {
+	if !uidPattern.MatchString(uid) {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(uid, "0x"), 16, 64)
+	return id, err == nil
+}
+
+// querier runs statements on a database or in a transaction
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// sqlArgs are the arguments of a statement
+type sqlArgs []interface{}
+
+// add appends an argument and returns its placeholder
+func (a *sqlArgs) add(v interface{}) string {
+	*a = append(*a, v)
+	return fmt.Sprintf("$%d", len(*a))
+}
+
+// in returns the placeholders of a list of identifiers
+func (a *sqlArgs) in(ids []int64) string {
+	placeholders := make([]string, len(ids))
+	for i, id := range ids {
+		placeholders[i] = a.add(id)
+	}
+	return strings.Join(placeholders, ", ")
+}
+
+// SQLStore is the Store of a relational database, SQLite or PostgreSQL. It
+// is written like the MemoryStore, and also walks the citations with
+// recursive queries.
+type SQLStore struct {
+	DB *sql.DB
+}
+
+// OpenSQLStore opens a database with a driver, sqlite or pgx for
+// PostgreSQL, and creates its tables
+func OpenSQLStore(driver, source string) (*SQLStore, error) {
+	identifier, ok := sqlIdentifiers[driver]
+	if !ok {
+		return nil, fmt.Errorf("Unknown SQL driver %q", driver)
+	}
+	db, err := sql.Open(driver, source)
+	if err != nil {
+		return nil, err
+	}
+	if driver == "sqlite" {
+		// SQLite writes one at a time, and each connection to :memory:
+		// would be another database
+		db.SetMaxOpenConns(1)
+	}
+	for _, table := range sqlTables {
+		if _, err = db.Exec(strings.Replace(table, "IDENTIFIER", identifier, 1)); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return &SQLStore{DB: db}, nil
+}
+
+// Close closes the database
+func (s *SQLStore) Close() error {
+	return s.DB.Close()
+}
+
+// findID returns the identifier selected by a query, 0 when there is none
+func findID(q querier, query string, args ...interface{}) (int64, error) {
+	var id int64
+	err := q.QueryRow(query, args...).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// update sets the columns of a row
+func update(q querier, table string, id int64, columns []string, values sqlArgs) error {
+	if len(columns) == 0 {
+		return nil
+	}
+	var set []string
+	for i, column := range columns {
+		set = append(set, fmt.Sprintf("%s = $%d", column, i+1))
+	}
+	values = append(values, id)
+	_, err := q.Exec(fmt.Sprintf("UPDATE %s SET %s WHERE id = $%d", table, strings.Join(set, ", "), len(values)), values...)
+	return err
+}
+
+// changes lists the columns of the non-zero values, as setting a JSON node
+// in Dgraph with omitempty
+type changes struct {
+	columns []string
+	values  sqlArgs
+}
+
+func (c *changes) set(column string, value interface{}, zero bool) {
+	if !zero {
+		c.columns = append(c.columns, column)
+		c.values = append(c.values, value)
+	}
+}
+
+// addAuthor stores an author, merged with the one of the same name
+func addAuthor(q querier, author models.Author) (int64, error) {
+	id, err := findID(q, "SELECT id FROM authors WHERE name = $1", author.Name)
+	if err != nil {
+		return 0, err
+	}
+	if id == 0 {
+		if id, err = findID(q, "INSERT INTO authors (name) VALUES ($1) RETURNING id", author.Name); err != nil {
+			return 0, err
+		}
+	}
+	c := changes{}
+	c.set("url", author.URL, author.URL == "")
+	c.set("citationcount", author.CitationCount, author.CitationCount == 0)
+	c.set("hindex", author.HIndex, author.HIndex == 0)
+	c.set("i10index", author.I10Index, author.I10Index == 0)
+	return id, update(q, "authors", id, c.columns, c.values)
+}
+
+// addCategory stores a category, merged with the one of the same code
+func addCategory(q querier, category models.Category) (int64, error) {
+	id, err := findID(q, "SELECT id FROM categories WHERE code = $1", category.Code)
+	if err != nil {
+		return 0, err
+	}
+	if id == 0 {
+		if id, err = findID(q, "INSERT INTO categories (code) VALUES ($1) RETURNING id", category.Code); err != nil {
+			return 0, err
+		}
+	}
+	c := changes{}
+	c.set("name", category.Name, category.Name == "")
+	return id, update(q, "categories", id, c.columns, c.values)
+}
+
+// addArticle stores an article, merged with the one of the same UID, arXiv
+// ID, or title when it has no ID, with its authors, categories, sections
+// and cited papers
+func addArticle(q querier, article models.Article) (int64, error) {
+	var id int64
+	var err error
+	if known, ok := sqlID(article.UID); ok {
+		if id, err = findID(q, "SELECT id FROM articles WHERE id = $1", known); err != nil {
+			return 0, err
+		}
+	}
+	if id == 0 && article.ArXivID != "" {
+		if id, err = findID(q, "SELECT id FROM articles WHERE arxivid = $1", article.ArXivID); err != nil {
+			return 0, err
+		}
+	} else if id == 0 && article.Title != "" {
+		if id, err = findID(q, "SELECT id FROM articles WHERE title = $1", article.Title); err != nil {
+			return 0, err
+		}
+	}
+	if id == 0 {
+		if id, err = findID(q, "INSERT INTO articles (title) VALUES ($1) RETURNING id", article.Title); err != nil {
+			return 0, err
+		}
+	}
+
+	c := changes{}
+	c.set("arxivid", article.ArXivID, article.ArXivID == "")
+	c.set("title", article.Title, article.Title == "")
+	c.set("abstract", article.Abstract, article.Abstract == "")
+	c.set("submissiondate", formatSQLTime(article.SubmissionDate), article.SubmissionDate.IsZero())
+	c.set("crawledat", formatSQLTime(article.CrawledAt), article.CrawledAt.IsZero())
+	c.set("pdfurl", article.PDFURL, article.PDFURL == "")
+	c.set("otherformaturl", article.OtherFormatURL, article.OtherFormatURL == "")
+	c.set("metaurl", article.MetaURL, article.MetaURL == "")
+	c.set("archiveref", article.ArchiveRef, article.ArchiveRef == "")
+	c.set("pdfkey", article.PDFKey, article.PDFKey == "")
+	c.set("pdfchecksum", article.PDFChecksum, article.PDFChecksum == "")
+	c.set("fulltextkey", article.FullTextKey, article.FullTextKey == "")
+	c.set("sourcekey", article.SourceKey, article.SourceKey == "")
+	c.set("citationcount", article.CitationCount, article.CitationCount == 0)
+	c.set("citationvelocity", article.CitationVelocity, article.CitationVelocity == 0)
+	c.set("pagerank", article.PageRank, article.PageRank == 0)
+	c.set("article", true, !hasType(article.DType, "Article"))
+	if err = update(q, "articles", id, c.columns, c.values); err != nil {
+		return 0, err
+	}
+
+	if len(article.Sections) > 0 {
+		if _, err = q.Exec("DELETE FROM sections WHERE article_id = $1", id); err != nil {
+			return 0, err
+		}
+		for i, section := range article.Sections {
+			_, err = q.Exec("INSERT INTO sections (article_id, position, name, text) VALUES ($1, $2, $3, $4)",
+				id, i, section.Name, section.Text)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	for i, author := range article.Authors {
+		if author.Name == "" {
+			continue
+		}
+		to, err := addAuthor(q, author)
+		if err != nil {
+			return 0, err
+		}
+		_, err = q.Exec(`INSERT INTO article_authors (article_id, author_id, position) VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING`, id, to, i)
+		if err != nil {
+			return 0, err
+		}
+	}
+	for _, category := range article.Categories {
+		if category.Code == "" {
+			continue
+		}
+		to, err := addCategory(q, category)
+		if err != nil {
+			return 0, err
+		}
+		_, err = q.Exec(`INSERT INTO article_categories (article_id, category_id) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING`, id, to)
+		if err != nil {
+			return 0, err
+		}
+	}
+	for _, cited := range article.CitedPapers {
+		to, err := addArticle(q, cited)
+		if err != nil {
+			return 0, err
+		}
+		_, err = q.Exec(`INSERT INTO citations (citing_id, cited_id) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING`, id, to)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return id, nil
+}
+
+// inTransaction runs f in a transaction, committed when f succeeds
+func (s *SQLStore) inTransaction(f func(tx *sql.Tx) error) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	if err = f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// AddArticle stores an article with its authors, categories and cited
+// papers, and returns its UID
+func (s *SQLStore) AddArticle(article models.Article) (string, error) {
+	var id int64
+	err := s.inTransaction(func(tx *sql.Tx) error {
+		var err error
+		id, err = addArticle(tx, article)
+		return err
+	})
+	return sqlUID(id), err
+}
+
+// UpdateArticle sets the non-zero fields of a stored article and adds its
+// edges
+func (s *SQLStore) UpdateArticle(article models.Article) error {
+	id, ok := sqlID(article.UID)
+	if !ok {
+		return fmt.Errorf("No UID for article %s", article.ArXivID)
+	}
+	return s.inTransaction(func(tx *sql.Tx) error {
+		found, err := findID(tx, "SELECT id FROM articles WHERE id = $1", id)
+		if err != nil {
+			return err
+		}
+		if found == 0 {
+			return ErrNotFound
+		}
+		_, err = addArticle(tx, article)
+		return err
+	})
+}
+
+// sqlDeletions remove the values of the predicates of an article, the pages
+// are never stored in htmlresponse
+var sqlDeletions = map[string]string{
+	"authors":      "DELETE FROM article_authors WHERE article_id = $1",
+	"categories":   "DELETE FROM article_categories WHERE article_id = $1",
+	"citedpapers":  "DELETE FROM citations WHERE citing_id = $1",
+	"sections":     "DELETE FROM sections WHERE article_id = $1",
+	"htmlresponse": "",
+}
+
+// DeleteEdges removes every value of the given predicates from an article,
+// among the ones of sqlDeletions
+func (s *SQLStore) DeleteEdges(uid string, predicates []string) error {
+	id, ok := sqlID(uid)
+	if !ok {
+		return ErrNotFound
+	}
+	return s.inTransaction(func(tx *sql.Tx) error {
+		for _, predicate := range predicates {
+			statement, ok := sqlDeletions[predicate]
+			if !ok {
+				return fmt.Errorf("Unknown predicate %s", predicate)
+			}
+			if statement == "" {
+				continue
+			}
+			if _, err := tx.Exec(statement, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Load stores a batch of imported articles in a single transaction, it
+// makes the SQLStore an imports.Sink
+func (s *SQLStore) Load(articles []models.Article) error {
+	return s.inTransaction(func(tx *sql.Tx) error {
+		for _, article := range articles {
+			// The UIDs of the dump belong to another database
+			article.UID = ""
+			article.DType = []string{"Article"}
+			if _, err := addArticle(tx, article); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AddCommunity stores a community, replacing the one its members were in
+func (s *SQLStore) AddCommunity(community models.Community) (string, error) {
+	var id int64
+	err := s.inTransaction(func(tx *sql.Tx) error {
+		var err error
+		id, err = findID(tx, "INSERT INTO communities (label, size, method) VALUES ($1, $2, $3) RETURNING id",
+			community.Label, community.Size, community.Method)
+		if err != nil {
+			return err
+		}
+		for i, member := range community.Members {
+			to, err := addAuthor(tx, member)
+			if err != nil {
+				return err
+			}
+			if _, err = tx.Exec("DELETE FROM community_members WHERE author_id = $1", to); err != nil {
+				return err
+			}
+			_, err = tx.Exec("INSERT INTO community_members (community_id, author_id, position) VALUES ($1, $2, $3)",
+				id, to, i)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return sqlUID(id), err
+}
+
+// lookup returns the UID selected by a query
+func (s *SQLStore) lookup(query, value string) (string, error) {
+	id, err := findID(s.DB, query, value)
+	if err != nil {
+		return "", err
+	}
+	if id == 0 {
+		return "", ErrNotFound
+	}
+	return sqlUID(id), nil
+}
+
+// AuthorUID gives the UID of the author with a name
+func (s *SQLStore) AuthorUID(name string) (string, error) {
+	return s.lookup("SELECT id FROM authors WHERE name = $1", name)
+}
+
+// ArticleUID gives the UID of the article with a title
+func (s *SQLStore) ArticleUID(title string) (string, error) {
+	return s.lookup("SELECT id FROM articles WHERE title = $1", title)
+}
+
+// ArticleUIDByArXivID gives the UID of the article with an arXiv ID
+func (s *SQLStore) ArticleUIDByArXivID(arXivID string) (string, error) {
+	return s.lookup("SELECT id FROM articles WHERE arxivid = $1", arXivID)
+}
+
+// CrawledArticleUID gives the UID of the article with an arXiv ID whose
+// page was stored, and not only its ID as a cited paper
+func (s *SQLStore) CrawledArticleUID(arXivID string) (string, error) {
+	return s.lookup("SELECT id FROM articles WHERE arxivid = $1 AND title <> ''", arXivID)
+}
+
+// CategoryUID gives the UID of the category with a code
+func (s *SQLStore) CategoryUID(code string) (string, error) {
+	return s.lookup("SELECT id FROM categories WHERE code = $1", code)
+}
+
+// sqlArticleColumns are the columns of an article returned by the Store,
+// as articleFields
+const sqlArticleColumns = `a.id, COALESCE(a.arxivid, ''), a.title, a.abstract, a.submissiondate,
+	a.crawledat, a.pdfurl, a.otherformaturl, a.metaurl, a.citationcount, a.citationvelocity, a.pagerank`
+
+// scanArticle reads the sqlArticleColumns of a row after the columns in
+// before
+func scanArticle(rows *sql.Rows, before ...interface{}) (models.Article, int64, error) {
+	var a models.Article
+	var id int64
+	var submission, crawled string
+	err := rows.Scan(append(before, &id, &a.ArXivID, &a.Title, &a.Abstract, &submission,
+		&crawled, &a.PDFURL, &a.OtherFormatURL, &a.MetaURL, &a.CitationCount, &a.CitationVelocity, &a.PageRank)...)
+	a.UID = sqlUID(id)
+	a.SubmissionDate = parseSQLTime(submission)
+	a.CrawledAt = parseSQLTime(crawled)
+	return a, id, err
+}
+
+// articles runs a query of the sqlArticleColumns and sets the authors and
+// the categories of the articles
+func (s *SQLStore) articles(query string, args ...interface{}) ([]models.Article, error) {
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	var articles []models.Article
+	var ids []int64
+	for rows.Next() {
+		a, id, err := scanArticle(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		articles = append(articles, a)
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return articles, s.setEdges(articles, ids)
+}
+
+// setEdges sets the authors and the categories of articles, once the rows
+// of the articles are closed
+func (s *SQLStore) setEdges(articles []models.Article, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	positions := make(map[int64][]int)
+	for i, id := range ids {
+		positions[id] = append(positions[id], i)
+	}
+	unique := make([]int64, 0, len(positions))
+	for id := range positions {
+		unique = append(unique, id)
+	}
+
+	args := sqlArgs{}
+	rows, err := s.DB.Query(`SELECT aa.article_id, u.id, u.name, u.url, u.citationcount, u.hindex, u.i10index
+		FROM article_authors aa JOIN authors u ON u.id = aa.author_id
+		WHERE aa.article_id IN (`+args.in(unique)+`) ORDER BY aa.article_id, aa.position`, args...)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var article, id int64
+		var author models.Author
+		if err = rows.Scan(&article, &id, &author.Name, &author.URL, &author.CitationCount, &author.HIndex, &author.I10Index); err != nil {
+			rows.Close()
+			return err
+		}
+		author.UID = sqlUID(id)
+		for _, i := range positions[article] {
+			articles[i].Authors = append(articles[i].Authors, author)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	args = sqlArgs{}
+	rows, err = s.DB.Query(`SELECT ac.article_id, c.id, c.code, c.name
+		FROM article_categories ac JOIN categories c ON c.id = ac.category_id
+		WHERE ac.article_id IN (`+args.in(unique)+`) ORDER BY ac.article_id, c.code`, args...)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var article, id int64
+		var category models.Category
+		if err = rows.Scan(&article, &id, &category.Code, &category.Name); err != nil {
+			rows.Close()
+			return err
+		}
+		category.UID = sqlUID(id)
+		for _, i := range positions[article] {
+			articles[i].Categories = append(articles[i].Categories, category)
+		}
+	}
+	rows.Close()
+	return rows.Err()
+}
+
+// Article returns the article with an arXiv ID
+func (s *SQLStore) Article(arXivID string) (models.Article, error) {
+	articles, err := s.articles("SELECT "+sqlArticleColumns+" FROM articles a WHERE a.arxivid = $1", arXivID)
+	if err != nil {
+		return models.Article{}, err
+	}
+	if len(articles) == 0 {
+		return models.Article{}, ErrNotFound
+	}
+	return articles[0], nil
+}
+
+// sqlEdges are the join tables of the edges of Store.Linked, from the
+// column of the node to the column of the linked articles
+var sqlEdges = map[string]struct{ table, from, to string }{
+	AuthorArticlesEdge:   {"article_authors", "author_id", "article_id"},
+	CategoryArticlesEdge: {"article_categories", "category_id", "article_id"},
+	ReferencesEdge:       {"citations", "citing_id", "cited_id"},
+	CitationsEdge:        {"citations", "cited_id", "citing_id"},
+}
+
+// linked returns the articles linked to nodes by an edge, newest first.
+// With a limit, offset and limit apply to all the nodes together.
+func (s *SQLStore) linked(edge string, ids []int64, offset, limit int) (map[int64][]models.Article, error) {
+	e, ok := sqlEdges[edge]
+	if !ok {
+		return nil, fmt.Errorf("Unknown edge %q", edge)
+	}
+	linked := make(map[int64][]models.Article)
+	if len(ids) == 0 {
+		return linked, nil
+	}
+	args := sqlArgs{}
+	query := fmt.Sprintf(`SELECT e.%s, %s FROM %s e JOIN articles a ON a.id = e.%s
+		WHERE e.%s IN (%s) ORDER BY a.submissiondate DESC, a.id`,
+		e.from, sqlArticleColumns, e.table, e.to, e.from, args.in(ids))
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %s OFFSET %s", args.add(limit), args.add(offset))
+	}
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	var articles []models.Article
+	var from, articleIDs []int64
+	for rows.Next() {
+		var node int64
+		a, id, err := scanArticle(rows, &node)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		articles = append(articles, a)
+		from = append(from, node)
+		articleIDs = append(articleIDs, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if err = s.setEdges(articles, articleIDs); err != nil {
+		return nil, err
+	}
+	for i, a := range articles {
+		linked[from[i]] = append(linked[from[i]], a)
+	}
+	return linked, nil
+}
+
+// node returns the identifier selected by a query, ErrNotFound when there
+// is none
+func (s *SQLStore) node(query, value string) (int64, error) {
+	id, err := findID(s.DB, query, value)
+	if err == nil && id == 0 {
+		err = ErrNotFound
+	}
+	return id, err
+}
+
+// articleEdge returns a page of the articles linked to an article
+func (s *SQLStore) articleEdge(arXivID, edge string, offset, limit int) ([]models.Article, error) {
+	id, err := s.node("SELECT id FROM articles WHERE arxivid = $1", arXivID)
+	if err != nil {
+		return nil, err
+	}
+	linked, err := s.linked(edge, []int64{id}, offset, limit)
+	return linked[id], err
+}
+
+// Citations returns the articles citing an article
+func (s *SQLStore) Citations(arXivID string, offset, limit int) ([]models.Article, error) {
+	return s.articleEdge(arXivID, CitationsEdge, offset, limit)
+}
+
+// References returns the articles cited by an article
+func (s *SQLStore) References(arXivID string, offset, limit int) ([]models.Article, error) {
+	return s.articleEdge(arXivID, ReferencesEdge, offset, limit)
+}
+
+// Author returns the author with a name
+func (s *SQLStore) Author(name string) (models.Author, error) {
+	var author models.Author
+	var id int64
+	err := s.DB.QueryRow("SELECT id, name, url, citationcount, hindex, i10index FROM authors WHERE name = $1", name).
+		Scan(&id, &author.Name, &author.URL, &author.CitationCount, &author.HIndex, &author.I10Index)
+	if err == sql.ErrNoRows {
+		return author, ErrNotFound
+	}
+	author.UID = sqlUID(id)
+	return author, err
+}
+
+// AuthorArticles returns the articles of an author, newest first
+func (s *SQLStore) AuthorArticles(name string, offset, limit int) ([]models.Article, error) {
+	id, err := s.node("SELECT id FROM authors WHERE name = $1", name)
+	if err != nil {
+		return nil, err
+	}
+	linked, err := s.linked(AuthorArticlesEdge, []int64{id}, offset, limit)
+	return linked[id], err
+}
+
+// Coauthors returns the coauthors of an author, most frequent first
+func (s *SQLStore) Coauthors(name string, offset, limit int) ([]Coauthor, error) {
+	id, err := s.node("SELECT id FROM authors WHERE name = $1", name)
+	if err != nil {
+		return nil, err
+	}
+	linked, err := s.linked(AuthorArticlesEdge, []int64{id}, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return paginate(CountCoauthors(sqlUID(id), linked[id]), offset, limit), nil
+}
+
+// AuthorCommunity returns the community of an author with its members
+func (s *SQLStore) AuthorCommunity(name string) (models.Community, error) {
+	var id int64
+	err := s.DB.QueryRow("SELECT id FROM authors WHERE name = $1", name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return models.Community{}, ErrNotFound
+	}
+	if err != nil {
+		return models.Community{}, err
+	}
+	communities, err := s.authorCommunities([]int64{id})
+	if err != nil {
+		return models.Community{}, err
+	}
+	c, ok := communities[id]
+	if !ok {
+		return models.Community{}, ErrNotFound
+	}
+	return c, nil
+}
+
+// AuthorCommunities returns by UID the communities of the authors
+func (s *SQLStore) AuthorCommunities(uids []string) (map[string]models.Community, error) {
+	ids := make([]int64, 0, len(uids))
+	for _, uid := range uids {
+		if id, ok := sqlID(uid); ok {
+			ids = append(ids, id)
+		}
+	}
+	byID, err := s.authorCommunities(ids)
+	if err != nil {
+		return nil, err
+	}
+	communities := make(map[string]models.Community, len(byID))
+	for id, c := range byID {
+		communities[sqlUID(id)] = c
+	}
+	return communities, nil
+}
+
+// authorCommunities returns by author ID the communities of the authors,
+// with at most MaxLinked of their members
+func (s *SQLStore) authorCommunities(ids []int64) (map[int64]models.Community, error) {
+	byAuthor := make(map[int64]models.Community, len(ids))
+	if len(ids) == 0 {
+		return byAuthor, nil
+	}
+	args := sqlArgs{}
+	rows, err := s.DB.Query(`SELECT m.author_id, c.id, c.label, c.size, c.method
+		FROM community_members m JOIN communities c ON c.id = m.community_id
+		WHERE m.author_id IN (`+args.in(ids)+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	communityOf := make(map[int64]int64)
+	communities := make(map[int64]*models.Community)
+	for rows.Next() {
+		var author, id int64
+		var c models.Community
+		if err = rows.Scan(&author, &id, &c.Label, &c.Size, &c.Method); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		c.UID = sqlUID(id)
+		communityOf[author] = id
+		communities[id] = &c
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(communities) == 0 {
+		return byAuthor, nil
+	}
+
+	// The first members of every community are loaded at once
+	unique := make([]int64, 0, len(communities))
+	for id := range communities {
+		unique = append(unique, id)
+	}
+	args = sqlArgs{}
+	rows, err = s.DB.Query(`SELECT community_id, id, name, url, citationcount, hindex, i10index FROM
+		(SELECT m.community_id, u.id, u.name, u.url, u.citationcount, u.hindex, u.i10index, m.position,
+			ROW_NUMBER() OVER (PARTITION BY m.community_id ORDER BY m.position) AS n
+		FROM community_members m JOIN authors u ON u.id = m.author_id
+		WHERE m.community_id IN (`+args.in(unique)+`)) ranked
+		WHERE n <= `+args.add(MaxLinked)+` ORDER BY community_id, position`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var member models.Author
+		var communityID, memberID int64
+		if err = rows.Scan(&communityID, &memberID, &member.Name, &member.URL, &member.CitationCount,
+			&member.HIndex, &member.I10Index); err != nil {
+			return nil, err
+		}
+		member.UID = sqlUID(memberID)
+		c := communities[communityID]
+		c.Members = append(c.Members, member)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	for author, id := range communityOf {
+		byAuthor[author] = *communities[id]
+	}
+	return byAuthor, nil
+}
+
+// sqlFilters translates the date, author and category filters in
+// conditions on the articles a
+func sqlFilters(filters SearchFilters, args *sqlArgs) ([]string, error) {
+	conditions := []string{"a.article = TRUE"}
+	if !filters.From.IsZero() {
+		conditions = append(conditions, "a.submissiondate >= "+args.add(formatSQLTime(filters.From)))
+	}
+	if !filters.To.IsZero() {
+		conditions = append(conditions, "a.submissiondate <> ''",
+			"a.submissiondate <= "+args.add(formatSQLTime(filters.To)))
+	}
+	if filters.Author != "" {
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM article_authors aa JOIN authors u ON u.id = aa.author_id
+			WHERE aa.article_id = a.id AND u.name = `+args.add(filters.Author)+`)`)
+	}
+	if filters.Category != "" {
+		if err := validateCategory(filters); err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (SELECT 1 FROM article_categories ac JOIN categories c ON c.id = ac.category_id
+			WHERE ac.article_id = a.id AND (c.code = %s OR c.code LIKE %s))`,
+			args.add(filters.Category), args.add(filters.Category+".%")))
+	}
+	return conditions, nil
+}
+
+// Search returns the articles whose title, abstract or sections contain a
+// term of the query, ranked as by Dgraph
+func (s *SQLStore) Search(query string, filters SearchFilters) ([]SearchResult, error) {
+	terms := SearchTerms(query)
+	if len(terms) == 0 {
+		return nil, &ValidationError{Field: "query"}
+	}
+	args := sqlArgs{}
+	conditions, err := sqlFilters(filters, &args)
+	if err != nil {
+		return nil, err
+	}
+	// The terms match as the stems of termFrequency
+	var inText, inSections []string
+	for _, term := range terms {
+		stem := term
+		if len(stem) > 4 {
+			stem = stem[:len(stem)-1]
+		}
+		pattern := args.add("%" + stem + "%")
+		inText = append(inText, "LOWER(a.title) LIKE "+pattern, "LOWER(a.abstract) LIKE "+pattern)
+		inSections = append(inSections, "LOWER(s.text) LIKE "+pattern)
+	}
+	inSection := strings.Join(inSections, " OR ")
+	statement := fmt.Sprintf(`SELECT %s FROM articles a WHERE %s AND (%s OR EXISTS
+		(SELECT 1 FROM sections s WHERE s.article_id = a.id AND (%s))) ORDER BY a.id`,
+		sqlArticleColumns, strings.Join(conditions, " AND "), strings.Join(inText, " OR "), inSection)
+
+	// LIKE matches substrings, the hits are counted on the words as in the
+	// MemoryStore
+	bodyHits, err := s.bodyHits(fmt.Sprintf(`SELECT s.article_id, s.text FROM sections s
+		JOIN articles a ON a.id = s.article_id WHERE %s AND (%s)`,
+		strings.Join(conditions, " AND "), inSection), terms, args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every match is ranked, the edges are only set on the page
+	rows, err := s.DB.Query(statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	var results []SearchResult
+	for rows.Next() {
+		var r SearchResult
+		var id int64
+		r.Article, id, err = scanArticle(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		r.BodyHits = bodyHits[id]
+		if termHits(terms, r.Title)+termHits(terms, r.Abstract)+r.BodyHits == 0 {
+			continue
+		}
+		results = append(results, r)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	results = pageResults(Rank(query, results), filters)
+	articles := make([]models.Article, len(results))
+	ids := make([]int64, len(results))
+	for i := range results {
+		articles[i] = results[i].Article
+		ids[i], _ = sqlID(results[i].UID)
+	}
+	if err = s.setEdges(articles, ids); err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Article = articles[i]
+	}
+	return results, nil
+}
+
+// bodyHits runs a query of the article IDs and the texts of sections and
+// counts by article the sections where the terms are found
+func (s *SQLStore) bodyHits(query string, terms []string, args sqlArgs) (map[int64]int, error) {
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	hits := make(map[int64]int)
+	for rows.Next() {
+		var id int64
+		var text string
+		if err = rows.Scan(&id, &text); err != nil {
+			return nil, err
+		}
+		if termHits(terms, text) > 0 {
+			hits[id]++
+		}
+	}
+	return hits, rows.Err()
+}
+
+// Articles returns the articles matching the filters, newest first
+func (s *SQLStore) Articles(filters SearchFilters) ([]models.Article, error) {
+	args := sqlArgs{}
+	conditions, err := sqlFilters(filters, &args)
+	if err != nil {
+		return nil, err
+	}
+	limit := filters.Limit
+	if limit <= 0 {
+		limit = MaxLinked
+	}
+	query := fmt.Sprintf("SELECT %s FROM articles a WHERE %s ORDER BY a.submissiondate DESC, a.id LIMIT %s OFFSET %s",
+		sqlArticleColumns, strings.Join(conditions, " AND "), args.add(limit), args.add(filters.Offset))
+	return s.articles(query, args...)
+}
+
+// Category returns the category with a code
+func (s *SQLStore) Category(code string) (models.Category, error) {
+	var category models.Category
+	var id int64
+	err := s.DB.QueryRow("SELECT id, code, name FROM categories WHERE code = $1", code).
+		Scan(&id, &category.Code, &category.Name)
+	if err == sql.ErrNoRows {
+		return category, ErrNotFound
+	}
+	category.UID = sqlUID(id)
+	return category, err
+}
+
+// Linked returns by UID the articles linked to the nodes with the UIDs
+func (s *SQLStore) Linked(edge string, uids []string) (map[string][]models.Article, error) {
+	if _, ok := sqlEdges[edge]; !ok {
+		return nil, fmt.Errorf("Unknown edge %q", edge)
+	}
+	ids := make([]int64, len(uids))
+	for i, uid := range uids {
+		id, ok := sqlID(uid)
+		if !ok {
+			return nil, fmt.Errorf("Invalid UID %q", uid)
+		}
+		ids[i] = id
+	}
+	byID, err := s.linked(edge, ids, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	linked := make(map[string][]models.Article, len(uids))
+	for id, articles := range byID {
+		if len(articles) > MaxLinked {
+			articles = articles[:MaxLinked]
+		}
+		linked[sqlUID(id)] = articles
+	}
+	return linked, nil
+}
+
+// ArticlesAfter returns the articles following the UID after, at most
+// first, with the UIDs and arXiv IDs of their cited papers
+func (s *SQLStore) ArticlesAfter(after string, first int) ([]models.Article, error) {
+	from, ok := sqlID(after)
+	if !ok {
+		return nil, fmt.Errorf("Invalid UID %q", after)
+	}
+	articles, err := s.articles("SELECT "+sqlArticleColumns+" FROM articles a WHERE a.id > $1 ORDER BY a.id LIMIT $2",
+		from, first)
+	if err != nil || len(articles) == 0 {
+		return articles, err
+	}
+	ids := make([]int64, len(articles))
+	index := make(map[int64]int, len(articles))
+	for i, a := range articles {
+		ids[i], _ = sqlID(a.UID)
+		index[ids[i]] = i
+	}
+	args := sqlArgs{}
+	rows, err := s.DB.Query(`SELECT c.citing_id, a.id, COALESCE(a.arxivid, '')
+		FROM citations c JOIN articles a ON a.id = c.cited_id
+		WHERE c.citing_id IN (`+args.in(ids)+`) ORDER BY c.citing_id, a.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var citing, id int64
+		var cited models.Article
+		if err = rows.Scan(&citing, &id, &cited.ArXivID); err != nil {
+			return nil, err
+		}
+		cited.UID = sqlUID(id)
+		i := index[citing]
+		articles[i].CitedPapers = append(articles[i].CitedPapers, cited)
+	}
+	return articles, rows.Err()
+}
+
+// traverse follows the citations from an article up to depth times with a
+// recursive query, forward to the cited papers or backward to the citing
+// ones. Each article comes once, at its smallest depth.
+func (s *SQLStore) traverse(arXivID string, forward bool, depth int) ([]ReachedArticle, error) {
+	start, err := s.node("SELECT id FROM articles WHERE arxivid = $1", arXivID)
+	if err != nil {
+		return nil, err
+	}
+	from, to := "citing_id", "cited_id"
+	if !forward {
+		from, to = to, from
+	}
+	query := fmt.Sprintf(`WITH RECURSIVE reached (id, depth) AS (
+			SELECT CAST($1 AS BIGINT), 0
+			UNION
+			SELECT c.%[2]s, r.depth + 1 FROM citations c JOIN reached r ON c.%[1]s = r.id
+			WHERE r.depth < $2
+		)
+		SELECT r.depth, %[3]s FROM articles a
+		JOIN (SELECT id, MIN(depth) AS depth FROM reached GROUP BY id) r ON r.id = a.id
+		WHERE r.depth > 0 ORDER BY r.depth, a.submissiondate DESC, a.id`, from, to, sqlArticleColumns)
+	rows, err := s.DB.Query(query, start, depth)
+	if err != nil {
+		return nil, err
+	}
+	var reached []ReachedArticle
+	var articles []models.Article
+	var ids []int64
+	for rows.Next() {
+		var r ReachedArticle
+		a, id, err := scanArticle(rows, &r.Depth)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		reached = append(reached, r)
+		articles = append(articles, a)
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if err = s.setEdges(articles, ids); err != nil {
+		return nil, err
+	}
+	for i := range reached {
+		reached[i].Article = articles[i]
+	}
+	return reached, nil
+}
+
+// ReferencesWithin returns the articles cited by an article, directly or
+// through at most depth citations
+func (s *SQLStore) ReferencesWithin(arXivID string, depth int) ([]ReachedArticle, error) {
+	return s.traverse(arXivID, true, depth)
+}
+
+// CitationsWithin returns the articles citing an article, directly or
+// through at most depth citations
+func (s *SQLStore) CitationsWithin(arXivID string, depth int) ([]ReachedArticle, error) {
+	return s.traverse(arXivID, false, depth)
+}
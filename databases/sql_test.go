@@ -0,0 +1,132 @@
+package databases
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"pandor/models"
+)
+
+func openTestSQLStore() *SQLStore {
+	s, err := OpenSQLStore("sqlite", ":memory:")
+	if err != nil {
+		log.Fatal(err)
+	}
+	return s
+}
+
+func TestSQLStore(t *testing.T) {
+	s := openTestSQLStore()
+	defer s.Close()
+	checkStore(s)
+
+	// Articles of the same title and different arXiv IDs are not merged
+	err := s.Load([]models.Article{
+		{ArXivID: "1001.0001", Title: "Erratum"},
+		{ArXivID: "1002.0002", Title: "Erratum"},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	first, err := s.Article("1001.0001")
+	if err != nil {
+		log.Fatal(err)
+	}
+	second, err := s.Article("1002.0002")
+	if err != nil || second.UID == first.UID {
+		log.Fatalf("Wrong articles %v and %v %v", first, second, err)
+	}
+}
+
+func TestSQLGraph(t *testing.T) {
+	s := openTestSQLStore()
+	defer s.Close()
+	checkGraph(s)
+}
+
+func TestSQLStoreReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pandor")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "pandor.db")
+	s, err := OpenSQLStore("sqlite", path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = s.AddArticle(models.Article{ArXivID: "0801.0002", Title: "Globular clusters", DType: []string{"Article"}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.Close()
+
+	// The tables are kept
+	s, err = OpenSQLStore("sqlite", path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer s.Close()
+	if _, err = s.Article("0801.0002"); err != nil {
+		log.Fatal(err)
+	}
+	if _, err = OpenSQLStore("mysql", path); err == nil {
+		log.Fatal("Unknown drivers should be rejected")
+	}
+	// Every accepted driver is registered
+	for driver := range sqlIdentifiers {
+		db, err := sql.Open(driver, "")
+		if err != nil {
+			log.Fatal(err)
+		}
+		db.Close()
+	}
+}
+
+func TestSQLTraversal(t *testing.T) {
+	s := openTestSQLStore()
+	defer s.Close()
+	// a cites b and c, b cites d, d cites a
+	err := s.Load([]models.Article{
+		{ArXivID: "a", Title: "A", CitedPapers: []models.Article{{ArXivID: "b"}, {ArXivID: "c"}}},
+		{ArXivID: "b", Title: "B", CitedPapers: []models.Article{{ArXivID: "d"}}},
+		{ArXivID: "d", Title: "D", CitedPapers: []models.Article{{ArXivID: "a"}}},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	depths := func(reached []ReachedArticle) map[string]int {
+		d := make(map[string]int)
+		for _, r := range reached {
+			d[r.ArXivID] = r.Depth
+		}
+		return d
+	}
+	reached, err := s.ReferencesWithin("a", 1)
+	if d := depths(reached); err != nil || len(d) != 2 || d["b"] != 1 || d["c"] != 1 {
+		log.Fatalf("Wrong references within 1 %v %v", d, err)
+	}
+	// The cycle back to a does not return it
+	reached, err = s.ReferencesWithin("a", 5)
+	if d := depths(reached); err != nil || len(d) != 3 || d["d"] != 2 {
+		log.Fatalf("Wrong references within 5 %v %v", d, err)
+	}
+	reached, err = s.CitationsWithin("d", 2)
+	if d := depths(reached); err != nil || len(d) != 2 || d["b"] != 1 || d["a"] != 2 {
+		log.Fatalf("Wrong citations within 2 %v %v", d, err)
+	}
+	if _, err = s.CitationsWithin("z", 2); err != ErrNotFound {
+		log.Fatalf("An unknown article should not be found, got %v", err)
+	}
+
+	// The imported articles are articles, the cited ones only once crawled
+	articles, err := s.Articles(SearchFilters{})
+	if err != nil || len(articles) != 3 {
+		log.Fatalf("Wrong articles %v %v", articles, err)
+	}
+}
@@ -0,0 +1,491 @@
+package databases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"pandor/models"
+
+	"github.com/dgraph-io/dgo/v2"
+)
+
+// ErrNotFound is returned when an article or an author does not exist
+var ErrNotFound = errors.New("Not found")
+
+// Coauthor is an author with the number of articles shared with another one
+type Coauthor struct {
+	models.Author
+	Shared int `json:"shared"`
+}
+
+// Store reads the knowledge graph. It hides the query language from the
+// consumers such as the API server.
+type Store interface {
+	// Article returns the article with an arXiv ID
+	Article(arXivID string) (models.Article, error)
+	// Citations returns the articles citing an article
+	Citations(arXivID string, offset, limit int) ([]models.Article, error)
+	// References returns the articles cited by an article
+	References(arXivID string, offset, limit int) ([]models.Article, error)
+	// Author returns the author with a name
+	Author(name string) (models.Author, error)
+	// AuthorArticles returns the articles of an author, newest first
+	AuthorArticles(name string, offset, limit int) ([]models.Article, error)
+	// Coauthors returns the coauthors of an author, most frequent first
+	Coauthors(name string, offset, limit int) ([]Coauthor, error)
+	// AuthorCommunity returns the community of an author with at most
+	// MaxLinked of its members, the most prolific first
+	AuthorCommunity(name string) (models.Community, error)
+	// AuthorCommunities returns by UID the communities of the authors with
+	// the UIDs, as AuthorCommunity. The authors without a community are
+	// absent.
+	AuthorCommunities(uids []string) (map[string]models.Community, error)
+	// Search returns the articles matching a query, best ranked first
+	Search(query string, filters SearchFilters) ([]SearchResult, error)
+	// Articles returns the articles matching the filters, newest first
+	Articles(filters SearchFilters) ([]models.Article, error)
+	// Category returns the category with a code
+	Category(code string) (models.Category, error)
+	// Linked returns by UID the articles linked to the nodes with the UIDs
+	// by an edge, at most MaxLinked per node. It lets the nested fields of
+	// many nodes be loaded at once.
+	Linked(edge string, uids []string) (map[string][]models.Article, error)
+}
+
+// Writer stores the crawled articles and the computed communities, in the
+// stores other than Dgraph
+type Writer interface {
+	// AddArticle stores an article with its authors, categories and cited
+	// papers, merged with the stored nodes, and returns its UID
+	AddArticle(article models.Article) (string, error)
+	// AddCommunity stores a community and returns its UID
+	AddCommunity(community models.Community) (string, error)
+}
+
+// ReachedArticle is an article reached by following the citations
+type ReachedArticle struct {
+	models.Article
+	Depth int `json:"depth"` // number of citations followed, from 1
+}
+
+// Traversal follows the citations of an article over several hops, in a
+// single query. The SQLStore implements it.
+type Traversal interface {
+	// ReferencesWithin returns the articles cited by an article, directly
+	// or through at most depth citations, nearest first
+	ReferencesWithin(arXivID string, depth int) ([]ReachedArticle, error)
+	// CitationsWithin returns the articles citing an article, directly or
+	// through at most depth citations, nearest first
+	CitationsWithin(arXivID string, depth int) ([]ReachedArticle, error)
+}
+
+// Edges accepted by Store.Linked
+const (
+	AuthorArticlesEdge   = "~authors"     // from authors
+	CategoryArticlesEdge = "~categories"  // from categories
+	ReferencesEdge       = "citedpapers"  // from articles
+	CitationsEdge        = "~citedpapers" // from articles
+)
+
+// MaxLinked bounds the number of articles returned by Linked for a node
+var MaxLinked = 1000
+
+// articleFields are the predicates of an article returned by the Store
+const articleFields = `uid
+	arxivid
+	title
+	abstract
+	submissiondate
+	crawledat
+	pdfurl
+	otherformaturl
+	metaurl
+	citationcount
+	citationvelocity
+	pagerank
+	authors { uid name url citationcount hindex i10index }
+	categories { uid categorycode categoryname }`
+
+// DgraphStore is the Store of a Dgraph database
+type DgraphStore struct {
+	DG *dgo.Dgraph
+}
+
+// NewDgraphStore builds a Store reading dg
+func NewDgraphStore(dg *dgo.Dgraph) *DgraphStore {
+	return &DgraphStore{DG: dg}
+}
+
+// query runs a read-only query and decodes its result into v. Unlike
+// QueryWithVars, errors are returned to the caller.
+func (s *DgraphStore) query(query string, variables map[string]string, v interface{}) error {
+	resp, err := s.DG.NewReadOnlyTxn().QueryWithVars(context.Background(), query, variables)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(resp.Json, v)
+}
+
+// page returns the variables paginating an edge
+func page(variables map[string]string, offset, limit int) map[string]string {
+	variables["$offset"] = fmt.Sprintf("%d", offset)
+	variables["$first"] = fmt.Sprintf("%d", limit)
+	return variables
+}
+
+// Article returns the article with an arXiv ID
+func (s *DgraphStore) Article(arXivID string) (models.Article, error) {
+	var article models.Article
+	err := FindBy("arxivid", arXivID).Fields(articleFields).One(&article, s.DG)
+	return article, err
+}
+
+// articleEdge returns a page of the articles linked to an article by edge
+func (s *DgraphStore) articleEdge(arXivID, edge string, offset, limit int) ([]models.Article, error) {
+	query := `query Edge($id: string, $first: int, $offset: int){
+		articles(func: eq(arxivid, $id), first: 1){
+			uid
+			linked: ` + edge + `(first: $first, offset: $offset, orderdesc: submissiondate){
+				` + articleFields + `
+			}
+		}
+	}`
+	type Linked struct {
+		UID    string           `json:"uid"`
+		Linked []models.Article `json:"linked"`
+	}
+	type Root struct {
+		Articles []Linked `json:"articles"`
+	}
+	var r Root
+	err := s.query(query, page(map[string]string{"$id": arXivID}, offset, limit), &r)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.Articles) == 0 {
+		return nil, ErrNotFound
+	}
+	return r.Articles[0].Linked, nil
+}
+
+// Citations returns the articles citing an article
+func (s *DgraphStore) Citations(arXivID string, offset, limit int) ([]models.Article, error) {
+	return s.articleEdge(arXivID, CitationsEdge, offset, limit)
+}
+
+// References returns the articles cited by an article
+func (s *DgraphStore) References(arXivID string, offset, limit int) ([]models.Article, error) {
+	return s.articleEdge(arXivID, ReferencesEdge, offset, limit)
+}
+
+// Author returns the author with a name
+func (s *DgraphStore) Author(name string) (models.Author, error) {
+	var author models.Author
+	err := FindBy("name", name).Fields("uid", "name", "url", "citationcount", "hindex", "i10index").One(&author, s.DG)
+	return author, err
+}
+
+// AuthorArticles returns the articles of an author, newest first
+func (s *DgraphStore) AuthorArticles(name string, offset, limit int) ([]models.Article, error) {
+	query := `query AuthorArticles($name: string, $first: int, $offset: int){
+		authors(func: eq(name, $name), first: 1){
+			uid
+			articles: ~authors(first: $first, offset: $offset, orderdesc: submissiondate){
+				` + articleFields + `
+			}
+		}
+	}`
+	type Articles struct {
+		UID      string           `json:"uid"`
+		Articles []models.Article `json:"articles"`
+	}
+	type Root struct {
+		Authors []Articles `json:"authors"`
+	}
+	var r Root
+	err := s.query(query, page(map[string]string{"$name": name}, offset, limit), &r)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.Authors) == 0 {
+		return nil, ErrNotFound
+	}
+	return r.Authors[0].Articles, nil
+}
+
+// AuthorCommunity returns the community of an author with its members,
+// ordered by their rank facet
+func (s *DgraphStore) AuthorCommunity(name string) (models.Community, error) {
+	query := fmt.Sprintf(`query AuthorCommunity($name: string){
+		authors(func: eq(name, $name), first: 1){
+			uid
+			communities: ~members(first: 1){
+				%s
+			}
+		}
+	}`, communityFields())
+	type Communities struct {
+		UID         string             `json:"uid"`
+		Communities []models.Community `json:"communities"`
+	}
+	type Root struct {
+		Authors []Communities `json:"authors"`
+	}
+	var r Root
+	err := s.query(query, map[string]string{"$name": name}, &r)
+	if err != nil {
+		return models.Community{}, err
+	}
+	if len(r.Authors) == 0 || len(r.Authors[0].Communities) == 0 {
+		return models.Community{}, ErrNotFound
+	}
+	return r.Authors[0].Communities[0], nil
+}
+
+// communityFields are the predicates of a community returned by the Store,
+// with at most MaxLinked of its members ordered by their rank facet
+func communityFields() string {
+	return fmt.Sprintf(`uid
+		communitylabel
+		communitysize
+		communitymethod
+		members(first: %d) @facets(orderasc: rank){ uid name url citationcount hindex i10index }`, MaxLinked)
+}
+
+// AuthorCommunities returns by UID the communities of the authors
+func (s *DgraphStore) AuthorCommunities(uids []string) (map[string]models.Community, error) {
+	communities := make(map[string]models.Community, len(uids))
+	if len(uids) == 0 {
+		return communities, nil
+	}
+	for _, uid := range uids {
+		if !uidPattern.MatchString(uid) {
+			return nil, fmt.Errorf("Invalid UID %q", uid)
+		}
+	}
+	query := fmt.Sprintf(`{
+		authors(func: uid(%s)){
+			uid
+			communities: ~members(first: 1){
+				%s
+			}
+		}
+	}`, strings.Join(uids, ", "), communityFields())
+	type Communities struct {
+		UID         string             `json:"uid"`
+		Communities []models.Community `json:"communities"`
+	}
+	type Root struct {
+		Authors []Communities `json:"authors"`
+	}
+	var r Root
+	err := s.query(query, nil, &r)
+	if err != nil {
+		return nil, err
+	}
+	for _, author := range r.Authors {
+		if len(author.Communities) > 0 {
+			communities[author.UID] = author.Communities[0]
+		}
+	}
+	return communities, nil
+}
+
+// Coauthors returns the coauthors of an author, most frequent first
+func (s *DgraphStore) Coauthors(name string, offset, limit int) ([]Coauthor, error) {
+	query := `query Coauthors($name: string){
+		authors(func: eq(name, $name), first: 1){
+			uid
+			articles: ~authors {
+				authors { uid name url }
+			}
+		}
+	}`
+	type Articles struct {
+		UID      string           `json:"uid"`
+		Articles []models.Article `json:"articles"`
+	}
+	type Root struct {
+		Authors []Articles `json:"authors"`
+	}
+	var r Root
+	err := s.query(query, map[string]string{"$name": name}, &r)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.Authors) == 0 {
+		return nil, ErrNotFound
+	}
+	coauthors := CountCoauthors(r.Authors[0].UID, r.Authors[0].Articles)
+	return paginate(coauthors, offset, limit), nil
+}
+
+// CountCoauthors counts the articles shared with the authors of articles,
+// except the author with uid, and sorts them by decreasing count
+func CountCoauthors(uid string, articles []models.Article) []Coauthor {
+	index := make(map[string]int)
+	var coauthors []Coauthor
+	for _, article := range articles {
+		for _, author := range article.Authors {
+			if author.UID == uid {
+				continue
+			}
+			i, ok := index[author.UID]
+			if !ok {
+				i = len(coauthors)
+				index[author.UID] = i
+				coauthors = append(coauthors, Coauthor{Author: author})
+			}
+			coauthors[i].Shared++
+		}
+	}
+	sort.SliceStable(coauthors, func(i, j int) bool {
+		if coauthors[i].Shared != coauthors[j].Shared {
+			return coauthors[i].Shared > coauthors[j].Shared
+		}
+		return coauthors[i].Name < coauthors[j].Name
+	})
+	return coauthors
+}
+
+func paginate(coauthors []Coauthor, offset, limit int) []Coauthor {
+	if offset >= len(coauthors) {
+		return nil
+	}
+	coauthors = coauthors[offset:]
+	if limit > 0 && limit < len(coauthors) {
+		coauthors = coauthors[:limit]
+	}
+	return coauthors
+}
+
+// Search returns the articles matching a query, best ranked first
+func (s *DgraphStore) Search(query string, filters SearchFilters) ([]SearchResult, error) {
+	return Search(query, filters, s.DG)
+}
+
+// Articles returns the articles matching the filters, newest first
+func (s *DgraphStore) Articles(filters SearchFilters) ([]models.Article, error) {
+	c, err := newFilterClauses(filters)
+	if err != nil {
+		return nil, err
+	}
+	limit := filters.Limit
+	if limit <= 0 {
+		limit = MaxLinked
+	}
+	page(c.variables, filters.Offset, limit)
+	declarations := append([]string{"$first: int", "$offset: int"}, c.declarations...)
+	query := fmt.Sprintf(`query Articles(%s){
+		%s
+		articles(func: type(Article), orderdesc: submissiondate, first: $first, offset: $offset)
+			@filter(%s){
+			%s
+		}
+	}`, strings.Join(declarations, ", "), strings.Join(c.blocks, "\n\t\t"),
+		strings.Join(c.conditions, " AND "), articleFields)
+	type Root struct {
+		Articles []models.Article `json:"articles"`
+	}
+	var r Root
+	err = s.query(query, c.variables, &r)
+	return r.Articles, err
+}
+
+// Category returns the category with a code
+func (s *DgraphStore) Category(code string) (models.Category, error) {
+	query := `query Category($code: string){
+		categories(func: eq(categorycode, $code), first: 1){
+			uid
+			categorycode
+			categoryname
+		}
+	}`
+	type Root struct {
+		Categories []models.Category `json:"categories"`
+	}
+	var r Root
+	err := s.query(query, map[string]string{"$code": code}, &r)
+	if err != nil {
+		return models.Category{}, err
+	}
+	if len(r.Categories) == 0 {
+		return models.Category{}, ErrNotFound
+	}
+	return r.Categories[0], nil
+}
+
+var uidPattern = regexp.MustCompile(`^0x[0-9a-f]+$`)
+
+// Linked returns by UID the articles linked to the nodes with the UIDs
+func (s *DgraphStore) Linked(edge string, uids []string) (map[string][]models.Article, error) {
+	switch edge {
+	case AuthorArticlesEdge, CategoryArticlesEdge, ReferencesEdge, CitationsEdge:
+	default:
+		return nil, fmt.Errorf("Unknown edge %q", edge)
+	}
+	linked := make(map[string][]models.Article, len(uids))
+	if len(uids) == 0 {
+		return linked, nil
+	}
+	// The UIDs are validated and written in the query, uid() does not
+	// accept a list variable
+	for _, uid := range uids {
+		if !uidPattern.MatchString(uid) {
+			return nil, fmt.Errorf("Invalid UID %q", uid)
+		}
+	}
+	query := fmt.Sprintf(`{
+		nodes(func: uid(%s)){
+			uid
+			linked: %s(first: %d, orderdesc: submissiondate){
+				%s
+			}
+		}
+	}`, strings.Join(uids, ", "), edge, MaxLinked, articleFields)
+	type Node struct {
+		UID    string           `json:"uid"`
+		Linked []models.Article `json:"linked"`
+	}
+	type Root struct {
+		Nodes []Node `json:"nodes"`
+	}
+	var r Root
+	err := s.query(query, nil, &r)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range r.Nodes {
+		linked[node.UID] = node.Linked
+	}
+	return linked, nil
+}
+
+// ArticlesAfter returns the articles following the UID after, at most
+// first, with the UIDs and names of their authors and the UIDs and arXiv
+// IDs of their cited papers. Starting from "0x0" it pages through the whole
+// graph in UID order.
+func (s *DgraphStore) ArticlesAfter(after string, first int) ([]models.Article, error) {
+	query := `query ArticlesAfter($first: int, $after: string){
+		articles(func: type(Article), first: $first, after: $after){
+			uid
+			arxivid
+			title
+			submissiondate
+			authors { uid name }
+			citedpapers { uid arxivid }
+		}
+	}`
+	type Root struct {
+		Articles []models.Article `json:"articles"`
+	}
+	var r Root
+	variables := map[string]string{"$first": fmt.Sprintf("%d", first), "$after": after}
+	err := s.query(query, variables, &r)
+	return r.Articles, err
+}
@@ -0,0 +1,201 @@
+package downloads
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"pandor/storages"
+	"pandor/utils"
+)
+
+// StatusError is returned when the server answers with an unexpected status
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("GET %s: unexpected status %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// ContentTypeError is returned when the server sends something else than
+// the expected files, such as an HTML error page instead of a PDF
+type ContentTypeError struct {
+	URL         string
+	ContentType string
+}
+
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("GET %s: unexpected content type %q", e.URL, e.ContentType)
+}
+
+// Result describes a completed download
+type Result struct {
+	Key     string
+	Size    int64
+	SHA256  string
+	Resumed bool
+}
+
+// Manager downloads files to a Storage. Files are first streamed to a
+// partial file in TempDir, which lets an interrupted download resume with
+// a Range request, then checksummed and moved to the Storage.
+type Manager struct {
+	Client       *http.Client
+	Storage      storages.Storage
+	TempDir      string
+	UserAgent    string
+	ContentTypes []string // accepted media types, any when empty
+}
+
+// NewManager builds a Manager storing files in storage
+func NewManager(storage storages.Storage, tempDir string) *Manager {
+	return &Manager{
+		Client:  &http.Client{Timeout: 10 * time.Minute},
+		Storage: storage,
+		TempDir: tempDir,
+	}
+}
+
+// partialPath returns the partial file of a key
+func (m *Manager) partialPath(key string) string {
+	name := strings.Replace(filepath.Clean("/"+key), "/", "_", -1)
+	return filepath.Join(m.TempDir, name+".part")
+}
+
+func (m *Manager) acceptedType(contentType string) bool {
+	if len(m.ContentTypes) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range m.ContentTypes {
+		if strings.EqualFold(mediaType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+var contentRangePattern = regexp.MustCompile(`^bytes (\d+)-\d+/(\d+|\*)$`)
+
+// fetch appends the missing bytes of url to the partial file and tells
+// whether the download has been resumed
+func (m *Manager) fetch(url, partial string) (bool, error) {
+	var offset int64
+	if info, err := os.Stat(partial); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return false, err
+	}
+	if m.UserAgent != "" {
+		req.Header.Set("User-Agent", m.UserAgent)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	resumed := false
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// The server ignored the Range header, start over
+		flags |= os.O_TRUNC
+	case http.StatusPartialContent:
+		match := contentRangePattern.FindStringSubmatch(resp.Header.Get("Content-Range"))
+		if match == nil {
+			return false, fmt.Errorf("GET %s: invalid Content-Range %q", url, resp.Header.Get("Content-Range"))
+		}
+		start, _ := strconv.ParseInt(match[1], 10, 64)
+		if start != offset {
+			return false, fmt.Errorf("GET %s: range starts at %d instead of %d", url, start, offset)
+		}
+		flags |= os.O_APPEND
+		resumed = true
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is not a prefix of the current file
+		os.Remove(partial)
+		return false, &StatusError{URL: url, StatusCode: resp.StatusCode}
+	default:
+		return false, &StatusError{URL: url, StatusCode: resp.StatusCode}
+	}
+	if contentType := resp.Header.Get("Content-Type"); !m.acceptedType(contentType) {
+		return false, &ContentTypeError{URL: url, ContentType: contentType}
+	}
+
+	out, err := os.OpenFile(partial, flags, 0644)
+	if err != nil {
+		return false, err
+	}
+	n, err := io.Copy(out, resp.Body)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return resumed, err
+	}
+	if resp.ContentLength >= 0 && n != resp.ContentLength {
+		return resumed, fmt.Errorf("GET %s: received %d bytes instead of %d", url, n, resp.ContentLength)
+	}
+	return resumed, nil
+}
+
+// Download fetches url and stores it under key. An interrupted download
+// is resumed by the next call with the same key.
+func (m *Manager) Download(url, key string) (Result, error) {
+	result := Result{Key: key}
+
+	err := utils.BuildDir(m.TempDir)
+	if err != nil {
+		return result, err
+	}
+	partial := m.partialPath(key)
+
+	result.Resumed, err = m.fetch(url, partial)
+	if err != nil {
+		return result, err
+	}
+
+	f, err := os.Open(partial)
+	if err != nil {
+		return result, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	result.Size, err = io.Copy(h, f)
+	if err != nil {
+		return result, err
+	}
+	result.SHA256 = hex.EncodeToString(h.Sum(nil))
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return result, err
+	}
+
+	err = m.Storage.Put(key, f)
+	if err != nil {
+		return result, err
+	}
+	f.Close()
+	return result, os.Remove(partial)
+}
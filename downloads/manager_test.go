@@ -0,0 +1,93 @@
+package downloads
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"pandor/storages"
+)
+
+func TestDownload(t *testing.T) {
+	pdf := []byte("%PDF-1.4\n" + strings.Repeat("stream of a paper\n", 1000))
+	sum := sha256.Sum256(pdf)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pdf/0801.0001":
+			w.Header().Set("Content-Type", "application/pdf")
+			http.ServeContent(w, r, "0801.0001.pdf", time.Time{}, bytes.NewReader(pdf))
+		case "/pdf/0801.0002":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html>PDF unavailable</html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "pandor")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	storage := storages.NewLocal(filepath.Join(dir, "files"))
+	m := NewManager(storage, filepath.Join(dir, "tmp"))
+	m.ContentTypes = []string{"application/pdf"}
+
+	// An interrupted download left the beginning of the file
+	err = os.MkdirAll(m.TempDir, 0755)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = ioutil.WriteFile(m.partialPath("pdf/0801.0001.pdf"), pdf[:100], 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	res, err := m.Download(server.URL+"/pdf/0801.0001", "pdf/0801.0001.pdf")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !res.Resumed || res.Size != int64(len(pdf)) || res.SHA256 != hex.EncodeToString(sum[:]) {
+		log.Fatalf("Wrong result: %+v", res)
+	}
+	stored, err := ioutil.ReadFile(filepath.Join(dir, "files", "pdf", "0801.0001.pdf"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !bytes.Equal(stored, pdf) {
+		log.Fatal("Stored file differs from the served one")
+	}
+
+	// Downloading again overwrites the stored file
+	res, err = m.Download(server.URL+"/pdf/0801.0001", "pdf/0801.0001.pdf")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if res.Resumed {
+		log.Fatal("A complete download has been resumed")
+	}
+
+	_, err = m.Download(server.URL+"/pdf/0801.0002", "pdf/0801.0002.pdf")
+	if _, ok := err.(*ContentTypeError); !ok {
+		log.Fatalf("Wrong error for an HTML page: %v", err)
+	}
+	_, err = m.Download(server.URL+"/pdf/0801.0003", "pdf/0801.0003.pdf")
+	if e, ok := err.(*StatusError); !ok || e.StatusCode != http.StatusNotFound {
+		log.Fatalf("Wrong error for a missing file: %v", err)
+	}
+	if exists, _ := storage.Exists("pdf/0801.0003.pdf"); exists {
+		log.Fatal("A missing file has been stored")
+	}
+}
@@ -0,0 +1,77 @@
+package embeddings
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"pandor/databases"
+	"pandor/models"
+
+	"github.com/dgraph-io/dgo/v2"
+)
+
+// PageSize is the number of articles loaded at once by Build
+var PageSize = 500
+
+// Dims is the default size of the embeddings
+var Dims = 128
+
+// Text returns the text of an article which is embedded
+func Text(article models.Article) string {
+	return article.Title + "\n" + article.Abstract
+}
+
+// Build trains a model on the titles and abstracts of all the articles and
+// returns the index of their embeddings
+func Build(dims int, dg *dgo.Dgraph) (*Index, error) {
+	type Root struct {
+		Articles []models.Article `json:"articles"`
+	}
+
+	query := `query Embeddings($first: int, $after: string){
+		articles(func: type(Article), first: $first, after: $after) @filter(has(abstract)){
+			uid
+			arxivid
+			title
+			abstract
+		}
+	}`
+	var ids, docs []string
+	after := "0x0"
+	for {
+		variables := map[string]string{
+			"$first": fmt.Sprintf("%d", PageSize),
+			"$after": after,
+		}
+		resp, err := databases.QueryWithVars(query, variables, dg)
+		if err != nil {
+			return nil, err
+		}
+		var root Root
+		err = json.Unmarshal(resp.Json, &root)
+		if err != nil {
+			return nil, err
+		}
+		if len(root.Articles) == 0 {
+			break
+		}
+		for _, article := range root.Articles {
+			if article.ArXivID == "" {
+				continue
+			}
+			ids = append(ids, article.ArXivID)
+			docs = append(docs, Text(article))
+		}
+		after = root.Articles[len(root.Articles)-1].UID
+	}
+
+	model, err := Train(docs, dims)
+	if err != nil {
+		return nil, err
+	}
+	ix := &Index{Model: model}
+	for i, id := range ids {
+		ix.Add(id, model.Embed(docs[i]))
+	}
+	return ix, nil
+}
@@ -0,0 +1,106 @@
+package embeddings
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var corpus = map[string]string{
+	"a1": "Dwarf galaxies in the halo of Andromeda, stellar streams and dwarf satellites",
+	"a2": "Stellar streams around Andromeda reveal accreted dwarf galaxies",
+	"a3": "Satellite galaxies and stellar halos of spiral galaxies",
+	"b1": "Quantum error correction codes for superconducting qubits",
+	"b2": "Superconducting qubits with improved coherence and error correction",
+	"b3": "Fault tolerant quantum computing with surface codes and qubits",
+}
+
+func trainCorpus() *Index {
+	var ids, docs []string
+	for _, id := range []string{"a1", "a2", "a3", "b1", "b2", "b3"} {
+		ids = append(ids, id)
+		docs = append(docs, corpus[id])
+	}
+	model, err := Train(docs, 4)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ix := &Index{Model: model}
+	for i, id := range ids {
+		ix.Add(id, model.Embed(docs[i]))
+	}
+	return ix
+}
+
+func TestTokenize(t *testing.T) {
+	tokens := Tokenize("The Dwarf-galaxies of M31, and 2 streams")
+	expected := []string{"dwarf", "galaxies", "m31", "streams"}
+	if len(tokens) != len(expected) {
+		log.Fatalf("Wrong tokens %v", tokens)
+	}
+	for i := range tokens {
+		if tokens[i] != expected[i] {
+			log.Fatalf("Wrong tokens %v", tokens)
+		}
+	}
+}
+
+func TestSimilar(t *testing.T) {
+	ix := trainCorpus()
+	if ix.Model.Dims() != 4 {
+		log.Fatalf("Expected 4 dimensions, got %d", ix.Model.Dims())
+	}
+	neighbors, err := ix.Similar("a1", 2)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(neighbors) != 2 {
+		log.Fatalf("Expected 2 neighbors, got %v", neighbors)
+	}
+	for _, n := range neighbors {
+		if n.ArXivID[0] != 'a' {
+			log.Fatalf("Unrelated neighbor of a1: %v", neighbors)
+		}
+	}
+
+	neighbors = ix.SimilarToText("qubits error correction", 1)
+	if len(neighbors) != 1 || neighbors[0].ArXivID[0] != 'b' {
+		log.Fatalf("Unrelated neighbor of the text: %v", neighbors)
+	}
+
+	_, err = ix.Similar("c1", 2)
+	if err == nil {
+		log.Fatal("Unknown articles should fail")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "embeddings")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ix := trainCorpus()
+	path := filepath.Join(dir, "embeddings.gob")
+	err = Save(ix, path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expected, _ := ix.Similar("b2", 3)
+	neighbors, err := loaded.Similar("b2", 3)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for i := range expected {
+		if neighbors[i] != expected[i] {
+			log.Fatalf("Loaded index differs: %v instead of %v", neighbors, expected)
+		}
+	}
+}
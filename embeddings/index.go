@@ -0,0 +1,142 @@
+package embeddings
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"pandor/utils"
+)
+
+// Neighbor is an article close to a query
+type Neighbor struct {
+	ArXivID    string
+	Similarity float64
+}
+
+// Index stores the embeddings of the articles next to the graph, with the
+// model which computed them so that new texts can be compared
+type Index struct {
+	Model   *Model
+	IDs     []string
+	Vectors [][]float32
+
+	positions map[string]int
+}
+
+// Add sets the embedding of an article
+func (ix *Index) Add(arXivID string, vector []float32) {
+	if i, ok := ix.position(arXivID); ok {
+		ix.Vectors[i] = vector
+		return
+	}
+	ix.positions[arXivID] = len(ix.IDs)
+	ix.IDs = append(ix.IDs, arXivID)
+	ix.Vectors = append(ix.Vectors, vector)
+}
+
+func (ix *Index) position(arXivID string) (int, bool) {
+	if ix.positions == nil {
+		ix.positions = make(map[string]int, len(ix.IDs))
+		for i, id := range ix.IDs {
+			ix.positions[id] = i
+		}
+	}
+	i, ok := ix.positions[arXivID]
+	return i, ok
+}
+
+// Vector returns the embedding of an article
+func (ix *Index) Vector(arXivID string) ([]float32, bool) {
+	i, ok := ix.position(arXivID)
+	if !ok {
+		return nil, false
+	}
+	return ix.Vectors[i], true
+}
+
+// Nearest returns the k articles whose embeddings are the most similar to
+// a unit vector, by cosine similarity. The articles in exclude are skipped.
+func (ix *Index) Nearest(vector []float32, k int, exclude ...string) []Neighbor {
+	skipped := make(map[string]bool, len(exclude))
+	for _, id := range exclude {
+		skipped[id] = true
+	}
+	var neighbors []Neighbor
+	for i, v := range ix.Vectors {
+		if skipped[ix.IDs[i]] || len(v) != len(vector) {
+			continue
+		}
+		similarity := 0.0
+		for j := range v {
+			similarity += float64(v[j]) * float64(vector[j])
+		}
+		if similarity <= 0 {
+			continue
+		}
+		neighbors = append(neighbors, Neighbor{ArXivID: ix.IDs[i], Similarity: similarity})
+	}
+	sort.Slice(neighbors, func(i, j int) bool {
+		if neighbors[i].Similarity != neighbors[j].Similarity {
+			return neighbors[i].Similarity > neighbors[j].Similarity
+		}
+		return neighbors[i].ArXivID < neighbors[j].ArXivID
+	})
+	if k > 0 && len(neighbors) > k {
+		neighbors = neighbors[:k]
+	}
+	return neighbors
+}
+
+// Similar returns the k articles the most similar to an indexed article
+func (ix *Index) Similar(arXivID string, k int) ([]Neighbor, error) {
+	vector, ok := ix.Vector(arXivID)
+	if !ok {
+		return nil, fmt.Errorf("No embedding for %s, run embed first", arXivID)
+	}
+	return ix.Nearest(vector, k, arXivID), nil
+}
+
+// SimilarToText returns the k articles the most similar to a text
+func (ix *Index) SimilarToText(text string, k int) []Neighbor {
+	return ix.Nearest(ix.Model.Embed(text), k)
+}
+
+// Save writes the index to a file, atomically
+func Save(ix *Index, path string) error {
+	err := utils.BuildDir(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = gob.NewEncoder(f).Encode(ix)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Load reads an index written by Save
+func Load(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ix := &Index{}
+	err = gob.NewDecoder(f).Decode(ix)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid embeddings index %s: %v", path, err)
+	}
+	return ix, nil
+}
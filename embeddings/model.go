@@ -0,0 +1,259 @@
+package embeddings
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Parameters of the training
+var (
+	// MinDocFrequency drops the terms found in fewer documents
+	MinDocFrequency = 2
+	// MaxDocShare drops the terms found in a larger share of the documents
+	MaxDocShare = 0.5
+	// MaxTerms bounds the vocabulary, the most frequent terms are kept
+	MaxTerms = 20000
+	// Iterations of the subspace iteration computing the SVD
+	Iterations = 10
+)
+
+// stopWords are frequent English words which carry no topic
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "with": true, "that": true,
+	"this": true, "from": true, "which": true, "these": true, "their": true, "our": true,
+	"its": true, "has": true, "have": true, "was": true, "were": true, "been": true,
+	"can": true, "not": true, "but": true, "also": true, "into": true, "than": true,
+	"such": true, "both": true, "using": true, "use": true, "used": true, "show": true,
+	"paper": true, "present": true, "results": true, "based": true, "between": true,
+	"two": true, "new": true, "over": true, "only": true, "may": true, "all": true,
+	"here": true, "there": true, "other": true, "when": true, "where": true, "well": true,
+}
+
+// Tokenize splits a text in lower case words of three letters or more,
+// without the stop words
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := words[:0]
+	for _, w := range words {
+		if len([]rune(w)) >= 3 && !stopWords[w] {
+			tokens = append(tokens, w)
+		}
+	}
+	return tokens
+}
+
+// Sparse is a sparse vector of the vocabulary
+type Sparse struct {
+	Index []int
+	Value []float64
+}
+
+// Model projects the TF-IDF vector of a text on the main singular vectors
+// of the TF-IDF matrix of the training documents (latent semantic analysis)
+type Model struct {
+	Terms      map[string]int
+	IDF        []float64
+	Components [][]float64 // one vector of len(Terms) per dimension
+}
+
+// Dims returns the size of the embeddings
+func (m *Model) Dims() int {
+	return len(m.Components)
+}
+
+// TFIDF returns the normalized TF-IDF vector of tokens on the vocabulary
+// built by Vocabulary
+func TFIDF(tokens []string, terms map[string]int, idf []float64) Sparse {
+	counts := make(map[int]float64)
+	for _, t := range tokens {
+		if i, ok := terms[t]; ok {
+			counts[i]++
+		}
+	}
+	v := Sparse{}
+	for i := range counts {
+		v.Index = append(v.Index, i)
+	}
+	sort.Ints(v.Index)
+	norm := 0.0
+	for _, i := range v.Index {
+		w := (1 + math.Log(counts[i])) * idf[i]
+		v.Value = append(v.Value, w)
+		norm += w * w
+	}
+	norm = math.Sqrt(norm)
+	for j := range v.Value {
+		v.Value[j] /= norm
+	}
+	return v
+}
+
+// tfidf returns the normalized TF-IDF vector of a text
+func (m *Model) tfidf(text string) Sparse {
+	return TFIDF(Tokenize(text), m.Terms, m.IDF)
+}
+
+// Embed returns the unit embedding of a text, zero when none of its words
+// are in the vocabulary
+func (m *Model) Embed(text string) []float32 {
+	v := m.tfidf(text)
+	embedding := make([]float64, m.Dims())
+	for d, component := range m.Components {
+		for j, i := range v.Index {
+			embedding[d] += v.Value[j] * component[i]
+		}
+	}
+	return normalize(embedding)
+}
+
+func normalize(v []float64) []float32 {
+	norm := 0.0
+	for _, x := range v {
+		norm += x * x
+	}
+	norm = math.Sqrt(norm)
+	out := make([]float32, len(v))
+	if norm == 0 {
+		return out
+	}
+	for i, x := range v {
+		out[i] = float32(x / norm)
+	}
+	return out
+}
+
+// Vocabulary selects the terms of the tokenized documents and computes
+// their IDF
+func Vocabulary(docs [][]string) (map[string]int, []float64) {
+	df := make(map[string]int)
+	for _, tokens := range docs {
+		seen := make(map[string]bool)
+		for _, t := range tokens {
+			if !seen[t] {
+				seen[t] = true
+				df[t]++
+			}
+		}
+	}
+
+	maxDF := int(MaxDocShare * float64(len(docs)))
+	var kept []string
+	for t, n := range df {
+		if n >= MinDocFrequency && n <= maxDF {
+			kept = append(kept, t)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool {
+		if df[kept[i]] != df[kept[j]] {
+			return df[kept[i]] > df[kept[j]]
+		}
+		return kept[i] < kept[j]
+	})
+	if len(kept) > MaxTerms {
+		kept = kept[:MaxTerms]
+	}
+
+	terms := make(map[string]int, len(kept))
+	idf := make([]float64, len(kept))
+	for i, t := range kept {
+		terms[t] = i
+		idf[i] = math.Log(float64(1+len(docs))/float64(1+df[t])) + 1
+	}
+	return terms, idf
+}
+
+// Train builds a model of at most dims dimensions from the documents. The
+// truncated SVD is computed by subspace iteration on the sparse matrix.
+func Train(docs []string, dims int) (*Model, error) {
+	tokens := make([][]string, len(docs))
+	for i, doc := range docs {
+		tokens[i] = Tokenize(doc)
+	}
+	m := &Model{}
+	m.Terms, m.IDF = Vocabulary(tokens)
+	if len(m.Terms) == 0 {
+		return nil, fmt.Errorf("Empty vocabulary with %d documents", len(docs))
+	}
+	rows := make([]Sparse, len(docs))
+	for i, doc := range docs {
+		rows[i] = m.tfidf(doc)
+	}
+	if dims > len(m.Terms) {
+		dims = len(m.Terms)
+	}
+	if dims > len(docs) {
+		dims = len(docs)
+	}
+
+	// q holds dims vectors of the vocabulary, started at random with a
+	// fixed seed so that the training is reproducible
+	random := rand.New(rand.NewSource(1))
+	q := make([][]float64, dims)
+	for d := range q {
+		q[d] = make([]float64, len(m.Terms))
+		for i := range q[d] {
+			q[d][i] = random.NormFloat64()
+		}
+	}
+	orthonormalize(q)
+
+	for it := 0; it < Iterations; it++ {
+		// z = At A q
+		z := make([][]float64, dims)
+		for d := range z {
+			z[d] = make([]float64, len(m.Terms))
+		}
+		for _, row := range rows {
+			for d := range q {
+				b := 0.0
+				for j, i := range row.Index {
+					b += row.Value[j] * q[d][i]
+				}
+				if b == 0 {
+					continue
+				}
+				for j, i := range row.Index {
+					z[d][i] += b * row.Value[j]
+				}
+			}
+		}
+		q = z
+		orthonormalize(q)
+	}
+	m.Components = q
+	return m, nil
+}
+
+// orthonormalize applies the modified Gram-Schmidt process to the vectors,
+// the degenerate ones are zeroed
+func orthonormalize(vectors [][]float64) {
+	for d, v := range vectors {
+		for _, u := range vectors[:d] {
+			dot := 0.0
+			for i := range v {
+				dot += v[i] * u[i]
+			}
+			for i := range v {
+				v[i] -= dot * u[i]
+			}
+		}
+		norm := 0.0
+		for _, x := range v {
+			norm += x * x
+		}
+		norm = math.Sqrt(norm)
+		for i := range v {
+			if norm < 1e-12 {
+				v[i] = 0
+			} else {
+				v[i] /= norm
+			}
+		}
+	}
+}
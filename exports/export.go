@@ -0,0 +1,138 @@
+package exports
+
+import (
+	"io"
+	"strings"
+
+	"pandor/databases"
+	"pandor/models"
+)
+
+// PageSize is the number of articles loaded at once by Export
+var PageSize = 500
+
+// Source is the part of databases.Store read by the exports
+type Source interface {
+	Article(arXivID string) (models.Article, error)
+	Articles(filters databases.SearchFilters) ([]models.Article, error)
+	Linked(edge string, uids []string) (map[string][]models.Article, error)
+}
+
+// Selection describes the articles to export. When Around is set, the
+// articles are the ones within Depth citations of the article Around,
+// cited or citing, restricted by the date, author and category filters.
+type Selection struct {
+	Filters databases.SearchFilters
+	Around  string
+	Depth   int
+}
+
+// Matches tells whether an article passes the date, author and category
+// filters, the pagination is ignored
+func Matches(article models.Article, filters databases.SearchFilters) bool {
+	if !filters.From.IsZero() && article.SubmissionDate.Before(filters.From) {
+		return false
+	}
+	if !filters.To.IsZero() && article.SubmissionDate.After(filters.To) {
+		return false
+	}
+	if filters.Author != "" {
+		found := false
+		for _, author := range article.Authors {
+			found = found || author.Name == filters.Author
+		}
+		if !found {
+			return false
+		}
+	}
+	if filters.Category != "" {
+		found := false
+		for _, category := range article.Categories {
+			found = found || category.Code == filters.Category ||
+				strings.HasPrefix(category.Code, filters.Category+".")
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Neighborhood returns the article with an arXiv ID and the articles
+// within depth citations of it, in breadth-first order
+func Neighborhood(source Source, arXivID string, depth int) ([]models.Article, error) {
+	start, err := source.Article(arXivID)
+	if err != nil {
+		return nil, err
+	}
+	articles := []models.Article{start}
+	seen := map[string]bool{start.UID: true}
+	frontier := []string{start.UID}
+	for d := 0; d < depth && len(frontier) > 0; d++ {
+		var next []string
+		for _, edge := range []string{databases.ReferencesEdge, databases.CitationsEdge} {
+			linked, err := source.Linked(edge, frontier)
+			if err != nil {
+				return nil, err
+			}
+			for _, uid := range frontier {
+				for _, article := range linked[uid] {
+					if seen[article.UID] {
+						continue
+					}
+					seen[article.UID] = true
+					articles = append(articles, article)
+					next = append(next, article.UID)
+				}
+			}
+		}
+		frontier = next
+	}
+	return articles, nil
+}
+
+// Export writes the selected articles to w and returns their number
+func Export(source Source, selection Selection, format Format, w io.Writer) (int, error) {
+	err := format.Begin(w)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	if selection.Around != "" {
+		articles, err := Neighborhood(source, selection.Around, selection.Depth)
+		if err != nil {
+			return 0, err
+		}
+		for _, article := range articles {
+			if !Matches(article, selection.Filters) {
+				continue
+			}
+			err = format.Write(w, article)
+			if err != nil {
+				return count, err
+			}
+			count++
+		}
+	} else {
+		filters := selection.Filters
+		filters.Limit = PageSize
+		for filters.Offset = 0; ; filters.Offset += PageSize {
+			articles, err := source.Articles(filters)
+			if err != nil {
+				return count, err
+			}
+			for _, article := range articles {
+				err = format.Write(w, article)
+				if err != nil {
+					return count, err
+				}
+				count++
+			}
+			if len(articles) < PageSize {
+				break
+			}
+		}
+	}
+	return count, format.End(w)
+}
@@ -0,0 +1,179 @@
+package exports
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"pandor/databases"
+	"pandor/models"
+)
+
+var article = models.Article{
+	UID:            "0x10",
+	ArXivID:        "0801.0002",
+	Title:          "Dwarf galaxies & {streams}\n  in M31",
+	Abstract:       "We find 50% more\nsatellites.",
+	SubmissionDate: time.Date(2008, 1, 3, 0, 0, 0, 0, time.UTC),
+	Authors: []models.Author{
+		{UID: "0x1", Name: "Huxor_A"},
+		{UID: "0x2", Name: "Alice Martin"},
+	},
+	Categories: []models.Category{{Code: "astro-ph.GA"}, {Code: "astro-ph.CO"}},
+	PDFURL:     "https://arxiv.org/pdf/0801.0002",
+}
+
+func TestBibTeX(t *testing.T) {
+	var b bytes.Buffer
+	err := BibTeX{}.Write(&b, article)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expected := `@misc{0801.0002,
+  title = {Dwarf galaxies \& \{streams\} in M31},
+  author = {Huxor, A and Martin, Alice},
+  year = {2008},
+  month = jan,
+  eprint = {0801.0002},
+  archivePrefix = {arXiv},
+  primaryClass = {astro-ph.GA},
+  url = {https://arxiv.org/abs/0801.0002},
+  abstract = {We find 50\% more satellites.},
+}
+
+`
+	if b.String() != expected {
+		log.Fatalf("Wrong BibTeX entry:\n%s", b.String())
+	}
+}
+
+func TestRIS(t *testing.T) {
+	var b bytes.Buffer
+	err := RIS{}.Write(&b, article)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, line := range []string{"TY  - UNPB\r\n", "AU  - Huxor, A\r\n", "AU  - Martin, Alice\r\n",
+		"DA  - 2008/01/03\r\n", "KW  - astro-ph.CO\r\n", "L1  - https://arxiv.org/pdf/0801.0002\r\n"} {
+		if !strings.Contains(b.String(), line) {
+			log.Fatalf("Missing %q in the RIS record:\n%s", line, b.String())
+		}
+	}
+	if !strings.HasSuffix(b.String(), "ER  - \r\n\r\n") {
+		log.Fatalf("Unterminated RIS record:\n%s", b.String())
+	}
+}
+
+func TestCSLJSON(t *testing.T) {
+	var b bytes.Buffer
+	format := &CSLJSON{}
+	format.Begin(&b)
+	format.Write(&b, article)
+	format.Write(&b, models.Article{ArXivID: "hep-th/9901001", Title: "Strings"})
+	format.End(&b)
+
+	var items []map[string]interface{}
+	err := json.Unmarshal(b.Bytes(), &items)
+	if err != nil {
+		log.Fatalf("Invalid CSL-JSON %v:\n%s", err, b.String())
+	}
+	if len(items) != 2 || items[0]["id"] != "arXiv:0801.0002" || items[1]["URL"] != "https://arxiv.org/abs/hep-th/9901001" {
+		log.Fatalf("Wrong CSL items %v", items)
+	}
+	authors := items[0]["author"].([]interface{})
+	if authors[1].(map[string]interface{})["family"] != "Martin" {
+		log.Fatalf("Wrong CSL authors %v", authors)
+	}
+}
+
+// fakeSource is a chain of citations 0x10 -> 0x11 -> 0x12
+type fakeSource struct {
+	articles []models.Article
+	pages    int
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{articles: []models.Article{
+		article,
+		{UID: "0x11", ArXivID: "0801.0003", Title: "Streams", Categories: []models.Category{{Code: "astro-ph.GA"}}},
+		{UID: "0x12", ArXivID: "hep-th/9901001", Title: "Strings", Categories: []models.Category{{Code: "hep-th"}}},
+	}}
+}
+
+func (s *fakeSource) Article(arXivID string) (models.Article, error) {
+	for _, a := range s.articles {
+		if a.ArXivID == arXivID {
+			return a, nil
+		}
+	}
+	return models.Article{}, databases.ErrNotFound
+}
+
+func (s *fakeSource) Articles(filters databases.SearchFilters) ([]models.Article, error) {
+	s.pages++
+	if filters.Offset >= len(s.articles) {
+		return nil, nil
+	}
+	page := s.articles[filters.Offset:]
+	if len(page) > filters.Limit {
+		page = page[:filters.Limit]
+	}
+	return page, nil
+}
+
+func (s *fakeSource) Linked(edge string, uids []string) (map[string][]models.Article, error) {
+	linked := make(map[string][]models.Article)
+	for _, uid := range uids {
+		for i, a := range s.articles {
+			if a.UID != uid {
+				continue
+			}
+			if edge == databases.ReferencesEdge && i+1 < len(s.articles) {
+				linked[uid] = append(linked[uid], s.articles[i+1])
+			}
+			if edge == databases.CitationsEdge && i > 0 {
+				linked[uid] = append(linked[uid], s.articles[i-1])
+			}
+		}
+	}
+	return linked, nil
+}
+
+func TestExport(t *testing.T) {
+	PageSize = 2
+	source := newFakeSource()
+	var b bytes.Buffer
+	count, err := Export(source, Selection{}, JSONLines{}, &b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if count != 3 || strings.Count(b.String(), "\n") != 3 || source.pages != 2 {
+		log.Fatalf("Wrong export of %d articles in %d pages:\n%s", count, source.pages, b.String())
+	}
+
+	b.Reset()
+	selection := Selection{Around: "0801.0003", Depth: 1}
+	selection.Filters.Category = "astro-ph"
+	count, err = Export(source, selection, BibTeX{}, &b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if count != 2 || !strings.Contains(b.String(), "@misc{0801.0002,") || strings.Contains(b.String(), "hep-th") {
+		log.Fatalf("Wrong export of the neighborhood:\n%s", b.String())
+	}
+
+	articles, err := Neighborhood(source, "0801.0002", 2)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(articles) != 3 {
+		log.Fatalf("Wrong neighborhood %v", articles)
+	}
+	_, err = Neighborhood(source, "0000.0000", 1)
+	if err != databases.ErrNotFound {
+		log.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,280 @@
+package exports
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"pandor/models"
+)
+
+// AbsURL is the prefix of the abstract pages, used when an article has no
+// MetaURL
+var AbsURL = "https://arxiv.org/abs/"
+
+// Format writes articles in a bibliographic format. Begin and End frame the
+// articles, for the formats which are not a plain sequence of records.
+type Format interface {
+	Begin(w io.Writer) error
+	Write(w io.Writer, article models.Article) error
+	End(w io.Writer) error
+}
+
+// FormatNames lists the names accepted by NewFormat
+var FormatNames = []string{"bibtex", "ris", "csljson", "jsonl"}
+
+// NewFormat returns the format with a name
+func NewFormat(name string) (Format, error) {
+	switch name {
+	case "bibtex":
+		return BibTeX{}, nil
+	case "ris":
+		return RIS{}, nil
+	case "csljson":
+		return &CSLJSON{}, nil
+	case "jsonl":
+		return JSONLines{}, nil
+	}
+	return nil, fmt.Errorf("Unknown export format %q, expected one of %s", name, strings.Join(FormatNames, ", "))
+}
+
+// URL returns the abstract page of an article
+func URL(article models.Article) string {
+	if article.MetaURL != "" {
+		return article.MetaURL
+	}
+	return AbsURL + article.ArXivID
+}
+
+// SplitName returns the family and given names of an author. The names
+// taken from the arXiv search links are written as Family_I, the others
+// as Given Family.
+func SplitName(name string) (string, string) {
+	name = strings.TrimSpace(name)
+	if i := strings.Index(name, "_"); i > 0 {
+		return name[:i], strings.Replace(name[i+1:], "_", " ", -1)
+	}
+	if i := strings.LastIndex(name, " "); i > 0 {
+		return name[i+1:], name[:i]
+	}
+	return name, ""
+}
+
+// oneLine collapses the whitespace of a text
+func oneLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// primaryCategory returns the code of the first category of an article
+func primaryCategory(article models.Article) string {
+	if len(article.Categories) == 0 {
+		return ""
+	}
+	return article.Categories[0].Code
+}
+
+// BibTeX writes @misc entries, as recommended by arXiv for the preprints
+type BibTeX struct{}
+
+var bibTeXEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`&`, `\&`,
+	`%`, `\%`,
+	`$`, `\$`,
+	`#`, `\#`,
+	`_`, `\_`,
+	`~`, `\textasciitilde{}`,
+	`^`, `\textasciicircum{}`,
+)
+
+var months = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+
+// BibTeXKey returns the citation key of an article, its arXiv ID without
+// slashes
+func BibTeXKey(article models.Article) string {
+	return strings.Replace(article.ArXivID, "/", "_", -1)
+}
+
+// Begin does nothing
+func (BibTeX) Begin(w io.Writer) error { return nil }
+
+// End does nothing
+func (BibTeX) End(w io.Writer) error { return nil }
+
+// Write an article
+func (BibTeX) Write(w io.Writer, article models.Article) error {
+	var b strings.Builder
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "  %s = {%s},\n", name, value)
+		}
+	}
+	fmt.Fprintf(&b, "@misc{%s,\n", BibTeXKey(article))
+	field("title", bibTeXEscaper.Replace(oneLine(article.Title)))
+	var authors []string
+	for _, author := range article.Authors {
+		family, given := SplitName(author.Name)
+		if given != "" {
+			family += ", " + given
+		}
+		authors = append(authors, bibTeXEscaper.Replace(family))
+	}
+	field("author", strings.Join(authors, " and "))
+	if !article.SubmissionDate.IsZero() {
+		field("year", fmt.Sprintf("%d", article.SubmissionDate.Year()))
+		fmt.Fprintf(&b, "  month = %s,\n", months[article.SubmissionDate.Month()-1])
+	}
+	field("eprint", article.ArXivID)
+	field("archivePrefix", "arXiv")
+	field("primaryClass", primaryCategory(article))
+	field("url", URL(article))
+	field("abstract", bibTeXEscaper.Replace(oneLine(article.Abstract)))
+	b.WriteString("}\n\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// RIS writes UNPB (unpublished work) records
+type RIS struct{}
+
+// Begin does nothing
+func (RIS) Begin(w io.Writer) error { return nil }
+
+// End does nothing
+func (RIS) End(w io.Writer) error { return nil }
+
+// Write an article
+func (RIS) Write(w io.Writer, article models.Article) error {
+	var b strings.Builder
+	tag := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "%s  - %s\r\n", name, value)
+		}
+	}
+	tag("TY", "UNPB")
+	tag("ID", article.ArXivID)
+	tag("TI", oneLine(article.Title))
+	for _, author := range article.Authors {
+		family, given := SplitName(author.Name)
+		if given != "" {
+			family += ", " + given
+		}
+		tag("AU", family)
+	}
+	if !article.SubmissionDate.IsZero() {
+		tag("PY", fmt.Sprintf("%d", article.SubmissionDate.Year()))
+		tag("DA", article.SubmissionDate.Format("2006/01/02"))
+	}
+	tag("AB", oneLine(article.Abstract))
+	for _, category := range article.Categories {
+		tag("KW", category.Code)
+	}
+	tag("PB", "arXiv")
+	tag("N1", "arXiv:"+article.ArXivID)
+	tag("UR", URL(article))
+	if article.PDFURL != "" {
+		tag("L1", article.PDFURL)
+	}
+	b.WriteString("ER  - \r\n\r\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// CSLJSON writes a JSON array of CSL items, read by Zotero and pandoc
+type CSLJSON struct {
+	count int
+}
+
+// cslName is a name variable of CSL-JSON
+type cslName struct {
+	Family string `json:"family"`
+	Given  string `json:"given,omitempty"`
+}
+
+// cslDate is a date variable of CSL-JSON
+type cslDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+// cslItem is the subset of the CSL-JSON variables filled from an article
+type cslItem struct {
+	ID       string    `json:"id"`
+	Type     string    `json:"type"`
+	Title    string    `json:"title"`
+	Author   []cslName `json:"author,omitempty"`
+	Issued   *cslDate  `json:"issued,omitempty"`
+	Abstract string    `json:"abstract,omitempty"`
+	Number   string    `json:"number"`
+	Genre    string    `json:"genre"`
+	Source   string    `json:"source"`
+	URL      string    `json:"URL"`
+	Keyword  string    `json:"keyword,omitempty"`
+}
+
+// Begin opens the array
+func (c *CSLJSON) Begin(w io.Writer) error {
+	c.count = 0
+	_, err := io.WriteString(w, "[")
+	return err
+}
+
+// End closes the array
+func (c *CSLJSON) End(w io.Writer) error {
+	_, err := io.WriteString(w, "\n]\n")
+	return err
+}
+
+// Write an article
+func (c *CSLJSON) Write(w io.Writer, article models.Article) error {
+	item := cslItem{
+		ID:       "arXiv:" + article.ArXivID,
+		Type:     "article",
+		Title:    oneLine(article.Title),
+		Abstract: oneLine(article.Abstract),
+		Number:   article.ArXivID,
+		Genre:    "Preprint",
+		Source:   "arXiv.org",
+		URL:      URL(article),
+	}
+	for _, author := range article.Authors {
+		family, given := SplitName(author.Name)
+		item.Author = append(item.Author, cslName{Family: family, Given: given})
+	}
+	if d := article.SubmissionDate; !d.IsZero() {
+		item.Issued = &cslDate{DateParts: [][]int{{d.Year(), int(d.Month()), d.Day()}}}
+	}
+	var codes []string
+	for _, category := range article.Categories {
+		codes = append(codes, category.Code)
+	}
+	item.Keyword = strings.Join(codes, ", ")
+
+	body, err := json.MarshalIndent(item, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	separator := ",\n  "
+	if c.count == 0 {
+		separator = "\n  "
+	}
+	c.count++
+	_, err = io.WriteString(w, separator+string(body))
+	return err
+}
+
+// JSONLines writes each article as a JSON object on its own line
+type JSONLines struct{}
+
+// Begin does nothing
+func (JSONLines) Begin(w io.Writer) error { return nil }
+
+// End does nothing
+func (JSONLines) End(w io.Writer) error { return nil }
+
+// Write an article
+func (JSONLines) Write(w io.Writer, article models.Article) error {
+	return json.NewEncoder(w).Encode(article)
+}
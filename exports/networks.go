@@ -0,0 +1,375 @@
+package exports
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"pandor/models"
+)
+
+// NetworkSource pages through the whole graph, see
+// databases.DgraphStore.ArticlesAfter
+type NetworkSource interface {
+	ArticlesAfter(after string, first int) ([]models.Article, error)
+}
+
+// Networks which can be exported
+const (
+	CitationNetwork  = "citation"  // articles, linked to the articles they cite
+	CoauthorNetwork  = "coauthor"  // authors, linked when they share articles
+	BipartiteNetwork = "bipartite" // authors linked to their articles
+)
+
+// NetworkNames lists the names accepted by ExportNetwork
+var NetworkNames = []string{CitationNetwork, CoauthorNetwork, BipartiteNetwork}
+
+// Kinds of nodes
+const (
+	ArticleNode = "article"
+	AuthorNode  = "author"
+)
+
+// Node of a network. The articles are identified by their arXiv ID and
+// the authors by their name.
+type Node struct {
+	ID    string
+	Label string
+	Kind  string
+	Year  int // submission year of the articles
+}
+
+// Edge of a network
+type Edge struct {
+	Source string
+	Target string
+	Weight int
+}
+
+// GraphWriter writes a network in a graph format. The nodes are all written
+// before the edges.
+type GraphWriter interface {
+	Begin(w io.Writer, directed bool) error
+	Node(w io.Writer, node Node) error
+	Edge(w io.Writer, edge Edge) error
+	End(w io.Writer) error
+}
+
+// GraphFormatNames lists the names accepted by NewGraphWriter
+var GraphFormatNames = []string{"graphml", "gexf", "csv"}
+
+// NewGraphWriter returns the graph format with a name
+func NewGraphWriter(name string) (GraphWriter, error) {
+	switch name {
+	case "graphml":
+		return &GraphML{}, nil
+	case "gexf":
+		return &GEXF{}, nil
+	case "csv":
+		return &EdgeList{}, nil
+	}
+	return nil, fmt.Errorf("Unknown graph format %q, expected one of %s", name, strings.Join(GraphFormatNames, ", "))
+}
+
+// articleID identifies an article node
+func articleID(article models.Article) string {
+	if article.ArXivID != "" {
+		return article.ArXivID
+	}
+	return article.UID
+}
+
+// authorID identifies an author node
+func authorID(author models.Author) string {
+	if author.Name != "" {
+		return author.Name
+	}
+	return author.UID
+}
+
+func articleNode(article models.Article) Node {
+	node := Node{ID: articleID(article), Label: oneLine(article.Title), Kind: ArticleNode}
+	if !article.SubmissionDate.IsZero() {
+		node.Year = article.SubmissionDate.Year()
+	}
+	return node
+}
+
+func authorNode(author models.Author) Node {
+	return Node{ID: authorID(author), Label: author.Name, Kind: AuthorNode}
+}
+
+// eachArticle calls f on every article of the source, page by page
+func eachArticle(source NetworkSource, f func(models.Article) error) error {
+	after := "0x0"
+	for {
+		articles, err := source.ArticlesAfter(after, PageSize)
+		if err != nil {
+			return err
+		}
+		if len(articles) == 0 {
+			return nil
+		}
+		for _, article := range articles {
+			if err = f(article); err != nil {
+				return err
+			}
+		}
+		after = articles[len(articles)-1].UID
+	}
+}
+
+// ExportNetwork writes a network of the graph and returns its numbers of
+// nodes and edges. The graph is read twice, for the nodes then the edges, so
+// that only the co-authorship network has to be held in memory: its edges
+// are weighted by the number of shared articles.
+func ExportNetwork(source NetworkSource, network string, writer GraphWriter, w io.Writer) (int, int, error) {
+	nodes, edges := 0, 0
+	switch network {
+	case CitationNetwork, CoauthorNetwork, BipartiteNetwork:
+	default:
+		return 0, 0, fmt.Errorf("Unknown network %q, expected one of %s", network, strings.Join(NetworkNames, ", "))
+	}
+	err := writer.Begin(w, network != CoauthorNetwork)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// First pass, the nodes
+	seenAuthors := make(map[string]bool)
+	writeNode := func(node Node) error {
+		nodes++
+		return writer.Node(w, node)
+	}
+	err = eachArticle(source, func(article models.Article) error {
+		if network != CoauthorNetwork {
+			if err := writeNode(articleNode(article)); err != nil {
+				return err
+			}
+		}
+		if network == CitationNetwork {
+			return nil
+		}
+		for _, author := range article.Authors {
+			if id := authorID(author); !seenAuthors[id] {
+				seenAuthors[id] = true
+				if err := writeNode(authorNode(author)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nodes, edges, err
+	}
+	seenAuthors = nil
+
+	// Second pass, the edges
+	writeEdge := func(edge Edge) error {
+		edges++
+		return writer.Edge(w, edge)
+	}
+	shared := make(map[[2]string]int)
+	err = eachArticle(source, func(article models.Article) error {
+		switch network {
+		case CitationNetwork:
+			for _, cited := range article.CitedPapers {
+				if err := writeEdge(Edge{Source: articleID(article), Target: articleID(cited), Weight: 1}); err != nil {
+					return err
+				}
+			}
+		case BipartiteNetwork:
+			for _, author := range article.Authors {
+				if err := writeEdge(Edge{Source: authorID(author), Target: articleID(article), Weight: 1}); err != nil {
+					return err
+				}
+			}
+		case CoauthorNetwork:
+			for i, a := range article.Authors {
+				for _, b := range article.Authors[i+1:] {
+					pair := [2]string{authorID(a), authorID(b)}
+					if pair[0] == pair[1] {
+						continue
+					}
+					if pair[0] > pair[1] {
+						pair[0], pair[1] = pair[1], pair[0]
+					}
+					shared[pair]++
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nodes, edges, err
+	}
+	if network == CoauthorNetwork {
+		pairs := make([][2]string, 0, len(shared))
+		for pair := range shared {
+			pairs = append(pairs, pair)
+		}
+		sort.Slice(pairs, func(i, j int) bool {
+			if pairs[i][0] != pairs[j][0] {
+				return pairs[i][0] < pairs[j][0]
+			}
+			return pairs[i][1] < pairs[j][1]
+		})
+		for _, pair := range pairs {
+			if err = writeEdge(Edge{Source: pair[0], Target: pair[1], Weight: shared[pair]}); err != nil {
+				return nodes, edges, err
+			}
+		}
+	}
+	return nodes, edges, writer.End(w)
+}
+
+// escape returns the XML text of s
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// GraphML writes the GraphML format, read by NetworkX and Gephi
+type GraphML struct {
+	edges int
+}
+
+// Begin writes the header and the attribute keys
+func (g *GraphML) Begin(w io.Writer, directed bool) error {
+	g.edges = 0
+	direction := "undirected"
+	if directed {
+		direction = "directed"
+	}
+	_, err := fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="label" for="node" attr.name="label" attr.type="string"/>
+  <key id="kind" for="node" attr.name="kind" attr.type="string"/>
+  <key id="year" for="node" attr.name="year" attr.type="int"/>
+  <key id="weight" for="edge" attr.name="weight" attr.type="int"/>
+  <graph id="pandor" edgedefault="%s">
+`, direction)
+	return err
+}
+
+// Node writes a node
+func (g *GraphML) Node(w io.Writer, node Node) error {
+	year := ""
+	if node.Year != 0 {
+		year = fmt.Sprintf(`<data key="year">%d</data>`, node.Year)
+	}
+	_, err := fmt.Fprintf(w, "    <node id=\"%s\"><data key=\"label\">%s</data><data key=\"kind\">%s</data>%s</node>\n",
+		escape(node.ID), escape(node.Label), node.Kind, year)
+	return err
+}
+
+// Edge writes an edge
+func (g *GraphML) Edge(w io.Writer, edge Edge) error {
+	g.edges++
+	_, err := fmt.Fprintf(w, "    <edge id=\"e%d\" source=\"%s\" target=\"%s\"><data key=\"weight\">%d</data></edge>\n",
+		g.edges, escape(edge.Source), escape(edge.Target), edge.Weight)
+	return err
+}
+
+// End closes the document
+func (g *GraphML) End(w io.Writer) error {
+	_, err := io.WriteString(w, "  </graph>\n</graphml>\n")
+	return err
+}
+
+// GEXF writes the GEXF 1.2 format of Gephi
+type GEXF struct {
+	inEdges bool
+	edges   int
+}
+
+// Begin writes the header and opens the nodes
+func (g *GEXF) Begin(w io.Writer, directed bool) error {
+	g.inEdges, g.edges = false, 0
+	direction := "undirected"
+	if directed {
+		direction = "directed"
+	}
+	_, err := fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<gexf xmlns="http://www.gexf.net/1.2draft" version="1.2">
+  <graph mode="static" defaultedgetype="%s">
+    <attributes class="node">
+      <attribute id="0" title="kind" type="string"/>
+      <attribute id="1" title="year" type="integer"/>
+    </attributes>
+    <nodes>
+`, direction)
+	return err
+}
+
+// Node writes a node
+func (g *GEXF) Node(w io.Writer, node Node) error {
+	year := ""
+	if node.Year != 0 {
+		year = fmt.Sprintf(`<attvalue for="1" value="%d"/>`, node.Year)
+	}
+	_, err := fmt.Fprintf(w, "      <node id=\"%s\" label=\"%s\"><attvalues><attvalue for=\"0\" value=\"%s\"/>%s</attvalues></node>\n",
+		escape(node.ID), escape(node.Label), node.Kind, year)
+	return err
+}
+
+// closeNodes ends the nodes and opens the edges
+func (g *GEXF) closeNodes(w io.Writer) error {
+	if g.inEdges {
+		return nil
+	}
+	g.inEdges = true
+	_, err := io.WriteString(w, "    </nodes>\n    <edges>\n")
+	return err
+}
+
+// Edge writes an edge
+func (g *GEXF) Edge(w io.Writer, edge Edge) error {
+	if err := g.closeNodes(w); err != nil {
+		return err
+	}
+	g.edges++
+	_, err := fmt.Fprintf(w, "      <edge id=\"%d\" source=\"%s\" target=\"%s\" weight=\"%d\"/>\n",
+		g.edges, escape(edge.Source), escape(edge.Target), edge.Weight)
+	return err
+}
+
+// End closes the document
+func (g *GEXF) End(w io.Writer) error {
+	if err := g.closeNodes(w); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "    </edges>\n  </graph>\n</gexf>\n")
+	return err
+}
+
+// EdgeList writes the edges as CSV, with a source,target,weight header.
+// The nodes are implied by the edges.
+type EdgeList struct {
+	csv *csv.Writer
+}
+
+// Begin writes the header
+func (e *EdgeList) Begin(w io.Writer, directed bool) error {
+	e.csv = csv.NewWriter(w)
+	return e.csv.Write([]string{"source", "target", "weight"})
+}
+
+// Node does nothing
+func (e *EdgeList) Node(w io.Writer, node Node) error { return nil }
+
+// Edge writes an edge
+func (e *EdgeList) Edge(w io.Writer, edge Edge) error {
+	return e.csv.Write([]string{edge.Source, edge.Target, fmt.Sprintf("%d", edge.Weight)})
+}
+
+// End flushes the CSV writer
+func (e *EdgeList) End(w io.Writer) error {
+	e.csv.Flush()
+	return e.csv.Error()
+}
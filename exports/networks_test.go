@@ -0,0 +1,126 @@
+package exports
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"pandor/models"
+)
+
+// pagedSource serves articles one page of two at a time
+type pagedSource struct {
+	articles []models.Article
+	calls    int
+}
+
+func (s *pagedSource) ArticlesAfter(after string, first int) ([]models.Article, error) {
+	s.calls++
+	if first > 2 {
+		first = 2
+	}
+	for i, a := range s.articles {
+		if a.UID > after {
+			end := i + first
+			if end > len(s.articles) {
+				end = len(s.articles)
+			}
+			return s.articles[i:end], nil
+		}
+	}
+	return nil, nil
+}
+
+func newPagedSource() *pagedSource {
+	lewis := models.Author{UID: "0x1", Name: "Lewis_G"}
+	huxor := models.Author{UID: "0x2", Name: "Huxor_A"}
+	doe := models.Author{UID: "0x3", Name: "O'Doe & Sons"}
+	return &pagedSource{articles: []models.Article{
+		{UID: "0x10", ArXivID: "0801.0002", Title: "Dwarf <galaxies>", Authors: []models.Author{lewis, huxor},
+			SubmissionDate: time.Date(2008, 1, 3, 0, 0, 0, 0, time.UTC),
+			CitedPapers:    []models.Article{{UID: "0x11", ArXivID: "0801.0003"}, {UID: "0x12", ArXivID: "hep-th/9901001"}}},
+		{UID: "0x11", ArXivID: "0801.0003", Title: "Streams", Authors: []models.Author{huxor, lewis, doe},
+			CitedPapers: []models.Article{{UID: "0x12", ArXivID: "hep-th/9901001"}}},
+		{UID: "0x12", ArXivID: "hep-th/9901001", Title: "Strings", Authors: []models.Author{doe}},
+	}}
+}
+
+// wellFormed fails when the document is not valid XML
+func wellFormed(document string) {
+	decoder := xml.NewDecoder(strings.NewReader(document))
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			log.Fatalf("Invalid XML %v:\n%s", err, document)
+		}
+	}
+}
+
+func TestCoauthorNetwork(t *testing.T) {
+	var b bytes.Buffer
+	nodes, edges, err := ExportNetwork(newPagedSource(), CoauthorNetwork, &EdgeList{}, &b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expected := "source,target,weight\n" +
+		"Huxor_A,Lewis_G,2\n" +
+		"Huxor_A,O'Doe & Sons,1\n" +
+		"Lewis_G,O'Doe & Sons,1\n"
+	if nodes != 3 || edges != 3 || b.String() != expected {
+		log.Fatalf("Wrong co-authorship network of %d nodes and %d edges:\n%s", nodes, edges, b.String())
+	}
+}
+
+func TestCitationNetwork(t *testing.T) {
+	source := newPagedSource()
+	var b bytes.Buffer
+	nodes, edges, err := ExportNetwork(source, CitationNetwork, &GraphML{}, &b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if nodes != 3 || edges != 3 {
+		log.Fatalf("Wrong citation network of %d nodes and %d edges", nodes, edges)
+	}
+	// Two passes of two pages, each ended by an empty page
+	if source.calls != 6 {
+		log.Fatalf("Expected 6 pages, got %d", source.calls)
+	}
+	wellFormed(b.String())
+	for _, expected := range []string{`edgedefault="directed"`, `<data key="label">Dwarf &lt;galaxies&gt;</data>`,
+		`<data key="year">2008</data>`, `source="0801.0003" target="hep-th/9901001"`} {
+		if !strings.Contains(b.String(), expected) {
+			log.Fatalf("Missing %s in:\n%s", expected, b.String())
+		}
+	}
+}
+
+func TestBipartiteNetwork(t *testing.T) {
+	var b bytes.Buffer
+	nodes, edges, err := ExportNetwork(newPagedSource(), BipartiteNetwork, &GEXF{}, &b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if nodes != 6 || edges != 6 {
+		log.Fatalf("Wrong bipartite network of %d nodes and %d edges", nodes, edges)
+	}
+	wellFormed(b.String())
+	document := b.String()
+	if strings.Index(document, "</nodes>") > strings.Index(document, "<edge ") {
+		log.Fatalf("Edges written before the end of the nodes:\n%s", document)
+	}
+	if !strings.Contains(document, `label="O&#39;Doe &amp; Sons"`) {
+		log.Fatalf("Author label not escaped:\n%s", document)
+	}
+
+	_, _, err = ExportNetwork(newPagedSource(), "unknown", &GEXF{}, &b)
+	if err == nil {
+		log.Fatal("Unknown networks should fail")
+	}
+}
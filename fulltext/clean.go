@@ -0,0 +1,97 @@
+package fulltext
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ligatures maps the typographic ligatures produced by PDF conversion to
+// their letters
+var ligatures = strings.NewReplacer(
+	"ﬀ", "ff",
+	"ﬁ", "fi",
+	"ﬂ", "fl",
+	"ﬃ", "ffi",
+	"ﬄ", "ffl",
+	"ﬅ", "st",
+	"ﬆ", "st",
+	"­", "", // soft hyphen
+	"\r\n", "\n",
+	"\r", "\n",
+)
+
+// hyphenation matches a word broken at the end of a line
+var hyphenation = regexp.MustCompile(`(\p{Ll})-\n[ \t]*(\p{Ll})`)
+
+// pageNumber matches a line made only of a page number
+var pageNumber = regexp.MustCompile(`^\s*(-\s*)?\d{1,4}(\s*-)?\s*$`)
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// edgeLines is the number of lines at the top and at the bottom of a page
+// where headers and footers are looked for
+const edgeLines = 3
+
+// Clean removes the artifacts of PDF conversion from a text: ligatures,
+// words hyphenated across lines, page numbers and the running headers and
+// footers repeated on most pages. Pages are separated by form feeds.
+func Clean(text string) string {
+	text = ligatures.Replace(text)
+
+	pages := strings.Split(text, "\f")
+	repeated := repeatedLines(pages)
+
+	var b strings.Builder
+	for _, page := range pages {
+		page = strings.TrimRight(page, "\n")
+		for _, line := range strings.Split(page, "\n") {
+			line = strings.TrimRight(line, " \t")
+			if pageNumber.MatchString(line) && line != "" {
+				continue
+			}
+			if repeated[strings.TrimSpace(line)] {
+				continue
+			}
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+	text = hyphenation.ReplaceAllString(b.String(), "$1$2")
+	text = blankLines.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
+
+// repeatedLines returns the running headers and footers: the lines found
+// among the first or last lines of at least half of the pages, when there
+// are at least three pages
+func repeatedLines(pages []string) map[string]bool {
+	repeated := make(map[string]bool)
+	if len(pages) < 3 {
+		return repeated
+	}
+	counts := make(map[string]int)
+	for _, page := range pages {
+		var lines []string
+		for _, line := range strings.Split(page, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) > 2*edgeLines {
+			lines = append(lines[:edgeLines], lines[len(lines)-edgeLines:]...)
+		}
+		seen := make(map[string]bool)
+		for _, line := range lines {
+			if !seen[line] {
+				seen[line] = true
+				counts[line]++
+			}
+		}
+	}
+	for line, count := range counts {
+		if 2*count >= len(pages) {
+			repeated[line] = true
+		}
+	}
+	return repeated
+}
@@ -0,0 +1,54 @@
+package fulltext
+
+import (
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestClean(t *testing.T) {
+	pages := []string{
+		"Journal of Tests 12 (2008)\nThe eﬃcient compu-\ntation of ﬁxed points\n1\n",
+		"Journal of Tests 12 (2008)\nis a well-known problem.\n2\n",
+		"Journal of Tests 12 (2008)\nWe conclude.\n3\n",
+	}
+	got := Clean(strings.Join(pages, "\f"))
+	want := "The efficient computation of fixed points\nis a well-known problem.\nWe conclude."
+	if got != want {
+		log.Fatalf("Wrong text:\n%q\ninstead of\n%q", got, want)
+	}
+}
+
+func TestSplitSections(t *testing.T) {
+	text := `Globular clusters in the outer halo of M31
+A. P. Huxor
+
+Abstract
+We report the discovery of 40 new globular clusters.
+
+1 Introduction
+Globular clusters are old.
+
+2 Observations
+We used INT/WFC imagery.
+
+References
+Huxor A., et al., 2005, MNRAS, 360, 1007`
+
+	sections := SplitSections(text)
+	want := map[string]string{
+		FrontMatter:  "Globular clusters in the outer halo of M31\nA. P. Huxor",
+		Abstract:     "We report the discovery of 40 new globular clusters.",
+		Introduction: "Globular clusters are old.",
+		Body:         "We used INT/WFC imagery.",
+		References:   "Huxor A., et al., 2005, MNRAS, 360, 1007",
+	}
+	if len(sections) != len(want) {
+		log.Fatalf("Wrong sections: %+v", sections)
+	}
+	for _, s := range sections {
+		if s.Text != want[s.Name] {
+			log.Fatalf("Wrong %s: %q instead of %q", s.Name, s.Text, want[s.Name])
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package fulltext
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"pandor/utils"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// ErrNoExtractor is returned when none of the PDF extractors can run
+var ErrNoExtractor = errors.New("No PDF extractor available: install poppler-utils " +
+	"for pdftotext or enable the purego extractor")
+
+// Extractor converts a PDF file to text
+type Extractor interface {
+	// Name identifies the extractor in the configuration
+	Name() string
+	// Available tells whether the dependencies of the extractor are installed
+	Available() bool
+	// Extract returns the text of a PDF file
+	Extract(path string) (string, error)
+}
+
+// Docconv extracts the text with docconv, which runs pdftotext from poppler
+type Docconv struct{}
+
+// Name of the extractor
+func (Docconv) Name() string { return "docconv" }
+
+// Available when pdftotext is in the PATH
+func (Docconv) Available() bool {
+	_, err := exec.LookPath("pdftotext")
+	return err == nil
+}
+
+// Extract the text of a PDF file
+func (Docconv) Extract(path string) (string, error) {
+	res, err := utils.PDFtoTXT(path)
+	if err != nil {
+		return "", err
+	}
+	return res.Body, nil
+}
+
+// PureGo extracts the text with a PDF parser written in Go. It is less
+// accurate than pdftotext but has no dependency. Pages are separated by
+// form feeds.
+type PureGo struct{}
+
+// Name of the extractor
+func (PureGo) Name() string { return "purego" }
+
+// Available always
+func (PureGo) Available() bool { return true }
+
+// Extract the text of a PDF file
+func (PureGo) Extract(path string) (text string, err error) {
+	// The parser panics on some malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Malformed PDF %s: %v", path, r)
+		}
+	}()
+
+	f, r, err := pdf.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var b strings.Builder
+	for i := 1; i <= r.NumPage(); i++ {
+		page := r.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		rows, err := page.GetTextByRow()
+		if err != nil {
+			return "", err
+		}
+		// PDF coordinates grow upwards
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].Position > rows[j].Position })
+		if i > 1 {
+			b.WriteString("\f")
+		}
+		for _, row := range rows {
+			for _, word := range row.Content {
+				b.WriteString(word.S)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String(), nil
+}
+
+// Extractors lists the known extractors by order of preference
+var Extractors = []Extractor{Docconv{}, PureGo{}}
+
+// SelectExtractors returns the available extractors among the ones with the
+// given names, all the known ones when no name is given
+func SelectExtractors(names ...string) ([]Extractor, error) {
+	var selected []Extractor
+	for _, e := range Extractors {
+		wanted := len(names) == 0
+		for _, name := range names {
+			wanted = wanted || name == e.Name()
+		}
+		if wanted && e.Available() {
+			selected = append(selected, e)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, ErrNoExtractor
+	}
+	return selected, nil
+}
+
+// ExtractText runs the extractors in turn until one of them returns some text
+func ExtractText(path string, extractors []Extractor) (string, error) {
+	if len(extractors) == 0 {
+		return "", ErrNoExtractor
+	}
+	var errs []string
+	for _, e := range extractors {
+		text, err := e.Extract(path)
+		if err == nil && strings.TrimSpace(text) != "" {
+			return text, nil
+		}
+		if err == nil {
+			err = errors.New("no text")
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", e.Name(), err))
+	}
+	return "", fmt.Errorf("Text extraction of %s failed: %s", path, strings.Join(errs, ", "))
+}
@@ -0,0 +1,91 @@
+package fulltext
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// minimalPDF builds a PDF with one page per text
+func minimalPDF(pages ...string) []byte {
+	n := len(pages)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+	}
+	var kids []string
+	for i, text := range pages {
+		page := 4 + 2*i
+		kids = append(kids, fmt.Sprintf("%d 0 R", page))
+		content := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] "+
+				"/Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", page+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		)
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), n)
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestPureGo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pandor")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "paper.pdf")
+	err = ioutil.WriteFile(path, minimalPDF("Globular clusters", "References"), 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+	text, err := ExtractText(path, []Extractor{PureGo{}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if text != "Globular clusters\n\fReferences\n" {
+		log.Fatalf("Wrong text: %q", text)
+	}
+
+	err = ioutil.WriteFile(path, []byte("<html>Not a PDF</html>"), 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err = ExtractText(path, []Extractor{PureGo{}}); err == nil {
+		log.Fatal("An HTML page has been converted")
+	}
+}
+
+func TestSelectExtractors(t *testing.T) {
+	extractors, err := SelectExtractors("purego")
+	if err != nil || len(extractors) != 1 || extractors[0].Name() != "purego" {
+		log.Fatalf("Wrong extractors: %v, %v", extractors, err)
+	}
+	if _, err = SelectExtractors("unknown"); err != ErrNoExtractor {
+		log.Fatalf("Wrong error: %v", err)
+	}
+	if _, err = ExtractText("paper.pdf", nil); err != ErrNoExtractor {
+		log.Fatalf("Wrong error: %v", err)
+	}
+}
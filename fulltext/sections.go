@@ -0,0 +1,98 @@
+package fulltext
+
+import (
+	"regexp"
+	"strings"
+
+	"pandor/models"
+)
+
+// Names of the sections found in a paper
+const (
+	FrontMatter  = "front"
+	Abstract     = "abstract"
+	Introduction = "introduction"
+	Body         = "body"
+	References   = "references"
+	Appendix     = "appendix"
+)
+
+// heading matches a line holding only a section title, optionally numbered
+// as "1", "1.", "I." or "A" ("A Proofs" in appendices)
+var heading = regexp.MustCompile(`^(?:(?:\d+|[IVX]+|[A-H])\.?\s+)?([A-Za-z][A-Za-z ]{2,40})\.?$`)
+
+// headingNames maps the normalized titles of the sections to their names
+var headingNames = map[string]string{
+	"abstract":         Abstract,
+	"introduction":     Introduction,
+	"references":       References,
+	"bibliography":     References,
+	"literature cited": References,
+	"appendix":         Appendix,
+	"appendices":       Appendix,
+}
+
+// sectionName returns the name of the section started by a line, if any
+func sectionName(line string) (string, bool) {
+	match := heading.FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil {
+		return "", false
+	}
+	name, ok := headingNames[strings.ToLower(strings.Join(strings.Fields(match[1]), " "))]
+	return name, ok
+}
+
+// SplitSections splits a cleaned text into its abstract, introduction,
+// body and references. The text preceding the first heading is the front
+// matter (title, authors, affiliations). Sections which are not found are
+// omitted, and the sections following the introduction are merged in the
+// body, up to the references.
+func SplitSections(text string) []models.Section {
+	var sections []models.Section
+	current := models.Section{Name: FrontMatter}
+	var b strings.Builder
+
+	flush := func() {
+		current.Text = strings.TrimSpace(b.String())
+		b.Reset()
+		if current.Text == "" {
+			return
+		}
+		// A section met twice, such as a second "References" list, is merged
+		for i := range sections {
+			if sections[i].Name == current.Name {
+				sections[i].Text += "\n\n" + current.Text
+				return
+			}
+		}
+		sections = append(sections, current)
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		name, ok := sectionName(line)
+		if !ok && current.Name == Introduction && isNumberedHeading(line) {
+			name, ok = Body, true
+		}
+		if ok && name != current.Name {
+			flush()
+			current = models.Section{Name: name}
+			continue
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	flush()
+
+	for i := range sections {
+		sections[i].DType = []string{"Section"}
+	}
+	return sections
+}
+
+// numberedHeading matches the headings of the numbered sections, such as
+// "2 Related Work" or "III. METHOD"
+var numberedHeading = regexp.MustCompile(`^(?:\d+|[IVX]+)\.?\s+[A-Z][A-Za-z ]{2,40}$`)
+
+func isNumberedHeading(line string) bool {
+	return numberedHeading.MatchString(strings.TrimSpace(line))
+}
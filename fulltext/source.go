@@ -0,0 +1,113 @@
+package fulltext
+
+import (
+	"strings"
+
+	"pandor/latex"
+	"pandor/models"
+)
+
+// SourceKey returns the storage key of the e-print of an article
+func SourceKey(arXivID string) string {
+	return "src/" + arXivID
+}
+
+// SourceURL returns the URL of the e-print of an article, which is the
+// "Source" link of its "Other formats" page
+func SourceURL(article models.Article) string {
+	return strings.Replace(article.OtherFormatURL, "/format/", "/e-print/", 1)
+}
+
+// SectionsFromLaTeX names the sections of a LaTeX document the same way
+// as the ones found in a PDF, and returns them with the text of the
+// document
+func SectionsFromLaTeX(doc latex.Document) ([]models.Section, string) {
+	var sections []models.Section
+	var text strings.Builder
+
+	add := func(name, title, body string) {
+		if body == "" {
+			return
+		}
+		text.WriteString(title + "\n" + body + "\n\n")
+		if title != "" && name == Body {
+			body = title + "\n" + body
+		}
+		for i := range sections {
+			if sections[i].Name == name {
+				sections[i].Text += "\n\n" + body
+				return
+			}
+		}
+		sections = append(sections, models.Section{
+			Name:  name,
+			Text:  body,
+			DType: []string{"Section"},
+		})
+	}
+
+	add(Abstract, "Abstract", doc.Abstract)
+	for _, s := range doc.Sections {
+		name, ok := sectionName(s.Title)
+		if !ok || name == Abstract {
+			name = Body
+		}
+		add(name, s.Title, s.Text)
+	}
+	refs := make([]string, len(doc.BibItems))
+	for i, item := range doc.BibItems {
+		refs[i] = item.Text
+	}
+	add(References, "References", strings.Join(refs, "\n"))
+
+	return sections, strings.TrimSpace(text.String())
+}
+
+// ProcessSource fetches the e-print of an article and, when it holds the
+// LaTeX source, sets the full text, the sections and the cited papers of
+// the article from it. latex.ErrNoSource is returned when the e-print is
+// only a PDF.
+func (s *Stage) ProcessSource(article *models.Article) error {
+	if s.Sources == nil || article.OtherFormatURL == "" {
+		return latex.ErrNoSource
+	}
+	key := SourceKey(article.ArXivID)
+	_, err := s.Sources.Download(SourceURL(*article), key)
+	if err != nil {
+		return err
+	}
+	rc, err := s.Storage.Get(key)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	files, err := latex.ExtractSource(rc)
+	if err != nil {
+		return err
+	}
+	doc, err := latex.Parse(files)
+	if err != nil {
+		return err
+	}
+
+	sections, text := SectionsFromLaTeX(doc)
+	err = s.Storage.Put(TextKey(article.ArXivID), strings.NewReader(text))
+	if err != nil {
+		return err
+	}
+	article.SourceKey = key
+	article.FullTextKey = TextKey(article.ArXivID)
+	article.Sections = sections
+	article.CitedPapers = nil
+	for _, id := range doc.CitedArXivIDs() {
+		if id == article.ArXivID {
+			continue
+		}
+		article.CitedPapers = append(article.CitedPapers, models.Article{
+			ArXivID: id,
+			DType:   []string{"Article"},
+		})
+	}
+	return nil
+}
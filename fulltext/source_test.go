@@ -0,0 +1,44 @@
+package fulltext
+
+import (
+	"log"
+	"testing"
+
+	"pandor/latex"
+	"pandor/models"
+)
+
+func TestSectionsFromLaTeX(t *testing.T) {
+	doc := latex.Document{
+		Abstract: "We learn to rank.",
+		Sections: []latex.Section{
+			{Title: "Introduction", Text: "Ranking matters."},
+			{Title: "Method", Text: "We use a graph."},
+			{Title: "Results", Text: "It works."},
+		},
+		BibItems: []latex.BibItem{{Key: "smith99", Text: "J. Smith, 1999"}},
+	}
+	sections, text := SectionsFromLaTeX(doc)
+	want := []models.Section{
+		{Name: Abstract, Text: "We learn to rank."},
+		{Name: Introduction, Text: "Ranking matters."},
+		{Name: Body, Text: "Method\nWe use a graph.\n\nResults\nIt works."},
+		{Name: References, Text: "J. Smith, 1999"},
+	}
+	if len(sections) != len(want) {
+		log.Fatalf("Wrong sections: %+v", sections)
+	}
+	for i := range want {
+		if sections[i].Name != want[i].Name || sections[i].Text != want[i].Text {
+			log.Fatalf("Wrong section: %+v instead of %+v", sections[i], want[i])
+		}
+	}
+	if text == "" {
+		log.Fatal("No text")
+	}
+
+	article := models.Article{OtherFormatURL: "https://export.arxiv.org/format/0801.0002"}
+	if url := SourceURL(article); url != "https://export.arxiv.org/e-print/0801.0002" {
+		log.Fatalf("Wrong source URL: %s", url)
+	}
+}
@@ -0,0 +1,198 @@
+package fulltext
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"pandor/databases"
+	"pandor/downloads"
+	"pandor/latex"
+	"pandor/logger"
+	"pandor/models"
+	"pandor/storages"
+	"pandor/utils"
+)
+
+// PageSize is the number of articles loaded at once by Run
+var PageSize = 100
+
+// TextKey returns the storage key of the full text of an article
+func TextKey(arXivID string) string {
+	return "text/" + arXivID + ".txt"
+}
+
+// Stage extracts the full text of the articles and stores it in Storage.
+// The LaTeX source fetched by Sources is preferred, as it gives a cleaner
+// text and the citations, the PDF downloaded to Storage is the fallback.
+type Stage struct {
+	Storage    storages.Storage
+	Sources    *downloads.Manager // sources are not fetched when nil
+	Extractors []Extractor        // tried in turn on the PDFs
+	TempDir    string
+}
+
+// NewStage builds a Stage reading and writing files in storage, with every
+// available extractor
+func NewStage(storage storages.Storage, tempDir string) *Stage {
+	extractors, _ := SelectExtractors()
+	return &Stage{Storage: storage, Extractors: extractors, TempDir: tempDir}
+}
+
+// Extract converts the PDF of an article to text
+func (s *Stage) Extract(article models.Article) (string, error) {
+	if article.PDFKey == "" {
+		return "", fmt.Errorf("No PDF downloaded for %s", article.ArXivID)
+	}
+	if len(s.Extractors) == 0 {
+		return "", ErrNoExtractor
+	}
+	rc, err := s.Storage.Get(article.PDFKey)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	// The converter works on files, the storage may be remote
+	err = utils.BuildDir(s.TempDir)
+	if err != nil {
+		return "", err
+	}
+	tmp, err := ioutil.TempFile(s.TempDir, "fulltext-*.pdf")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, rc)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return ExtractText(tmp.Name(), s.Extractors)
+}
+
+// Process extracts the full text of an article, stores it and sets the
+// FullTextKey and the Sections of the article
+func (s *Stage) Process(article *models.Article) error {
+	err := s.ProcessSource(article)
+	if err == nil {
+		return nil
+	}
+	if err != latex.ErrNoSource {
+		logger.Logger.Warn(fmt.Sprintf("LaTeX source of %s unusable, falling back on the PDF: %v", article.ArXivID, err))
+	}
+	return s.ProcessPDF(article)
+}
+
+// ProcessPDF extracts the full text of an article from its PDF
+func (s *Stage) ProcessPDF(article *models.Article) error {
+	text, err := s.Extract(*article)
+	if err != nil {
+		return err
+	}
+	text = Clean(text)
+
+	key := TextKey(article.ArXivID)
+	err = s.Storage.Put(key, strings.NewReader(text))
+	if err != nil {
+		return err
+	}
+	article.FullTextKey = key
+	article.Sections = SplitSections(text)
+	return nil
+}
+
+// Save stores the full text of a processed article in g, replacing its
+// previous sections, and its citations when they come from the source
+func Save(article models.Article, g databases.Graph) error {
+	predicates := []string{"sections"}
+	if article.SourceKey != "" {
+		predicates = append(predicates, "citedpapers")
+	}
+	err := g.DeleteEdges(article.UID, predicates)
+	if err != nil {
+		return err
+	}
+
+	// Cited papers which are not crawled yet are created with their ID only
+	for i, cited := range article.CitedPapers {
+		uid, err := g.ArticleUIDByArXivID(cited.ArXivID)
+		if err != nil {
+			uid = models.FormatUID(cited.ArXivID)
+		}
+		article.CitedPapers[i].UID = uid
+	}
+
+	return g.UpdateArticle(models.Article{
+		UID:         article.UID,
+		FullTextKey: article.FullTextKey,
+		SourceKey:   article.SourceKey,
+		Sections:    article.Sections,
+		CitedPapers: article.CitedPapers,
+	})
+}
+
+// Run processes every article with a PDF or a source and no full text yet
+func Run(stage *Stage) {
+	conn, dg, err := databases.NewClient()
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	defer conn.Close()
+	g := databases.NewDgraphStore(dg)
+
+	type Root struct {
+		Articles []models.Article `json:"articles"`
+	}
+
+	query := `query FullText($first: int, $after: string){
+		articles(func: type(Article), first: $first, after: $after)
+			@filter((has(pdfkey) OR has(otherformaturl)) AND NOT has(fulltextkey)){
+			uid
+			arxivid
+			pdfkey
+			otherformaturl
+		}
+	}`
+	after := "0x0"
+	count, failed := 0, 0
+	for {
+		variables := map[string]string{
+			"$first": fmt.Sprintf("%d", PageSize),
+			"$after": after,
+		}
+		resp, err := databases.QueryWithVars(query, variables, dg)
+		if err != nil {
+			logger.Logger.Fatal(err.Error())
+		}
+		var root Root
+		err = json.Unmarshal(resp.Json, &root)
+		if err != nil {
+			logger.Logger.Fatal(err.Error())
+		}
+		if len(root.Articles) == 0 {
+			break
+		}
+
+		for _, article := range root.Articles {
+			err = stage.Process(&article)
+			if err == nil {
+				err = Save(article, g)
+			}
+			if err != nil {
+				failed++
+				logger.Logger.Error(fmt.Sprintf("Full text of %s failed: %v", article.ArXivID, err))
+				continue
+			}
+			count++
+		}
+		after = root.Articles[len(root.Articles)-1].UID
+	}
+	logger.Logger.Info(fmt.Sprintf("Extracted the full text of %d articles, %d failures", count, failed))
+}
@@ -0,0 +1,196 @@
+package imports
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"pandor/logger"
+	"pandor/models"
+)
+
+// BatchSize is the number of articles loaded per transaction
+var BatchSize = 1000
+
+// ProgressInterval is the time between two progress reports
+var ProgressInterval = 10 * time.Second
+
+// Checkpoint records the progress of an import, so that an interrupted
+// import resumes after the last loaded batch. It is removed once the import
+// completes.
+type Checkpoint struct {
+	Input     string    `json:"input"`
+	Size      int64     `json:"size"`
+	Target    string    `json:"target"` // the sink loaded, such as the N-Quads file
+	Lines     int       `json:"lines"`
+	Articles  int       `json:"articles"`
+	Invalid   int       `json:"invalid"`
+	UpdatedAt time.Time `json:"updatedat"`
+}
+
+// Resumes tells whether the checkpoint is the one of an import of path,
+// which still has size bytes, into target
+func (c Checkpoint) Resumes(path string, size int64, target string) bool {
+	return c.Input == path && c.Size == size && c.Target == target
+}
+
+// LoadCheckpoint reads a checkpoint, the zero one when it does not exist
+func LoadCheckpoint(path string) (Checkpoint, error) {
+	var c Checkpoint
+	body, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	return c, json.Unmarshal(body, &c)
+}
+
+// SaveCheckpoint writes a checkpoint atomically
+func SaveCheckpoint(path string, c Checkpoint) error {
+	body, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, body, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// countingReader counts the bytes read, for the progress reports
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Import loads the dump at path into sink, described by target, and returns
+// the final checkpoint. Gzipped dumps end with .gz. When checkpointPath is
+// set, the lines already loaded into the same target by an interrupted
+// import of the same file are skipped, and the checkpoint is saved after
+// every batch.
+func Import(path, format string, sink Sink, target, checkpointPath string) (Checkpoint, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return Checkpoint{}, err
+	}
+
+	counter := &countingReader{r: f}
+	var input io.Reader = counter
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(counter)
+		if err != nil {
+			return Checkpoint{}, err
+		}
+		defer zr.Close()
+		input = zr
+	}
+	reader, err := NewReader(input, format)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+
+	checkpoint := Checkpoint{Input: path, Size: info.Size(), Target: target}
+	if checkpointPath != "" {
+		previous, err := LoadCheckpoint(checkpointPath)
+		if err != nil {
+			return checkpoint, err
+		}
+		switch {
+		case previous.Resumes(path, info.Size(), target):
+			checkpoint = previous
+			err = reader.Skip(checkpoint.Lines)
+			if err != nil {
+				return checkpoint, err
+			}
+			logger.Logger.Info(fmt.Sprintf("Resuming the import of %s after line %d", path, checkpoint.Lines))
+		case previous.Input != "":
+			logger.Logger.Warn(fmt.Sprintf("Ignoring the checkpoint of %s into %s, a different import",
+				previous.Input, previous.Target))
+		}
+	}
+
+	start, lastReport := time.Now(), time.Now()
+	imported := 0
+	report := func() {
+		rate := float64(imported) / time.Since(start).Seconds()
+		logger.Logger.Info(fmt.Sprintf("Imported %d articles, %.1f%% of %s, %.0f articles/s",
+			checkpoint.Articles, 100*float64(counter.n)/float64(info.Size()), path, rate))
+	}
+
+	batch := make([]models.Article, 0, BatchSize)
+	flush := func() error {
+		if len(batch) > 0 {
+			if err := sink.Load(batch); err != nil {
+				return err
+			}
+		}
+		imported += len(batch)
+		checkpoint.Articles += len(batch)
+		checkpoint.Lines = reader.Line()
+		checkpoint.UpdatedAt = time.Now().UTC()
+		batch = batch[:0]
+		if checkpointPath != "" {
+			if err := SaveCheckpoint(checkpointPath, checkpoint); err != nil {
+				return err
+			}
+		}
+		if time.Since(lastReport) >= ProgressInterval {
+			lastReport = time.Now()
+			report()
+		}
+		return nil
+	}
+
+	for {
+		article, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if _, ok := err.(*RecordError); !ok {
+				return checkpoint, err
+			}
+			checkpoint.Invalid++
+			logger.Logger.Warn(err.Error())
+			continue
+		}
+		batch = append(batch, article)
+		if len(batch) >= BatchSize {
+			if err = flush(); err != nil {
+				return checkpoint, err
+			}
+		}
+	}
+	if err = flush(); err != nil {
+		return checkpoint, err
+	}
+	report()
+	// A completed import is not resumed, the same dump can seed another
+	// database
+	if checkpointPath != "" {
+		if err = os.Remove(checkpointPath); err != nil {
+			return checkpoint, err
+		}
+	}
+	return checkpoint, nil
+}
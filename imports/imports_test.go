@@ -0,0 +1,162 @@
+package imports
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pandor/logger"
+	"pandor/models"
+)
+
+const kaggleRecord = `{"id":"0704.0001","submitter":"Pavel Nadolsky","authors":"C. Bal\\'azs, E. L. Berger",` +
+	`"title":"Calculation of prompt diphoton production cross sections at Tevatron and\n  LHC energies",` +
+	`"comments":"37 pages","journal-ref":"Phys.Rev.D76:013009,2007","doi":"10.1103/PhysRevD.76.013009",` +
+	`"categories":"hep-ph hep-ex","license":null,"abstract":"  A fully differential calculation.\n",` +
+	`"versions":[{"version":"v1","created":"Mon, 2 Apr 2007 19:18:42 GMT"},{"version":"v2","created":"Tue, 24 Jul 2007 20:10:27 GMT"}],` +
+	`"update_date":"2008-11-13","authors_parsed":[["Balázs","C.",""],["Berger","E. L.",""]]}`
+
+func TestKaggle(t *testing.T) {
+	reader, err := NewReader(strings.NewReader(kaggleRecord+"\n\n"), Kaggle)
+	if err != nil {
+		log.Fatal(err)
+	}
+	article, err := reader.Next()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if article.ArXivID != "0704.0001" ||
+		article.Title != "Calculation of prompt diphoton production cross sections at Tevatron and LHC energies" ||
+		article.Abstract != "A fully differential calculation." ||
+		article.SubmissionDate.Format("2006-01-02 15:04") != "2007-04-02 19:18" ||
+		article.PDFURL != "https://arxiv.org/pdf/0704.0001" {
+		log.Fatalf("Wrong article %+v", article)
+	}
+	if len(article.Authors) != 2 || article.Authors[0].Name != "Balázs_C" || article.Authors[1].Name != "Berger_E" {
+		log.Fatalf("Wrong authors %v", article.Authors)
+	}
+	if len(article.Categories) != 2 || article.Categories[1].Code != "hep-ex" {
+		log.Fatalf("Wrong categories %v", article.Categories)
+	}
+	if _, err = reader.Next(); err != io.EOF {
+		log.Fatalf("Expected EOF, got %v", err)
+	}
+}
+
+func TestNQuads(t *testing.T) {
+	var b bytes.Buffer
+	sink := &NQuads{W: &b}
+	err := sink.Load([]models.Article{{
+		ArXivID:     "0801.0002",
+		Title:       `Dwarf "galaxies"`,
+		Authors:     []models.Author{{Name: "Lewis_G"}},
+		CitedPapers: []models.Article{{ArXivID: "0801.0003"}},
+	}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	article := "_:" + BlankNode(articleKind, "0801.0002")
+	for _, expected := range []string{
+		article + ` <title> "Dwarf \"galaxies\"" .`,
+		article + " <authors> _:" + BlankNode(authorKind, "Lewis_G") + " .",
+		article + " <citedpapers> _:" + BlankNode(articleKind, "0801.0003") + " .",
+		"_:" + BlankNode(articleKind, "0801.0003") + ` <arxivid> "0801.0003" .`,
+	} {
+		if !strings.Contains(b.String(), expected+"\n") {
+			log.Fatalf("Missing %s in:\n%s", expected, b.String())
+		}
+	}
+	if strings.Contains(b.String(), "submissiondate") {
+		log.Fatalf("Zero dates should be omitted:\n%s", b.String())
+	}
+}
+
+// failingSink fails once on its second batch
+type failingSink struct {
+	loaded  []string
+	batches int
+	failed  bool
+}
+
+func (s *failingSink) Load(articles []models.Article) error {
+	s.batches++
+	if s.batches == 2 && !s.failed {
+		s.failed = true
+		return errors.New("connection refused")
+	}
+	for _, a := range articles {
+		s.loaded = append(s.loaded, a.ArXivID)
+	}
+	return nil
+}
+
+func TestImport(t *testing.T) {
+	logger.Logger = logger.InitLogger()
+	dir, err := ioutil.TempDir("", "imports")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A gzipped dump of five articles and an invalid line
+	path := filepath.Join(dir, "dump.jsonl.gz")
+	var dump bytes.Buffer
+	zw := gzip.NewWriter(&dump)
+	for _, line := range []string{`{"arxivid":"1"}`, `{"arxivid":"2"}`, `{"title":"no id"}`,
+		`{"arxivid":"3"}`, `{"arxivid":"4"}`, `{"arxivid":"5"}`} {
+		zw.Write([]byte(line + "\n"))
+	}
+	zw.Close()
+	err = ioutil.WriteFile(path, dump.Bytes(), 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	BatchSize = 2
+	checkpointPath := filepath.Join(dir, "checkpoint.json")
+	sink := &failingSink{}
+	_, err = Import(path, JSONLines, sink, "test", checkpointPath)
+	if err == nil {
+		log.Fatal("The failure of the sink should stop the import")
+	}
+	checkpoint, err := LoadCheckpoint(checkpointPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if checkpoint.Lines != 2 || checkpoint.Articles != 2 {
+		log.Fatalf("Wrong checkpoint %+v", checkpoint)
+	}
+	// A dump of another size is another input, another sink another import
+	size := int64(dump.Len())
+	if !checkpoint.Resumes(path, size, "test") || checkpoint.Resumes(path, size+1, "test") ||
+		checkpoint.Resumes(path, size, "nquads dump.nq") {
+		log.Fatalf("Wrong resumed input %+v", checkpoint)
+	}
+
+	checkpoint, err = Import(path, JSONLines, sink, "test", checkpointPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if strings.Join(sink.loaded, ",") != "1,2,3,4,5" {
+		log.Fatalf("Wrong loaded articles %v", sink.loaded)
+	}
+	if checkpoint.Lines != 6 || checkpoint.Articles != 5 || checkpoint.Invalid != 1 {
+		log.Fatalf("Wrong final checkpoint %+v", checkpoint)
+	}
+	// The completed import loads everything again
+	if _, err = os.Stat(checkpointPath); !os.IsNotExist(err) {
+		log.Fatalf("The checkpoint should be removed, got %v", err)
+	}
+	sink.loaded = nil
+	_, err = Import(path, JSONLines, sink, "test", checkpointPath)
+	if err != nil || strings.Join(sink.loaded, ",") != "1,2,3,4,5" {
+		log.Fatalf("Wrong imported again articles %v %v", sink.loaded, err)
+	}
+}
@@ -0,0 +1,183 @@
+package imports
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"pandor/models"
+)
+
+// Input formats
+const (
+	JSONLines = "jsonl"  // models.Article, as written by pandor export -format jsonl
+	Kaggle    = "kaggle" // the arXiv metadata snapshot published on Kaggle
+)
+
+// FormatNames lists the formats accepted by NewReader
+var FormatNames = []string{JSONLines, Kaggle}
+
+// MaxLineSize bounds the size of a record
+var MaxLineSize = 16 << 20
+
+// KaggleDate is the layout of the dates of the versions, whose days have
+// no leading zero
+const KaggleDate = "Mon, 2 Jan 2006 15:04:05 MST"
+
+// URLs given to the imported articles
+var (
+	AbsURL = "https://arxiv.org/abs/"
+	PDFURL = "https://arxiv.org/pdf/"
+)
+
+// Reader reads the articles of a dump, one JSON record per line
+type Reader struct {
+	format  string
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewReader returns a Reader of a dump in a format
+func NewReader(r io.Reader, format string) (*Reader, error) {
+	switch format {
+	case JSONLines, Kaggle:
+	default:
+		return nil, fmt.Errorf("Unknown import format %q, expected one of %s", format, strings.Join(FormatNames, ", "))
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), MaxLineSize)
+	return &Reader{format: format, scanner: scanner}, nil
+}
+
+// RecordError is returned for an invalid record, the next ones can still
+// be read
+type RecordError struct {
+	Line int
+	Err  error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("Line %d: %v", e.Line, e.Err)
+}
+
+// Skip reads n lines without parsing them
+func (r *Reader) Skip(n int) error {
+	for r.line < n && r.scanner.Scan() {
+		r.line++
+	}
+	if err := r.scanner.Err(); err != nil {
+		return err
+	}
+	if r.line < n {
+		return fmt.Errorf("Only %d lines to skip instead of %d", r.line, n)
+	}
+	return nil
+}
+
+// Line returns the number of lines read
+func (r *Reader) Line() int {
+	return r.line
+}
+
+// Next returns the next article, io.EOF at the end of the dump and a
+// RecordError for an invalid record
+func (r *Reader) Next() (models.Article, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := r.scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		var article models.Article
+		var err error
+		switch r.format {
+		case JSONLines:
+			err = json.Unmarshal(line, &article)
+		case Kaggle:
+			var record KaggleRecord
+			err = json.Unmarshal(line, &record)
+			if err == nil {
+				article, err = record.Article()
+			}
+		}
+		if err != nil {
+			return article, &RecordError{Line: r.line, Err: err}
+		}
+		if article.ArXivID == "" {
+			return article, &RecordError{Line: r.line, Err: errors.New("no arXiv ID")}
+		}
+		return article, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return models.Article{}, err
+	}
+	return models.Article{}, io.EOF
+}
+
+// KaggleRecord is a record of the Kaggle arXiv metadata snapshot
+type KaggleRecord struct {
+	ID         string `json:"id"`
+	Title      string `json:"title"`
+	Abstract   string `json:"abstract"`
+	Categories string `json:"categories"`
+	Versions   []struct {
+		Version string `json:"version"`
+		Created string `json:"created"`
+	} `json:"versions"`
+	AuthorsParsed [][]string `json:"authors_parsed"`
+}
+
+// KaggleAuthorName returns the name of an author as written in the arXiv
+// search links, Family_I, so that the imported authors match the crawled
+// ones
+func KaggleAuthorName(family, given string) string {
+	name := strings.Join(strings.Fields(family), " ")
+	if initial := []rune(strings.TrimSpace(given)); len(initial) > 0 {
+		name += "_" + string(initial[0])
+	}
+	return name
+}
+
+// Article converts a record to an article
+func (k KaggleRecord) Article() (models.Article, error) {
+	article := models.Article{
+		ArXivID:  strings.TrimSpace(k.ID),
+		Title:    strings.Join(strings.Fields(k.Title), " "),
+		Abstract: strings.TrimSpace(k.Abstract),
+		DType:    []string{"Article"},
+	}
+	if article.ArXivID == "" {
+		return article, nil
+	}
+	article.MetaURL = AbsURL + article.ArXivID
+	article.PDFURL = PDFURL + article.ArXivID
+
+	// The first version gives the submission date
+	if len(k.Versions) > 0 {
+		date, err := time.Parse(KaggleDate, k.Versions[0].Created)
+		if err != nil {
+			return article, fmt.Errorf("Invalid date of %s: %v", article.ArXivID, err)
+		}
+		article.SubmissionDate = date.UTC()
+	}
+	for _, parsed := range k.AuthorsParsed {
+		if len(parsed) < 2 || strings.TrimSpace(parsed[0]) == "" {
+			continue
+		}
+		article.Authors = append(article.Authors, models.Author{
+			Name:  KaggleAuthorName(parsed[0], parsed[1]),
+			DType: []string{"Author"},
+		})
+	}
+	for _, code := range strings.Fields(k.Categories) {
+		article.Categories = append(article.Categories, models.Category{
+			Code:  code,
+			DType: []string{"Category"},
+		})
+	}
+	return article, nil
+}
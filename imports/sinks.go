@@ -0,0 +1,260 @@
+package imports
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"pandor/models"
+
+	"github.com/dgraph-io/dgo/v2"
+	"github.com/dgraph-io/dgo/v2/protos/api"
+)
+
+// Sink receives the imported articles by batches
+type Sink interface {
+	Load(articles []models.Article) error
+}
+
+// Kinds of nodes, prefixes of their blank nodes
+const (
+	articleKind  = "article"
+	authorKind   = "author"
+	categoryKind = "category"
+)
+
+// BlankNode returns the label of the blank node of a node identified by a
+// key, an arXiv ID, an author name or a category code. Labels are the same
+// in every batch and every file, so that the Dgraph live loader merges the
+// nodes of N-Quads files with its xidmap.
+func BlankNode(kind, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return kind + "_" + hex.EncodeToString(sum[:8])
+}
+
+// NQuads writes the articles as RDF N-Quads, the input of the Dgraph live
+// and bulk loaders
+type NQuads struct {
+	W io.Writer
+}
+
+var literalEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// Load writes a batch of articles
+func (n *NQuads) Load(articles []models.Article) error {
+	var b strings.Builder
+	quad := func(subject, predicate, object string) {
+		fmt.Fprintf(&b, "_:%s <%s> %s .\n", subject, predicate, object)
+	}
+	literal := func(subject, predicate, value string) {
+		if value != "" {
+			quad(subject, predicate, `"`+literalEscaper.Replace(value)+`"`)
+		}
+	}
+	date := func(subject, predicate string, value time.Time) {
+		if !value.IsZero() {
+			quad(subject, predicate, `"`+value.Format(time.RFC3339)+`"^^<xs:dateTime>`)
+		}
+	}
+	for _, article := range articles {
+		a := BlankNode(articleKind, article.ArXivID)
+		literal(a, "dgraph.type", "Article")
+		literal(a, "arxivid", article.ArXivID)
+		literal(a, "title", article.Title)
+		literal(a, "abstract", article.Abstract)
+		date(a, "submissiondate", article.SubmissionDate)
+		literal(a, "pdfurl", article.PDFURL)
+		literal(a, "otherformaturl", article.OtherFormatURL)
+		literal(a, "metaurl", article.MetaURL)
+		for _, author := range article.Authors {
+			u := BlankNode(authorKind, author.Name)
+			literal(u, "dgraph.type", "Author")
+			literal(u, "name", author.Name)
+			literal(u, "url", author.URL)
+			quad(a, "authors", "_:"+u)
+		}
+		for _, category := range article.Categories {
+			c := BlankNode(categoryKind, category.Code)
+			literal(c, "dgraph.type", "Category")
+			literal(c, "categorycode", category.Code)
+			literal(c, "categoryname", category.Name)
+			quad(a, "categories", "_:"+c)
+		}
+		for _, cited := range article.CitedPapers {
+			if cited.ArXivID == "" {
+				continue
+			}
+			c := BlankNode(articleKind, cited.ArXivID)
+			literal(c, "dgraph.type", "Article")
+			literal(c, "arxivid", cited.ArXivID)
+			quad(a, "citedpapers", "_:"+c)
+		}
+	}
+	_, err := io.WriteString(n.W, b.String())
+	return err
+}
+
+// Loader loads the articles into Dgraph with JSON mutations. The existing
+// articles, authors and categories are looked up once per batch and their
+// UIDs are kept, so that the imports do not duplicate nodes.
+type Loader struct {
+	DG      *dgo.Dgraph
+	uids    map[string]string // by kind and key
+	pending map[string]string // kind and key of the blank nodes of the batch
+}
+
+// NewLoader builds a Loader writing to dg
+func NewLoader(dg *dgo.Dgraph) *Loader {
+	return &Loader{DG: dg, uids: make(map[string]string)}
+}
+
+// keys lists the nodes of a batch by kind
+func keys(articles []models.Article) map[string][]string {
+	seen := make(map[string]bool)
+	keys := make(map[string][]string)
+	add := func(kind, key string) {
+		if key != "" && !seen[kind+":"+key] {
+			seen[kind+":"+key] = true
+			keys[kind] = append(keys[kind], key)
+		}
+	}
+	for _, article := range articles {
+		add(articleKind, article.ArXivID)
+		for _, author := range article.Authors {
+			add(authorKind, author.Name)
+		}
+		for _, category := range article.Categories {
+			add(categoryKind, category.Code)
+		}
+		for _, cited := range article.CitedPapers {
+			add(articleKind, cited.ArXivID)
+		}
+	}
+	return keys
+}
+
+// lookupPredicates are the predicates identifying the nodes of each kind
+var lookupPredicates = map[string]string{
+	articleKind:  "arxivid",
+	authorKind:   "name",
+	categoryKind: "categorycode",
+}
+
+// lookup fetches the UIDs of the nodes of the batch which are not cached
+func (l *Loader) lookup(articles []models.Article) error {
+	var blocks []string
+	for kind, list := range keys(articles) {
+		var missing []string
+		for _, key := range list {
+			if _, ok := l.uids[kind+":"+key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		values, err := json.Marshal(missing)
+		if err != nil {
+			return err
+		}
+		predicate := lookupPredicates[kind]
+		blocks = append(blocks, fmt.Sprintf("%s(func: eq(%s, %s)) { uid key: %s }", kind, predicate, values, predicate))
+	}
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	resp, err := l.DG.NewReadOnlyTxn().Query(context.Background(), "{\n"+strings.Join(blocks, "\n")+"\n}")
+	if err != nil {
+		return err
+	}
+	var found map[string][]struct {
+		UID string `json:"uid"`
+		Key string `json:"key"`
+	}
+	err = json.Unmarshal(resp.Json, &found)
+	if err != nil {
+		return err
+	}
+	for kind, nodes := range found {
+		for _, node := range nodes {
+			l.uids[kind+":"+node.Key] = node.UID
+		}
+	}
+	return nil
+}
+
+// uid returns the UID of a node, or its blank node when it is new
+func (l *Loader) uid(kind, key string) string {
+	if uid, ok := l.uids[kind+":"+key]; ok {
+		return uid
+	}
+	blank := BlankNode(kind, key)
+	l.pending[blank] = kind + ":" + key
+	return "_:" + blank
+}
+
+// Load writes a batch of articles in a single transaction
+func (l *Loader) Load(articles []models.Article) error {
+	err := l.lookup(articles)
+	if err != nil {
+		return err
+	}
+	l.pending = make(map[string]string)
+
+	batch := make([]models.Article, 0, len(articles))
+	for _, article := range articles {
+		// The UIDs of the dump belong to another database
+		article.UID = l.uid(articleKind, article.ArXivID)
+		article.DType = []string{"Article"}
+		authors := make([]models.Author, len(article.Authors))
+		for i, author := range article.Authors {
+			author.UID = l.uid(authorKind, author.Name)
+			author.DType = []string{"Author"}
+			authors[i] = author
+		}
+		article.Authors = authors
+		categories := make([]models.Category, len(article.Categories))
+		for i, category := range article.Categories {
+			category.UID = l.uid(categoryKind, category.Code)
+			category.DType = []string{"Category"}
+			categories[i] = category
+		}
+		article.Categories = categories
+		var cited []models.Article
+		for _, c := range article.CitedPapers {
+			if c.ArXivID == "" {
+				continue
+			}
+			cited = append(cited, models.Article{
+				UID:     l.uid(articleKind, c.ArXivID),
+				ArXivID: c.ArXivID,
+				DType:   []string{"Article"},
+			})
+		}
+		article.CitedPapers = cited
+		article.Sections = nil
+		batch = append(batch, article)
+	}
+
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	resp, err := l.DG.NewTxn().Mutate(context.Background(), &api.Mutation{SetJson: body, CommitNow: true})
+	if err != nil {
+		return err
+	}
+	// The new nodes are known for the next batches
+	for blank, uid := range resp.Uids {
+		if key, ok := l.pending[blank]; ok {
+			l.uids[key] = uid
+		}
+	}
+	return nil
+}
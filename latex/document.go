@@ -0,0 +1,243 @@
+package latex
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Section is a \section of a LaTeX document, its subsections included
+type Section struct {
+	Title string
+	Text  string
+}
+
+// BibItem is an entry of the bibliography of a document
+type BibItem struct {
+	Key      string
+	Text     string
+	ArXivIDs []string
+}
+
+// Document is the structure of a LaTeX paper
+type Document struct {
+	Abstract string
+	Sections []Section
+	BibItems []BibItem
+}
+
+// maxInputDepth bounds the nesting of \input commands
+const maxInputDepth = 8
+
+var (
+	comment        = regexp.MustCompile(`(?m)(^|[^\\])%.*$`)
+	input          = regexp.MustCompile(`\\(?:input|include)\s*\{([^}]+)\}`)
+	abstractEnv    = regexp.MustCompile(`(?s)\\begin\{abstract\}(.*?)\\end\{abstract\}`)
+	sectionCommand = regexp.MustCompile(`\\section\*?\s*(?:\[[^\]]*\])?\s*\{`)
+	bibitem        = regexp.MustCompile(`\\bibitem\s*(?:\[[^\]]*\])?\s*\{([^}]*)\}`)
+	endBibliograph = regexp.MustCompile(`\\end\{thebibliography\}`)
+	bodyEnd        = regexp.MustCompile(`\\(?:end\{document\}|bibliography\{|begin\{thebibliography\})`)
+	// Current (1501.00001) and old (hep-th/9901001) identifiers
+	arXivIDPattern = regexp.MustCompile(`\b(\d{4}\.\d{4,5}|[a-z\-]+(?:\.[A-Z]{2})?/\d{7})(?:v\d+)?\b`)
+)
+
+// StripComments removes the LaTeX comments of a text
+func StripComments(tex string) string {
+	return comment.ReplaceAllString(tex, "$1")
+}
+
+// MainFile returns the name of the file holding \documentclass
+func MainFile(files map[string][]byte) (string, bool) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if strings.HasSuffix(name, ".bbl") {
+			continue
+		}
+		if strings.Contains(StripComments(string(files[name])), `\documentclass`) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// resolveInputs replaces the \input and \include commands by the content
+// of the included files
+func resolveInputs(tex, dir string, files map[string][]byte, depth int) string {
+	if depth > maxInputDepth {
+		return tex
+	}
+	return input.ReplaceAllStringFunc(tex, func(cmd string) string {
+		name := path.Join(dir, strings.TrimSpace(input.FindStringSubmatch(cmd)[1]))
+		body, ok := files[name]
+		if !ok {
+			body, ok = files[name+".tex"]
+		}
+		if !ok {
+			return ""
+		}
+		return resolveInputs(StripComments(string(body)), dir, files, depth+1)
+	})
+}
+
+// closingBrace returns the index of the brace closing the group opened
+// just before start
+func closingBrace(s string, start int) int {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// ParseSections splits the body of a document at its \section commands.
+// The text preceding the first section is not returned.
+func ParseSections(tex string) []Section {
+	if end := bodyEnd.FindStringIndex(tex); end != nil {
+		tex = tex[:end[0]]
+	}
+	var sections []Section
+	matches := sectionCommand.FindAllStringIndex(tex, -1)
+	for i, m := range matches {
+		closing := closingBrace(tex, m[1])
+		if closing < 0 {
+			break
+		}
+		end := len(tex)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		if closing >= end {
+			continue
+		}
+		sections = append(sections, Section{
+			Title: PlainText(tex[m[1]:closing]),
+			Text:  PlainText(tex[closing+1 : end]),
+		})
+	}
+	return sections
+}
+
+// ParseBibItems reads the \bibitem entries of a thebibliography
+// environment, as found in .bbl files
+func ParseBibItems(tex string) []BibItem {
+	var items []BibItem
+	matches := bibitem.FindAllStringSubmatchIndex(tex, -1)
+	for i, m := range matches {
+		end := len(tex)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		} else if e := endBibliograph.FindStringIndex(tex[m[1]:]); e != nil {
+			end = m[1] + e[0]
+		}
+		text := tex[m[1]:end]
+		item := BibItem{
+			Key:  strings.TrimSpace(tex[m[2]:m[3]]),
+			Text: PlainText(text),
+		}
+		seen := make(map[string]bool)
+		for _, id := range arXivIDPattern.FindAllStringSubmatch(text, -1) {
+			if !seen[id[1]] {
+				seen[id[1]] = true
+				item.ArXivIDs = append(item.ArXivIDs, id[1])
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+var (
+	dropped    = regexp.MustCompile(`~?\\(?:label|ref|eqref|cite[a-z]*|bibitem|bibliographystyle|newblock|vspace|hspace|title|author|date|thanks|affiliation)\b\*?\s*(?:\[[^\]]*\])?\s*(?:\{[^}]*\})?`)
+	formatting = regexp.MustCompile(`\\(?:emph|textbf|textit|textrm|texttt|textsc|mbox|url|href\{[^}]*\})\s*\{([^{}]*)\}`)
+	commands   = regexp.MustCompile(`\\[A-Za-z]+\*?`)
+	spaces     = regexp.MustCompile(`[ \t]+`)
+	paragraphs = regexp.MustCompile(`\n\s*\n\s*`)
+)
+
+// PlainText turns a LaTeX fragment into readable text: the references and
+// formatting commands are dropped, the math is kept as written
+func PlainText(tex string) string {
+	tex = StripComments(tex)
+	tex = dropped.ReplaceAllString(tex, "")
+	tex = formatting.ReplaceAllString(tex, "$1")
+	tex = commands.ReplaceAllString(tex, "")
+	tex = strings.NewReplacer(
+		`\\`, "\n",
+		`\%`, "%",
+		`\&`, "&",
+		`\_`, "_",
+		`\#`, "#",
+		"{", "",
+		"}", "",
+		"~", " ",
+	).Replace(tex)
+	lines := strings.Split(tex, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(spaces.ReplaceAllString(lines[i], " "))
+	}
+	tex = paragraphs.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(tex)
+}
+
+// Parse reads the files of an e-print: the main file with its inputs for
+// the abstract and the sections, and the .bbl files for the bibliography
+func Parse(files map[string][]byte) (Document, error) {
+	doc := Document{}
+	main, ok := MainFile(files)
+	if !ok {
+		return doc, ErrNoSource
+	}
+	tex := resolveInputs(StripComments(string(files[main])), path.Dir(main), files, 0)
+
+	if m := abstractEnv.FindStringSubmatch(tex); m != nil {
+		doc.Abstract = PlainText(m[1])
+	}
+	doc.Sections = ParseSections(tex)
+
+	// The bibliography is in the .bbl files generated by BibTeX, or
+	// written by hand in the main file
+	names := make([]string, 0, len(files))
+	for name := range files {
+		if strings.HasSuffix(name, ".bbl") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		doc.BibItems = append(doc.BibItems, ParseBibItems(string(files[name]))...)
+	}
+	if len(doc.BibItems) == 0 {
+		doc.BibItems = ParseBibItems(tex)
+	}
+	return doc, nil
+}
+
+// CitedArXivIDs returns the arXiv identifiers cited by a document
+func (doc Document) CitedArXivIDs() []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, item := range doc.BibItems {
+		for _, id := range item.ArXivIDs {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
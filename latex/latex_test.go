@@ -0,0 +1,114 @@
+package latex
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"log"
+	"reflect"
+	"testing"
+)
+
+const mainTex = `\documentclass{article}
+% \section{Commented out}
+\begin{document}
+\title{Learning to rank citations}
+\begin{abstract}
+We learn to rank the references of a \emph{paper}, 50\% faster.
+\end{abstract}
+\section{Introduction}\label{sec:intro}
+Ranking matters~\cite{smith99}.
+\input{method}
+\bibliographystyle{plain}
+\bibliography{refs}
+\end{document}
+`
+
+const methodTex = `\section[Method]{Our {\bf Method}}
+We use a graph.
+\subsection{Details}
+Many details.
+`
+
+const refsBbl = `\begin{thebibliography}{2}
+\bibitem{smith99}
+J.~Smith, \newblock Non-Abelian duality and the index, \newblock arXiv:hep-th/9901001v3.
+\bibitem[Martin(2015)]{martin15}
+A.~Martin, \newblock Learning to rank, \newblock arXiv:1501.00001, see also 1501.00001.
+\end{thebibliography}
+`
+
+func tarball(files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for name, body := range files {
+		err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(body)), Typeflag: tar.TypeReg})
+		if err != nil {
+			log.Fatal(err)
+		}
+		tw.Write([]byte(body))
+	}
+	tw.Close()
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestExtractSource(t *testing.T) {
+	files, err := ExtractSource(bytes.NewReader(tarball(map[string]string{
+		"./paper.tex": mainTex,
+		"method.tex":  methodTex,
+		"paper.bbl":   refsBbl,
+		"fig1.eps":    "%!PS",
+	})))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(files) != 3 || files["paper.tex"] == nil {
+		log.Fatalf("Wrong files: %v", files)
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(mainTex))
+	zw.Close()
+	files, err = ExtractSource(&buf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if string(files["main.tex"]) != mainTex {
+		log.Fatalf("Wrong single file: %v", files)
+	}
+
+	if _, err = ExtractSource(bytes.NewReader([]byte("%PDF-1.4"))); err != ErrNoSource {
+		log.Fatalf("Wrong error for a PDF: %v", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	doc, err := Parse(map[string][]byte{
+		"paper.tex":  []byte(mainTex),
+		"method.tex": []byte(methodTex),
+		"paper.bbl":  []byte(refsBbl),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if doc.Abstract != "We learn to rank the references of a paper, 50% faster." {
+		log.Fatalf("Wrong abstract: %q", doc.Abstract)
+	}
+	want := []Section{
+		{Title: "Introduction", Text: "Ranking matters."},
+		{Title: "Our Method", Text: "We use a graph.\nDetails\nMany details."},
+	}
+	if !reflect.DeepEqual(doc.Sections, want) {
+		log.Fatalf("Wrong sections: %#v", doc.Sections)
+	}
+	if len(doc.BibItems) != 2 || doc.BibItems[1].Key != "martin15" {
+		log.Fatalf("Wrong bibliography: %#v", doc.BibItems)
+	}
+	ids := doc.CitedArXivIDs()
+	if !reflect.DeepEqual(ids, []string{"hep-th/9901001", "1501.00001"}) {
+		log.Fatalf("Wrong citations: %v", ids)
+	}
+}
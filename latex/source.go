@@ -0,0 +1,89 @@
+package latex
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+// ErrNoSource is returned when the e-print of an article is only a PDF
+var ErrNoSource = errors.New("No LaTeX source in the e-print")
+
+// EPrintTypes are the media types of the e-prints served by arXiv, other
+// bodies such as an HTML error page are not sources
+var EPrintTypes = []string{"application/x-eprint-tar", "application/x-eprint", "application/gzip", "application/pdf"}
+
+// MaxFileSize bounds the size of a file extracted from an e-print
+var MaxFileSize int64 = 16 << 20
+
+// sourceExtensions are the files kept from an e-print
+var sourceExtensions = map[string]bool{
+	".tex": true,
+	".bbl": true,
+	".ltx": true,
+}
+
+// ExtractSource reads an arXiv e-print, which is either a gzipped tarball,
+// a single gzipped file or an uncompressed file, and returns its LaTeX
+// files by name
+func ExtractSource(r io.Reader) (map[string][]byte, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		br = bufio.NewReader(zr)
+	}
+
+	header, _ := br.Peek(512)
+	switch {
+	case bytes.HasPrefix(header, []byte("%PDF")):
+		return nil, ErrNoSource
+	case len(header) > 262 && bytes.Equal(header[257:262], []byte("ustar")):
+		return extractTar(br)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(br, MaxFileSize))
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{"main.tex": body}, nil
+}
+
+func extractTar(r io.Reader) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if !sourceExtensions[strings.ToLower(path.Ext(name))] {
+			continue
+		}
+		body, err := ioutil.ReadAll(io.LimitReader(tr, MaxFileSize))
+		if err != nil {
+			return nil, err
+		}
+		files[name] = body
+	}
+	if len(files) == 0 {
+		return nil, ErrNoSource
+	}
+	return files, nil
+}
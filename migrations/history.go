@@ -0,0 +1,217 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/dgraph-io/dgo/v2"
+	"github.com/dgraph-io/dgo/v2/protos/api"
+)
+
+// All lists the migrations in order. Their schemas merged together give
+// models.Schema, which a test checks: a change of models.Schema needs a new
+// migration.
+var All = []Migration{
+	{
+		Version: 1,
+		Name:    "initial schema of the crawler",
+		Schema: `
+  title: string @index(term, exact, hash, fulltext, trigram) .
+  name: string @index(term, exact, hash, fulltext, trigram) .
+  url: string @index(hash) .
+  arxivid: string @index(term, exact, hash, fulltext, trigram) .
+  abstract: string .
+  submissiondate: datetime .
+  crawledat: datetime .
+  htmlresponse: string .
+  pdfurl: string .
+  otherformaturl: string .
+  metaurl: string .
+  authors: [uid] @reverse .
+  citedpapers: [uid] @reverse .
+
+  type Article {
+    arxivid: string
+    title: string
+    abstract: string
+    submissiondate: datetime
+    crawledat: datetime
+    htmlresponse: string
+    pdfurl: string
+    otherformaturl: string
+    metaurl: string
+    authors: [Author]
+    citedpapers: [Article]
+  }
+
+  type Author {
+    name: string
+    url: string
+  }
+`,
+	},
+	{
+		Version: 2,
+		Name:    "archive, PDFs, sources and full text",
+		Schema: `
+  archiveref: string @index(exact) .
+  pdfkey: string .
+  pdfchecksum: string .
+  sourcekey: string .
+  fulltextkey: string .
+  sections: [uid] @reverse .
+  sectionname: string @index(exact) .
+  sectiontext: string .
+
+  type Article {
+    arxivid: string
+    title: string
+    abstract: string
+    submissiondate: datetime
+    crawledat: datetime
+    htmlresponse: string
+    archiveref: string
+    pdfurl: string
+    pdfkey: string
+    pdfchecksum: string
+    sourcekey: string
+    fulltextkey: string
+    otherformaturl: string
+    metaurl: string
+    authors: [Author]
+    citedpapers: [Article]
+    sections: [Section]
+  }
+
+  type Section {
+    sectionname: string
+    sectiontext: string
+  }
+`,
+	},
+	{
+		Version: 3,
+		Name:    "search indexes and categories",
+		Schema: `
+  abstract: string @index(term, fulltext) .
+  submissiondate: datetime @index(day) .
+  sectiontext: string @index(fulltext) .
+  categories: [uid] @reverse .
+  categorycode: string @index(exact, trigram) .
+  categoryname: string @index(term) .
+
+  type Article {
+    arxivid: string
+    title: string
+    abstract: string
+    submissiondate: datetime
+    crawledat: datetime
+    htmlresponse: string
+    archiveref: string
+    pdfurl: string
+    pdfkey: string
+    pdfchecksum: string
+    sourcekey: string
+    fulltextkey: string
+    otherformaturl: string
+    metaurl: string
+    authors: [Author]
+    categories: [Category]
+    citedpapers: [Article]
+    sections: [Section]
+  }
+
+  type Category {
+    categorycode: string
+    categoryname: string
+  }
+`,
+	},
+	{
+		Version: 4,
+		Name:    "citation metrics, centralities, communities and topics",
+		Schema: `
+  citationcount: int @index(int) .
+  citationvelocity: float @index(float) .
+  hindex: int @index(int) .
+  i10index: int .
+  pagerank: float @index(float) .
+  hubscore: float .
+  authorityscore: float .
+  betweenness: float .
+  communitylabel: string @index(term) .
+  communitysize: int @index(int) .
+  communitymethod: string .
+  members: [uid] @reverse .
+  topics: [uid] @reverse .
+  topicnumber: int @index(int) .
+  topicterms: string .
+
+  type Article {
+    arxivid: string
+    title: string
+    abstract: string
+    submissiondate: datetime
+    crawledat: datetime
+    htmlresponse: string
+    archiveref: string
+    pdfurl: string
+    pdfkey: string
+    pdfchecksum: string
+    sourcekey: string
+    fulltextkey: string
+    otherformaturl: string
+    metaurl: string
+    authors: [Author]
+    categories: [Category]
+    citedpapers: [Article]
+    sections: [Section]
+    citationcount: int
+    citationvelocity: float
+    pagerank: float
+    hubscore: float
+    authorityscore: float
+    betweenness: float
+    topics: [Topic]
+  }
+
+  type Topic {
+    topicnumber: int
+    topicterms: string
+  }
+
+  type Community {
+    communitylabel: string
+    communitysize: int
+    communitymethod: string
+    members: [Author]
+  }
+
+  type Author {
+    name: string
+    url: string
+    citationcount: int
+    hindex: int
+    i10index: int
+  }
+`,
+	},
+	{
+		Version:  5,
+		Name:     "drop the HTML of the archived pages",
+		Backfill: dropArchivedHTML,
+	},
+}
+
+// dropArchivedHTML removes the htmlresponse of the articles whose page is in
+// the archive, the archive keeps it
+func dropArchivedHTML(dg *dgo.Dgraph) error {
+	req := &api.Request{
+		Query: `{ archived as var(func: has(htmlresponse)) @filter(has(archiveref)) }`,
+		Mutations: []*api.Mutation{{
+			DelNquads: []byte("uid(archived) <htmlresponse> * ."),
+		}},
+		CommitNow: true,
+	}
+	_, err := dg.NewTxn().Do(context.Background(), req)
+	return err
+}
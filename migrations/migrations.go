@@ -0,0 +1,190 @@
+package migrations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"pandor/databases"
+
+	"github.com/dgraph-io/dgo/v2"
+	"github.com/dgraph-io/dgo/v2/protos/api"
+)
+
+// Migration upgrades the database from the previous version. Its schema
+// alters the predicates and the types it lists, a type being replaced as a
+// whole, then its backfill updates the data. Both run again when a
+// migration is interrupted, so they have to be idempotent.
+type Migration struct {
+	Version  int
+	Name     string
+	Schema   string
+	Backfill func(dg *dgo.Dgraph) error
+}
+
+// versionSchema holds the version of the schema of the database, on a
+// single SchemaVersion node
+const versionSchema = `
+  schemaversion: int .
+  migratedat: datetime .
+
+  type SchemaVersion {
+    schemaversion: int
+    migratedat: datetime
+  }
+`
+
+// Latest returns the version reached by all the migrations
+func Latest() int {
+	return All[len(All)-1].Version
+}
+
+// Desired returns the schema after the migrations up to a version
+func Desired(version int) (Schema, error) {
+	s := NewSchema()
+	for _, m := range All {
+		if m.Version > version {
+			break
+		}
+		parsed, err := ParseSchema(m.Schema)
+		if err != nil {
+			return s, fmt.Errorf("Migration %d: %v", m.Version, err)
+		}
+		s = s.Merge(parsed)
+	}
+	return s, nil
+}
+
+// versionNode is the SchemaVersion node, written without omitempty so that
+// the version 0 is kept
+type versionNode struct {
+	UID        string    `json:"uid"`
+	Version    int       `json:"schemaversion"`
+	MigratedAt time.Time `json:"migratedat"`
+	DType      []string  `json:"dgraph.type"`
+}
+
+// readVersion returns the SchemaVersion node, with a blank UID when the
+// database was never migrated
+func readVersion(dg *dgo.Dgraph) (versionNode, error) {
+	node := versionNode{UID: "_:version", DType: []string{"SchemaVersion"}}
+	resp, err := dg.NewReadOnlyTxn().Query(context.Background(),
+		`{ versions(func: type(SchemaVersion), first: 1) { uid schemaversion migratedat } }`)
+	if err != nil {
+		return node, err
+	}
+	var r struct {
+		Versions []versionNode `json:"versions"`
+	}
+	err = json.Unmarshal(resp.Json, &r)
+	if err != nil {
+		return node, err
+	}
+	if len(r.Versions) > 0 {
+		node.UID = r.Versions[0].UID
+		node.Version = r.Versions[0].Version
+		node.MigratedAt = r.Versions[0].MigratedAt
+	}
+	return node, nil
+}
+
+// Version returns the version of the schema of the database, 0 when it was
+// never migrated
+func Version(dg *dgo.Dgraph) (int, error) {
+	node, err := readVersion(dg)
+	return node.Version, err
+}
+
+// Check fails when the database is not at the latest version
+func Check(dg *dgo.Dgraph) error {
+	version, err := Version(dg)
+	if err != nil {
+		return err
+	}
+	if version < Latest() {
+		return fmt.Errorf("The schema is at version %d instead of %d, run pandor schema migrate", version, Latest())
+	}
+	return nil
+}
+
+func alter(schema string, dg *dgo.Dgraph) error {
+	return dg.Alter(context.Background(), &api.Operation{Schema: schema})
+}
+
+// Migrate runs the migrations from the version of the database up to
+// target and reports them to w. With dryRun, it only reports the pending
+// migrations and the difference between the current and the target schema.
+func Migrate(target int, dryRun bool, w io.Writer, dg *dgo.Dgraph) error {
+	if target > Latest() {
+		return fmt.Errorf("Unknown version %d, the latest is %d", target, Latest())
+	}
+	node, err := readVersion(dg)
+	if err != nil {
+		return err
+	}
+	if node.Version > target {
+		return fmt.Errorf("The schema is at version %d, after %d, and migrations only go up", node.Version, target)
+	}
+
+	var pending []Migration
+	for _, m := range All {
+		if m.Version > node.Version && m.Version <= target {
+			pending = append(pending, m)
+		}
+	}
+	fmt.Fprintf(w, "Schema version %d, target %d\n", node.Version, target)
+	for _, m := range pending {
+		backfill := ""
+		if m.Backfill != nil {
+			backfill = ", with a backfill"
+		}
+		fmt.Fprintf(w, "  %d %s%s\n", m.Version, m.Name, backfill)
+	}
+
+	if dryRun {
+		current, err := CurrentSchema(dg)
+		if err != nil {
+			return err
+		}
+		desired, err := Desired(target)
+		if err != nil {
+			return err
+		}
+		for _, change := range Diff(current, desired) {
+			fmt.Fprintln(w, change)
+		}
+		return nil
+	}
+
+	if len(pending) > 0 {
+		if err = alter(versionSchema, dg); err != nil {
+			return err
+		}
+	}
+	for _, m := range pending {
+		start := time.Now()
+		if m.Schema != "" {
+			if err = alter(m.Schema, dg); err != nil {
+				return fmt.Errorf("Migration %d: %v", m.Version, err)
+			}
+		}
+		if m.Backfill != nil {
+			if err = m.Backfill(dg); err != nil {
+				return fmt.Errorf("Backfill of migration %d: %v", m.Version, err)
+			}
+		}
+		node.Version = m.Version
+		node.MigratedAt = time.Now().UTC()
+		uids, err := databases.SetJSON(node, dg)
+		if err != nil {
+			return err
+		}
+		if uid, ok := uids["version"]; ok {
+			node.UID = uid
+		}
+		fmt.Fprintf(w, "Migrated to %d in %v\n", m.Version, time.Since(start).Round(time.Millisecond))
+	}
+	return nil
+}
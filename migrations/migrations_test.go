@@ -0,0 +1,106 @@
+package migrations
+
+import (
+	"log"
+	"strings"
+	"testing"
+
+	"pandor/models"
+)
+
+func TestVersions(t *testing.T) {
+	for i, m := range All {
+		if m.Version != i+1 {
+			log.Fatalf("Migration %d %q should be version %d", m.Version, m.Name, i+1)
+		}
+		if m.Schema == "" && m.Backfill == nil {
+			log.Fatalf("Migration %d does nothing", m.Version)
+		}
+	}
+}
+
+// TestDrift fails when models.Schema changes without a migration
+func TestDrift(t *testing.T) {
+	desired, err := Desired(Latest())
+	if err != nil {
+		log.Fatal(err)
+	}
+	schema, err := ParseSchema(models.Schema)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if changes := Diff(desired, schema); len(changes) > 0 {
+		log.Fatalf("models.Schema differs from the migrations, add a migration:\n%s", strings.Join(changes, "\n"))
+	}
+}
+
+func TestParseSchema(t *testing.T) {
+	s, err := ParseSchema(`
+  title: string @index(trigram, term) @upsert .
+	<cited.by>: [uid] @reverse @count .
+
+  type Article {
+    title: string
+    cited.by
+  }
+`)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expected := "cited.by: [uid] @reverse @count .\ntitle: string @index(term, trigram) @upsert .\n\n" +
+		"type Article {\n  cited.by\n  title\n}\n"
+	if s.String() != expected {
+		log.Fatalf("Wrong schema:\n%s", s)
+	}
+	again, err := ParseSchema(s.String())
+	if err != nil || len(Diff(s, again)) > 0 {
+		log.Fatalf("The schema should parse again %v %v", err, Diff(s, again))
+	}
+
+	for _, invalid := range []string{"title string .", "title: string @unique .", "type Article {\n title"} {
+		if _, err := ParseSchema(invalid); err == nil {
+			log.Fatalf("%q should not parse", invalid)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	current, _ := ParseSchema("title: string .\nurl: string .\ntype Author {\n url\n}\n")
+	desired, _ := ParseSchema("title: string @index(term) .\nname: string .\ntype Author {\n name\n url\n}\ntype Topic {\n name\n}\n")
+	expected := []string{
+		"+ name: string .",
+		"~ title: string . => title: string @index(term) .",
+		"- url: string .",
+		"~ type Author { url } => { name url }",
+		"+ type Topic { name }",
+	}
+	if changes := Diff(current, desired); strings.Join(changes, "\n") != strings.Join(expected, "\n") {
+		log.Fatalf("Wrong diff:\n%s", strings.Join(changes, "\n"))
+	}
+
+	merged := current.Merge(desired)
+	if len(merged.Predicates) != 3 || merged.Predicates["title"].Index[0] != "term" || len(merged.Types["Author"]) != 2 {
+		log.Fatalf("Wrong merge:\n%s", merged)
+	}
+}
+
+func TestDecodeSchema(t *testing.T) {
+	s, err := decodeSchema([]byte(`{
+		"schema": [
+			{"predicate": "authors", "type": "uid", "list": true, "reverse": true},
+			{"predicate": "title", "type": "string", "index": true, "tokenizer": ["trigram", "exact"]},
+			{"predicate": "dgraph.type", "type": "string", "index": true, "tokenizer": ["exact"], "list": true}
+		],
+		"types": [
+			{"name": "Article", "fields": [{"name": "title"}, {"name": "authors"}]},
+			{"name": "dgraph.graphql", "fields": [{"name": "dgraph.graphql.schema"}]}
+		]
+	}`))
+	if err != nil {
+		log.Fatal(err)
+	}
+	expected := "authors: [uid] @reverse .\ntitle: string @index(exact, trigram) .\n\ntype Article {\n  authors\n  title\n}\n"
+	if s.String() != expected {
+		log.Fatalf("Wrong decoded schema:\n%s", s)
+	}
+}
@@ -0,0 +1,280 @@
+package migrations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v2"
+)
+
+// Predicate is the definition of a predicate in a Dgraph schema
+type Predicate struct {
+	Name    string
+	Type    string   // such as string or [uid]
+	Index   []string // tokenizers, sorted
+	Reverse bool
+	Upsert  bool
+	Count   bool
+	Lang    bool
+}
+
+// String returns the definition as written in a schema
+func (p Predicate) String() string {
+	s := p.Name + ": " + p.Type
+	if len(p.Index) > 0 {
+		s += " @index(" + strings.Join(p.Index, ", ") + ")"
+	}
+	for _, d := range []struct {
+		set  bool
+		name string
+	}{{p.Reverse, "@reverse"}, {p.Upsert, "@upsert"}, {p.Count, "@count"}, {p.Lang, "@lang"}} {
+		if d.set {
+			s += " " + d.name
+		}
+	}
+	return s + " ."
+}
+
+// Schema is a parsed Dgraph schema, types are listed by their fields
+type Schema struct {
+	Predicates map[string]Predicate
+	Types      map[string][]string
+}
+
+// NewSchema returns an empty schema
+func NewSchema() Schema {
+	return Schema{Predicates: make(map[string]Predicate), Types: make(map[string][]string)}
+}
+
+var (
+	predicatePattern = regexp.MustCompile(`^<?([\w.~-]+)>?\s*:\s*(\[?\w+\]?)\s*(.*?)\s*\.$`)
+	directivePattern = regexp.MustCompile(`@(\w+)(?:\(([^)]*)\))?`)
+	typePattern      = regexp.MustCompile(`^type\s+(\w+)\s*\{$`)
+	fieldPattern     = regexp.MustCompile(`^([\w.~-]+)(?:\s*:\s*\[?\w+\]?)?$`)
+)
+
+// ParseSchema parses the schemas written as models.Schema, a predicate per
+// line and type blocks with a field per line
+func ParseSchema(text string) (Schema, error) {
+	s := NewSchema()
+	var typeName string
+	var fields []string
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if typeName != "" {
+			if line == "}" {
+				sort.Strings(fields)
+				s.Types[typeName] = fields
+				typeName, fields = "", nil
+				continue
+			}
+			m := fieldPattern.FindStringSubmatch(line)
+			if m == nil {
+				return s, fmt.Errorf("Line %d: invalid field %q of type %s", n+1, line, typeName)
+			}
+			fields = append(fields, m[1])
+			continue
+		}
+		if m := typePattern.FindStringSubmatch(line); m != nil {
+			typeName = m[1]
+			continue
+		}
+		m := predicatePattern.FindStringSubmatch(line)
+		if m == nil {
+			return s, fmt.Errorf("Line %d: invalid predicate %q", n+1, line)
+		}
+		p := Predicate{Name: m[1], Type: m[2]}
+		for _, d := range directivePattern.FindAllStringSubmatch(m[3], -1) {
+			switch d[1] {
+			case "index":
+				for _, tokenizer := range strings.Split(d[2], ",") {
+					p.Index = append(p.Index, strings.TrimSpace(tokenizer))
+				}
+				sort.Strings(p.Index)
+			case "reverse":
+				p.Reverse = true
+			case "upsert":
+				p.Upsert = true
+			case "count":
+				p.Count = true
+			case "lang":
+				p.Lang = true
+			default:
+				return s, fmt.Errorf("Line %d: unknown directive @%s", n+1, d[1])
+			}
+		}
+		s.Predicates[p.Name] = p
+	}
+	if typeName != "" {
+		return s, fmt.Errorf("Type %s is not closed", typeName)
+	}
+	return s, nil
+}
+
+// Merge returns the schema after altering s with other. As with Dgraph
+// alterations, the predicates and the types of other replace the ones of s.
+func (s Schema) Merge(other Schema) Schema {
+	merged := NewSchema()
+	for _, from := range []Schema{s, other} {
+		for name, p := range from.Predicates {
+			merged.Predicates[name] = p
+		}
+		for name, fields := range from.Types {
+			merged.Types[name] = fields
+		}
+	}
+	return merged
+}
+
+// String returns the schema in the format of ParseSchema, sorted
+func (s Schema) String() string {
+	var b strings.Builder
+	for _, name := range sortedKeys(s.Predicates) {
+		fmt.Fprintf(&b, "%s\n", s.Predicates[name])
+	}
+	types := make([]string, 0, len(s.Types))
+	for name := range s.Types {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	for _, name := range types {
+		fmt.Fprintf(&b, "\ntype %s {\n", name)
+		for _, field := range s.Types[name] {
+			fmt.Fprintf(&b, "  %s\n", field)
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+func sortedKeys(predicates map[string]Predicate) []string {
+	keys := make([]string, 0, len(predicates))
+	for name := range predicates {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Diff lists the changes from the current schema to the desired one, one
+// per line: + for an addition, ~ for a change and - for what only the
+// current schema has, which the migrations never drop
+func Diff(current, desired Schema) []string {
+	var changes []string
+	for _, name := range sortedKeys(desired.Predicates) {
+		p := desired.Predicates[name]
+		c, ok := current.Predicates[name]
+		switch {
+		case !ok:
+			changes = append(changes, "+ "+p.String())
+		case c.String() != p.String():
+			changes = append(changes, fmt.Sprintf("~ %s => %s", c, p))
+		}
+	}
+	for _, name := range sortedKeys(current.Predicates) {
+		if _, ok := desired.Predicates[name]; !ok {
+			changes = append(changes, "- "+current.Predicates[name].String())
+		}
+	}
+
+	names := make(map[string]bool)
+	for name := range desired.Types {
+		names[name] = true
+	}
+	for name := range current.Types {
+		names[name] = true
+	}
+	var types []string
+	for name := range names {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	for _, name := range types {
+		d, inDesired := desired.Types[name]
+		c, inCurrent := current.Types[name]
+		switch {
+		case !inCurrent:
+			changes = append(changes, fmt.Sprintf("+ type %s { %s }", name, strings.Join(d, " ")))
+		case !inDesired:
+			changes = append(changes, fmt.Sprintf("- type %s { %s }", name, strings.Join(c, " ")))
+		case strings.Join(c, " ") != strings.Join(d, " "):
+			changes = append(changes, fmt.Sprintf("~ type %s { %s } => { %s }", name, strings.Join(c, " "), strings.Join(d, " ")))
+		}
+	}
+	return changes
+}
+
+// dgraphSchema is the result of a schema {} query
+type dgraphSchema struct {
+	Schema []struct {
+		Predicate string   `json:"predicate"`
+		Type      string   `json:"type"`
+		Index     bool     `json:"index"`
+		Tokenizer []string `json:"tokenizer"`
+		Reverse   bool     `json:"reverse"`
+		Upsert    bool     `json:"upsert"`
+		Count     bool     `json:"count"`
+		Lang      bool     `json:"lang"`
+		List      bool     `json:"list"`
+	} `json:"schema"`
+	Types []struct {
+		Name   string `json:"name"`
+		Fields []struct {
+			Name string `json:"name"`
+		} `json:"fields"`
+	} `json:"types"`
+}
+
+// decodeSchema converts the result of a schema {} query, without the
+// internal predicates and types of Dgraph
+func decodeSchema(body []byte) (Schema, error) {
+	var d dgraphSchema
+	err := json.Unmarshal(body, &d)
+	if err != nil {
+		return Schema{}, err
+	}
+	s := NewSchema()
+	for _, p := range d.Schema {
+		if strings.HasPrefix(p.Predicate, "dgraph.") {
+			continue
+		}
+		predicate := Predicate{Name: p.Predicate, Type: p.Type, Reverse: p.Reverse,
+			Upsert: p.Upsert, Count: p.Count, Lang: p.Lang}
+		if p.List {
+			predicate.Type = "[" + p.Type + "]"
+		}
+		if p.Index {
+			predicate.Index = append([]string(nil), p.Tokenizer...)
+			sort.Strings(predicate.Index)
+		}
+		s.Predicates[p.Predicate] = predicate
+	}
+	for _, t := range d.Types {
+		if strings.HasPrefix(t.Name, "dgraph.") {
+			continue
+		}
+		fields := []string{}
+		for _, f := range t.Fields {
+			fields = append(fields, f.Name)
+		}
+		sort.Strings(fields)
+		s.Types[t.Name] = fields
+	}
+	return s, nil
+}
+
+// CurrentSchema reads the schema of the database
+func CurrentSchema(dg *dgo.Dgraph) (Schema, error) {
+	resp, err := dg.NewReadOnlyTxn().Query(context.Background(), "schema {}")
+	if err != nil {
+		return Schema{}, err
+	}
+	return decodeSchema(resp.Json)
+}
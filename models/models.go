@@ -6,61 +6,183 @@ import "time"
 // If omitempty is not set, then edges with empty values (0 for int/float, "" for string, false
 // for bool) would be created for values not specified explicitly.
 type Article struct {
-	UID            string    `json:"uid,omitempty"`
-	ArXivID        string    `json:"arxivid,omitempty"`
-	Title          string    `json:"title,omitempty"`
-	Abstract       string    `json:"abstract,omitempty"`
-	SubmissionDate time.Time `json:"submissiondate,omitempty"`
-	CrawledAt      time.Time `json:"crawledat,omitempty"`
-	HTMLResponse   string    `json:"htmlresponse,omitempty"`
-	PDFURL         string    `json:"pdfurl,omitempty"`
-	OtherFormatURL string    `json:"otherformaturl,omitempty"`
-	MetaURL        string    `json:"metaurl,omitempty"`
-	Authors        []Author  `json:"authors,omitempty"`
-	CitedPapers    []Article `json:"citedpapers,omitempty"`
-	DType          []string  `json:"dgraph.type,omitempty"`
+	UID              string     `json:"uid,omitempty"`
+	ArXivID          string     `json:"arxivid,omitempty" dgraph:"index=term,exact,hash,fulltext,trigram"`
+	Title            string     `json:"title,omitempty" dgraph:"index=term,exact,hash,fulltext,trigram"`
+	Abstract         string     `json:"abstract,omitempty" dgraph:"index=term,fulltext"`
+	SubmissionDate   time.Time  `json:"submissiondate,omitempty" dgraph:"index=day"`
+	CrawledAt        time.Time  `json:"crawledat,omitempty"`
+	HTMLResponse     string     `json:"htmlresponse,omitempty"` // only set on articles crawled before the archive
+	ArchiveRef       string     `json:"archiveref,omitempty" dgraph:"index=exact"`
+	PDFURL           string     `json:"pdfurl,omitempty"`
+	PDFKey           string     `json:"pdfkey,omitempty"`
+	PDFChecksum      string     `json:"pdfchecksum,omitempty"`
+	SourceKey        string     `json:"sourcekey,omitempty"`
+	FullTextKey      string     `json:"fulltextkey,omitempty"`
+	Sections         []Section  `json:"sections,omitempty" dgraph:"reverse"`
+	OtherFormatURL   string     `json:"otherformaturl,omitempty"`
+	MetaURL          string     `json:"metaurl,omitempty"`
+	Authors          []Author   `json:"authors,omitempty" dgraph:"reverse"`
+	Categories       []Category `json:"categories,omitempty" dgraph:"reverse"`
+	CitedPapers      []Article  `json:"citedpapers,omitempty" dgraph:"reverse"`
+	CitationCount    int        `json:"citationcount,omitempty" dgraph:"index=int"`
+	CitationVelocity float64    `json:"citationvelocity,omitempty" dgraph:"index=float"`
+	PageRank         float64    `json:"pagerank,omitempty" dgraph:"index=float"` // 1 for an average article
+	HubScore         float64    `json:"hubscore,omitempty"`
+	AuthorityScore   float64    `json:"authorityscore,omitempty"`
+	Betweenness      float64    `json:"betweenness,omitempty"`
+	Topics           []Topic    `json:"topics,omitempty" dgraph:"reverse"`
+	DType            []string   `json:"dgraph.type,omitempty"`
 }
 
-// Author type
+// Author type. On the members edge of a community its rank in the
+// community is a facet.
 type Author struct {
+	UID           string   `json:"uid,omitempty"`
+	Name          string   `json:"name,omitempty" dgraph:"index=term,exact,hash,fulltext,trigram"`
+	URL           string   `json:"url,omitempty" dgraph:"index=hash"`
+	CitationCount int      `json:"citationcount,omitempty" dgraph:"index=int"`
+	HIndex        int      `json:"hindex,omitempty" dgraph:"index=int"`
+	I10Index      int      `json:"i10index,omitempty"`
+	Rank          int      `json:"members|rank,omitempty"` // facet of the members edge of a community, from 1 for the most prolific
+	DType         []string `json:"dgraph.type,omitempty"`
+}
+
+// Category type, an arXiv subject class such as astro-ph.GA
+type Category struct {
+	UID   string   `json:"uid,omitempty"`
+	Code  string   `json:"categorycode,omitempty" dgraph:"index=exact,trigram"`
+	Name  string   `json:"categoryname,omitempty" dgraph:"index=term"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+// Community type, a group of authors who often write together
+type Community struct {
+	UID     string   `json:"uid,omitempty"`
+	Label   string   `json:"communitylabel,omitempty" dgraph:"index=term"` // name of its most prolific member
+	Size    int      `json:"communitysize,omitempty" dgraph:"index=int"`
+	Method  string   `json:"communitymethod,omitempty"`
+	Members []Author `json:"members,omitempty" dgraph:"reverse"`
+	DType   []string `json:"dgraph.type,omitempty"`
+}
+
+// Topic type, a topic of the abstracts. On the topics edge of an article
+// its weight in the article is a facet.
+type Topic struct {
+	UID    string   `json:"uid,omitempty"`
+	Number int      `json:"topicnumber,omitempty" dgraph:"index=int"` // from 1
+	Terms  string   `json:"topicterms,omitempty"`                     // most weighted terms, comma-separated
+	Weight float64  `json:"topics|weight,omitempty"`
+	DType  []string `json:"dgraph.type,omitempty"`
+}
+
+// Section type, a part of the full text of an Article
+type Section struct {
 	UID   string   `json:"uid,omitempty"`
-	Name  string   `json:"name,omitempty"`
-	URL   string   `json:"url,omitempty"`
+	Name  string   `json:"sectionname,omitempty" dgraph:"index=exact"`
+	Text  string   `json:"sectiontext,omitempty" dgraph:"index=fulltext"`
 	DType []string `json:"dgraph.type,omitempty"`
 }
 
-// Schema describing the types
+// Schema describing the types, generated from their struct tags by
+// GenerateSchema: run pandor schema generate after changing a model. It is
+// applied by the migrations package, whose migrations have to add up to it.
 var Schema = `
+  arxivid: string @index(term, exact, hash, fulltext, trigram) .
   title: string @index(term, exact, hash, fulltext, trigram) .
-  name: string @index(term, exact, hash, fulltext, trigram) .
-	url: string @index(hash) .
-	arxivid: string @index(term, exact, hash, fulltext, trigram) .
-  abstract: string .
-  submissiondate: datetime .
+  abstract: string @index(term, fulltext) .
+  submissiondate: datetime @index(day) .
   crawledat: datetime .
   htmlresponse: string .
+  archiveref: string @index(exact) .
   pdfurl: string .
+  pdfkey: string .
+  pdfchecksum: string .
+  sourcekey: string .
+  fulltextkey: string .
+  sections: [uid] @reverse .
   otherformaturl: string .
   metaurl: string .
   authors: [uid] @reverse .
+  categories: [uid] @reverse .
   citedpapers: [uid] @reverse .
+  citationcount: int @index(int) .
+  citationvelocity: float @index(float) .
+  pagerank: float @index(float) .
+  hubscore: float .
+  authorityscore: float .
+  betweenness: float .
+  topics: [uid] @reverse .
+  sectionname: string @index(exact) .
+  sectiontext: string @index(fulltext) .
+  categorycode: string @index(exact, trigram) .
+  categoryname: string @index(term) .
+  topicnumber: int @index(int) .
+  topicterms: string .
+  communitylabel: string @index(term) .
+  communitysize: int @index(int) .
+  communitymethod: string .
+  members: [uid] @reverse .
+  name: string @index(term, exact, hash, fulltext, trigram) .
+  url: string @index(hash) .
+  hindex: int @index(int) .
+  i10index: int .
 
   type Article {
-		arxivid: string
+    arxivid: string
     title: string
     abstract: string
     submissiondate: datetime
     crawledat: datetime
     htmlresponse: string
+    archiveref: string
     pdfurl: string
+    pdfkey: string
+    pdfchecksum: string
+    sourcekey: string
+    fulltextkey: string
+    sections: [Section]
     otherformaturl: string
     metaurl: string
     authors: [Author]
+    categories: [Category]
     citedpapers: [Article]
+    citationcount: int
+    citationvelocity: float
+    pagerank: float
+    hubscore: float
+    authorityscore: float
+    betweenness: float
+    topics: [Topic]
+  }
+
+  type Section {
+    sectionname: string
+    sectiontext: string
+  }
+
+  type Category {
+    categorycode: string
+    categoryname: string
+  }
+
+  type Topic {
+    topicnumber: int
+    topicterms: string
+  }
+
+  type Community {
+    communitylabel: string
+    communitysize: int
+    communitymethod: string
+    members: [Author]
   }
 
   type Author {
     name: string
-		url: string
+    url: string
+    citationcount: int
+    hindex: int
+    i10index: int
   }
 `
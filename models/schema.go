@@ -0,0 +1,106 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Types are the types of the schema, in the order of their type blocks
+var Types = []interface{}{Article{}, Section{}, Category{}, Topic{}, Community{}, Author{}}
+
+// predicate is a predicate of the schema and the type of its field in a
+// type block
+type predicate struct {
+	name       string
+	definition string
+	field      string
+}
+
+// scalarTypes are the Dgraph types of the scalar fields
+var scalarTypes = map[reflect.Kind]string{
+	reflect.String:  "string",
+	reflect.Int:     "int",
+	reflect.Int64:   "int",
+	reflect.Float64: "float",
+	reflect.Bool:    "bool",
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// parsePredicate reads the predicate of a field from its json tag and its
+// directives from its dgraph tag, such as `dgraph:"index=term,exact upsert
+// reverse"`. It returns false for the fields which are not predicates.
+func parsePredicate(f reflect.StructField) (predicate, bool, error) {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	tag := f.Tag.Get("dgraph")
+	// uid and dgraph.type are built in, a name with | is a facet
+	if name == "" || name == "-" || name == "uid" || name == "dgraph.type" || strings.Contains(name, "|") || tag == "-" {
+		return predicate{}, false, nil
+	}
+	p := predicate{name: name}
+
+	var dgraphType string
+	t := f.Type
+	switch {
+	case t == timeType:
+		dgraphType, p.field = "datetime", "datetime"
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct && t.Elem() != timeType:
+		dgraphType, p.field = "[uid]", "["+t.Elem().Name()+"]"
+	case t.Kind() == reflect.Struct:
+		dgraphType, p.field = "uid", t.Name()
+	default:
+		scalar, ok := scalarTypes[t.Kind()]
+		if !ok {
+			return p, false, fmt.Errorf("Field %s has no Dgraph type", f.Name)
+		}
+		dgraphType, p.field = scalar, scalar
+	}
+
+	directives := ""
+	for _, d := range strings.Fields(tag) {
+		switch {
+		case strings.HasPrefix(d, "index="):
+			tokenizers := strings.Split(strings.TrimPrefix(d, "index="), ",")
+			directives += " @index(" + strings.Join(tokenizers, ", ") + ")"
+		case d == "reverse" || d == "upsert" || d == "count" || d == "lang":
+			directives += " @" + d
+		default:
+			return p, false, fmt.Errorf("Field %s has an unknown directive %q", f.Name, d)
+		}
+	}
+	p.definition = fmt.Sprintf("%s: %s%s .", name, dgraphType, directives)
+	return p, true, nil
+}
+
+// GenerateSchema builds the schema of the types from their struct tags.
+// The predicates come in the order of the fields, a predicate shared by
+// several types has to be defined the same way in all of them.
+func GenerateSchema(types ...interface{}) (string, error) {
+	var definitions []string
+	defined := make(map[string]string)
+	var blocks strings.Builder
+	for _, v := range types {
+		t := reflect.TypeOf(v)
+		fmt.Fprintf(&blocks, "\n  type %s {\n", t.Name())
+		for i := 0; i < t.NumField(); i++ {
+			p, ok, err := parsePredicate(t.Field(i))
+			if err != nil {
+				return "", fmt.Errorf("%s: %v", t.Name(), err)
+			}
+			if !ok {
+				continue
+			}
+			if previous, ok := defined[p.name]; !ok {
+				defined[p.name] = p.definition
+				definitions = append(definitions, p.definition)
+			} else if previous != p.definition {
+				return "", fmt.Errorf("%s: %q is already defined as %q", t.Name(), p.definition, previous)
+			}
+			fmt.Fprintf(&blocks, "    %s: %s\n", p.name, p.field)
+		}
+		blocks.WriteString("  }\n")
+	}
+	return "\n  " + strings.Join(definitions, "\n  ") + "\n" + blocks.String(), nil
+}
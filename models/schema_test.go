@@ -0,0 +1,61 @@
+package models
+
+import (
+	"log"
+	"strings"
+	"testing"
+)
+
+// TestSchema fails when Schema is not the one generated from the models
+func TestSchema(t *testing.T) {
+	generated, err := GenerateSchema(Types...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if generated != Schema {
+		log.Fatalf("Schema differs from the models, run pandor schema generate:\n%s", generated)
+	}
+}
+
+func TestGenerateSchema(t *testing.T) {
+	type Node struct {
+		UID    string    `json:"uid,omitempty"`
+		Label  string    `json:"label,omitempty" dgraph:"index=exact,term upsert"`
+		Weight float64   `json:"edge|weight,omitempty"`
+		Edges  []Section `json:"edges,omitempty" dgraph:"reverse count"`
+		Parent Section   `json:"parent,omitempty"`
+		Hidden string    `json:"hidden,omitempty" dgraph:"-"`
+		DType  []string  `json:"dgraph.type,omitempty"`
+	}
+	s, err := GenerateSchema(Node{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	expected := `
+  label: string @index(exact, term) @upsert .
+  edges: [uid] @reverse @count .
+  parent: uid .
+
+  type Node {
+    label: string
+    edges: [Section]
+    parent: Section
+  }
+`
+	if s != expected {
+		log.Fatalf("Wrong schema:\n%s", s)
+	}
+
+	type Conflict struct {
+		Title string `json:"title,omitempty"`
+	}
+	if _, err = GenerateSchema(Article{}, Conflict{}); err == nil || !strings.Contains(err.Error(), "already defined") {
+		log.Fatalf("A predicate defined twice differently should fail, got %v", err)
+	}
+	type Unknown struct {
+		Title string `json:"title,omitempty" dgraph:"unique"`
+	}
+	if _, err = GenerateSchema(Unknown{}); err == nil {
+		log.Fatal("An unknown directive should fail")
+	}
+}
@@ -1,12 +1,630 @@
 package main
 
 import (
+	"bufio"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"pandor/analytics"
+	"pandor/databases"
+	"pandor/embeddings"
+	"pandor/exports"
+	"pandor/fulltext"
+	"pandor/imports"
+	"pandor/latex"
 	"pandor/logger"
+	"pandor/migrations"
+	"pandor/models"
 	"pandor/scrappers"
+	"pandor/servers"
+	"pandor/storages"
+	"pandor/topics"
 )
 
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage: %s [flags] [command]
+
+Commands:
+  crawl    crawl arXiv (default)
+  retry    fetch again the pages which failed permanently
+  reparse  parse again the stored pages and update the articles
+  fulltext extract the full text from the LaTeX sources or the PDFs
+  search   search the articles, see search -h
+  embed    compute the embeddings of the articles
+  similar  list the articles similar to an arXiv ID or a text, see similar -h
+  export   export articles to BibTeX, RIS, CSL-JSON or JSON Lines, see export -h
+  network  export the citation or co-authorship network to GraphML, GEXF or CSV, see network -h
+  import   load a JSON Lines or Kaggle dump of articles, see import -h
+  analytics compute the citation metrics and the centralities of the articles, see analytics -h
+  communities detect the communities of coauthors and export them to CSV, see communities -h
+  topics   model the topics of the abstracts and report their prevalence by month, see topics -h
+  schema   migrate or generate the schema of the database, see schema -h
+  serve    serve the HTTP/JSON API and the GraphQL endpoint on /graphql, from -backend
+
+Flags:
+`, os.Args[0])
+	flag.PrintDefaults()
+}
+
 func main() {
+	flag.StringVar(&scrappers.UserAgent, "user-agent", scrappers.UserAgent,
+		"User-Agent sent to arXiv, it should contain a way to contact you")
+	flag.Float64Var(&scrappers.RequestsPerSecond, "rate", scrappers.RequestsPerSecond,
+		"maximum number of requests per second and per host")
+	flag.IntVar(&scrappers.Burst, "burst", scrappers.Burst,
+		"number of requests which may be sent back to back to a host")
+	flag.BoolVar(&scrappers.RespectRobotsTxt, "robots", scrappers.RespectRobotsTxt,
+		"honor the robots.txt of the crawled hosts")
+	flag.IntVar(&scrappers.MaxAttempts, "max-attempts", scrappers.MaxAttempts,
+		"number of times a page is fetched before being moved to the dead letters")
+	pdfDir := flag.String("pdf-dir", "", "download the PDFs to this directory")
+	s3Endpoint := flag.String("s3-endpoint", "", "download the PDFs to this S3-compatible API, "+
+		"the credentials are read from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	s3Bucket := flag.String("s3-bucket", "pandor", "bucket receiving the PDFs")
+	s3Region := flag.String("s3-region", "us-east-1", "region of the bucket")
+	sources := flag.Bool("sources", true, "prefer the LaTeX sources to the PDFs for the full text")
+	extractors := flag.String("extractors", "", "comma-separated PDF extractors to use among "+
+		"docconv and purego, all the available ones when empty")
+	embeddingsFile := flag.String("embeddings", filepath.Join(scrappers.TempDir, "embeddings.gob"),
+		"index of the embeddings of the articles")
+	flag.IntVar(&embeddings.Dims, "dims", embeddings.Dims, "size of the embeddings")
+	addr := flag.String("addr", ":8080", "address of the API server")
+	flag.StringVar(&databases.Backend, "backend", databases.Backend, "storage of the articles among "+
+		strings.Join(databases.Backends, ", ")+", only dgraph for reparse, fulltext, embed, analytics, "+
+		"communities, topics and schema")
+	flag.StringVar(&databases.SQLDriver, "sql-driver", databases.SQLDriver, "driver of the sql backend, sqlite or pgx for PostgreSQL")
+	flag.StringVar(&databases.SQLSource, "sql-source", databases.SQLSource, "database of the sql backend, "+
+		"a file for sqlite or a connection string for pgx")
+	flag.Usage = usage
+	flag.Parse()
+
+	switch {
+	case *s3Endpoint != "":
+		scrappers.PDFStorage = storages.NewS3(*s3Endpoint, *s3Bucket, *s3Region,
+			os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"))
+	case *pdfDir != "":
+		scrappers.PDFStorage = storages.NewLocal(*pdfDir)
+	}
+
 	logger.Logger = logger.InitLogger()
 	defer logger.Logger.Sync()
-	scrappers.LaunchArXiv()
+
+	known := false
+	for _, backend := range databases.Backends {
+		known = known || backend == databases.Backend
+	}
+	if !known {
+		logger.Logger.Fatal(fmt.Sprintf("Unknown backend %q, expected one of %s",
+			databases.Backend, strings.Join(databases.Backends, ", ")))
+	}
+
+	switch flag.Arg(0) {
+	case "", "crawl":
+		g, closeGraph := openGraph()
+		defer closeGraph()
+		scrappers.LaunchArXiv(g)
+	case "retry":
+		g, closeGraph := openGraph()
+		defer closeGraph()
+		scrappers.RetryDeadLetters(g)
+	case "reparse":
+		needDgraph("reparse")
+		scrappers.Reparse()
+	case "fulltext":
+		needDgraph("fulltext")
+		if scrappers.PDFStorage == nil {
+			logger.Logger.Fatal("fulltext needs the storage of the PDFs, set -pdf-dir or -s3-endpoint")
+		}
+		stage := fulltext.NewStage(scrappers.PDFStorage, filepath.Join(scrappers.TempDir, "fulltext"))
+		if *extractors != "" {
+			var err error
+			stage.Extractors, err = fulltext.SelectExtractors(strings.Split(*extractors, ",")...)
+			if err != nil {
+				logger.Logger.Fatal(err.Error())
+			}
+		}
+		if *sources {
+			limiter := scrappers.NewHostLimiter(scrappers.RequestsPerSecond, scrappers.Burst)
+			stage.Sources = scrappers.NewDownloader(scrappers.PDFStorage, limiter, latex.EPrintTypes...)
+		}
+		fulltext.Run(stage)
+	case "search":
+		search(flag.Args()[1:])
+	case "embed":
+		embed(*embeddingsFile)
+	case "similar":
+		similar(*embeddingsFile, flag.Args()[1:])
+	case "export":
+		export(flag.Args()[1:])
+	case "network":
+		network(flag.Args()[1:])
+	case "import":
+		importDump(flag.Args()[1:])
+	case "analytics":
+		runAnalytics(flag.Args()[1:])
+	case "communities":
+		communities(flag.Args()[1:])
+	case "topics":
+		modelTopics(flag.Args()[1:])
+	case "schema":
+		schema(flag.Args()[1:])
+	case "serve":
+		store, closeStore := openStore()
+		defer closeStore()
+		err := servers.Serve(*addr, store)
+		if err != nil {
+			logger.Logger.Fatal(err.Error())
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// openGraph opens the graph of -backend written by the crawler, once the
+// schema of Dgraph is checked
+func openGraph() (databases.Graph, func() error) {
+	g, closeGraph, err := databases.OpenGraph()
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	if dgraph, ok := g.(*databases.DgraphStore); ok {
+		err = migrations.Check(dgraph.DG)
+		if err != nil {
+			logger.Logger.Fatal(err.Error())
+		}
+	}
+	return g, closeGraph
+}
+
+// openStore opens the Store of -backend
+func openStore() (databases.Store, func() error) {
+	store, closeStore, err := databases.OpenStore()
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	return store, closeStore
+}
+
+// needDgraph stops the commands which only run on Dgraph when another
+// backend is selected
+func needDgraph(command string) {
+	if databases.Backend != databases.DgraphBackend {
+		logger.Logger.Fatal(fmt.Sprintf("%s needs the %s backend, not %s",
+			command, databases.DgraphBackend, databases.Backend))
+	}
+}
+
+// parseDates sets the date range of filters from the from and to flags
+func parseDates(from, to string, filters *databases.SearchFilters) {
+	var err error
+	if from != "" {
+		if filters.From, err = time.Parse("2006-01-02", from); err != nil {
+			logger.Logger.Fatal(err.Error())
+		}
+	}
+	if to != "" {
+		if filters.To, err = time.Parse("2006-01-02", to); err != nil {
+			logger.Logger.Fatal(err.Error())
+		}
+		// The whole day is included
+		filters.To = filters.To.Add(24*time.Hour - time.Nanosecond)
+	}
+}
+
+// search runs the search command, which prints the ranked articles
+func search(args []string) {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	from := fs.String("from", "", "only the articles submitted on or after this date, as 2008-01-31")
+	to := fs.String("to", "", "only the articles submitted on or before this date")
+	filters := databases.SearchFilters{}
+	fs.StringVar(&filters.Category, "category", "", "only the articles of this category, "+
+		"such as astro-ph.GA or astro-ph for all its subclasses")
+	fs.StringVar(&filters.Author, "author", "", "only the articles of this author, such as Lewis_G")
+	fs.IntVar(&filters.Limit, "n", 20, "number of results")
+	fs.IntVar(&filters.Offset, "offset", 0, "number of results to skip")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s search [flags] query\n\nFlags:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	parseDates(*from, *to, &filters)
+
+	store, closeStore := openStore()
+	defer closeStore()
+
+	results, err := store.Search(strings.Join(fs.Args(), " "), filters)
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	for _, r := range results {
+		fmt.Printf("%6.2f  %-16s %s  %s\n", r.Score, r.ArXivID,
+			r.SubmissionDate.Format("2006-01-02"), r.Title)
+	}
+}
+
+// embed runs the embed command, which indexes the embeddings of the articles
+func embed(path string) {
+	needDgraph("embed")
+	d, dg, err := databases.NewClient()
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	defer d.Close()
+
+	ix, err := embeddings.Build(embeddings.Dims, dg)
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	err = embeddings.Save(ix, path)
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	logger.Logger.Info(fmt.Sprintf("Embedded %d articles in %d dimensions to %s",
+		len(ix.IDs), ix.Model.Dims(), path))
+}
+
+// similar runs the similar command, which prints the nearest articles
+func similar(path string, args []string) {
+	fs := flag.NewFlagSet("similar", flag.ExitOnError)
+	n := fs.Int("n", 10, "number of results")
+	text := fs.Bool("text", false, "the arguments are a text instead of an arXiv ID")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s similar [flags] arxivid|text\n\nFlags:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	ix, err := embeddings.Load(path)
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	var neighbors []embeddings.Neighbor
+	if *text {
+		neighbors = ix.SimilarToText(strings.Join(fs.Args(), " "), *n)
+	} else {
+		neighbors, err = ix.Similar(fs.Arg(0), *n)
+		if err != nil {
+			logger.Logger.Fatal(err.Error())
+		}
+	}
+	for _, neighbor := range neighbors {
+		fmt.Printf("%.3f  %s\n", neighbor.Similarity, neighbor.ArXivID)
+	}
+}
+
+// export runs the export command, which writes the selected articles
+func export(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "bibtex", "output format among "+strings.Join(exports.FormatNames, ", "))
+	output := fs.String("o", "", "output file, the standard output when empty")
+	from := fs.String("from", "", "only the articles submitted on or after this date, as 2008-01-31")
+	to := fs.String("to", "", "only the articles submitted on or before this date")
+	selection := exports.Selection{}
+	fs.StringVar(&selection.Filters.Category, "category", "", "only the articles of this category, "+
+		"such as astro-ph.GA or astro-ph for all its subclasses")
+	fs.StringVar(&selection.Filters.Author, "author", "", "only the articles of this author, such as Lewis_G")
+	fs.StringVar(&selection.Around, "around", "", "only the articles citing or cited by this arXiv ID")
+	fs.IntVar(&selection.Depth, "depth", 1, "number of citations followed from -around")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s export [flags]\n\nFlags:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	parseDates(*from, *to, &selection.Filters)
+
+	f, err := exports.NewFormat(*format)
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	w, closeOutput := createOutput(*output)
+	defer closeOutput()
+
+	store, closeStore := openStore()
+	defer closeStore()
+
+	buffered := bufio.NewWriter(w)
+	count, err := exports.Export(store, selection, f, buffered)
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	logger.Logger.Info(fmt.Sprintf("Exported %d articles", count))
+}
+
+// createOutput opens the output file, the standard output when empty
+func createOutput(path string) (*os.File, func()) {
+	if path == "" {
+		return os.Stdout, func() {}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	return f, func() { f.Close() }
+}
+
+// network runs the network command, which writes a network of the graph
+func network(args []string) {
+	fs := flag.NewFlagSet("network", flag.ExitOnError)
+	name := fs.String("network", exports.CitationNetwork, "network among "+strings.Join(exports.NetworkNames, ", "))
+	format := fs.String("format", "graphml", "output format among "+strings.Join(exports.GraphFormatNames, ", "))
+	output := fs.String("o", "", "output file, the standard output when empty")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s network [flags]\n\nFlags:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	writer, err := exports.NewGraphWriter(*format)
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	w, closeOutput := createOutput(*output)
+	defer closeOutput()
+
+	store, closeStore := openStore()
+	defer closeStore()
+	source, ok := store.(exports.NetworkSource)
+	if !ok {
+		logger.Logger.Fatal(fmt.Sprintf("The %s backend cannot export networks", databases.Backend))
+	}
+
+	buffered := bufio.NewWriter(w)
+	nodes, edges, err := exports.ExportNetwork(source, *name, writer, buffered)
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	logger.Logger.Info(fmt.Sprintf("Exported the %s network, %d nodes and %d edges", *name, nodes, edges))
+}
+
+// importDump runs the import command, which loads a dump into -backend or
+// converts it to N-Quads
+func importDump(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	format := fs.String("format", imports.JSONLines, "input format among "+strings.Join(imports.FormatNames, ", "))
+	checkpoint := fs.String("checkpoint", "", "progress file of the import, the input followed by .checkpoint when empty")
+	nquads := fs.String("nquads", "", "write the articles as N-Quads to this file instead of loading them")
+	fs.IntVar(&imports.BatchSize, "batch", imports.BatchSize, "number of articles per transaction")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s import [flags] dump[.gz]\n\nFlags:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	path := fs.Arg(0)
+	if *checkpoint == "" {
+		*checkpoint = path + ".checkpoint"
+	}
+
+	var sink imports.Sink
+	var target string
+	if *nquads != "" {
+		target = "nquads " + *nquads
+		// A resumed import appends to the N-Quads already written
+		previous, err := imports.LoadCheckpoint(*checkpoint)
+		if err != nil {
+			logger.Logger.Fatal(err.Error())
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			logger.Logger.Fatal(err.Error())
+		}
+		mode := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		if previous.Resumes(path, info.Size(), target) {
+			mode = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		}
+		f, err := os.OpenFile(*nquads, mode, 0644)
+		if err != nil {
+			logger.Logger.Fatal(err.Error())
+		}
+		defer f.Close()
+		sink = &imports.NQuads{W: f}
+	} else if databases.Backend == databases.SQLBackend {
+		target = fmt.Sprintf("sql %s %s", databases.SQLDriver, databases.SQLSource)
+		store, err := databases.OpenSQLStore(databases.SQLDriver, databases.SQLSource)
+		if err != nil {
+			logger.Logger.Fatal(err.Error())
+		}
+		defer store.Close()
+		sink = store
+	} else {
+		d, dg, err := databases.NewClient()
+		if err != nil {
+			logger.Logger.Fatal(err.Error())
+		}
+		defer d.Close()
+		target = "dgraph"
+		sink = imports.NewLoader(dg)
+	}
+
+	result, err := imports.Import(path, *format, sink, target, *checkpoint)
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	logger.Logger.Info(fmt.Sprintf("Imported %d articles from %s, %d invalid records",
+		result.Articles, path, result.Invalid))
+}
+
+// runAnalytics runs the analytics command, which writes the citation
+// metrics and the centralities once or periodically
+func runAnalytics(args []string) {
+	fs := flag.NewFlagSet("analytics", flag.ExitOnError)
+	every := fs.Duration("every", 0, "compute the metrics again after this duration, such as 24h, only once when 0")
+	top := fs.Int("top", 10, "number of most cited articles printed")
+	fs.IntVar(&analytics.VelocityYears, "velocity-years", analytics.VelocityYears, "window of the citation velocity, in years")
+	fs.IntVar(&analytics.BetweennessSamples, "betweenness-samples", analytics.BetweennessSamples,
+		"number of sources of the betweenness approximation")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s analytics [flags]\n\nFlags:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	needDgraph("analytics")
+
+	d, dg, err := databases.NewClient()
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	defer d.Close()
+
+	if *every > 0 {
+		analytics.Schedule(*every, dg)
+		return
+	}
+	m, c, err := analytics.Run(dg)
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	fmt.Println("Most cited:")
+	for _, a := range m.MostCited(*top) {
+		fmt.Printf("%6d  %6.2f/year  %s\n", a.Citations, a.Velocity, a.ArXivID)
+	}
+	fmt.Println("Highest PageRank:")
+	n := float64(len(c.Graph.UIDs))
+	for _, i := range analytics.Top(c.PageRank, *top) {
+		fmt.Printf("%8.3f  %s\n", c.PageRank[i]*n, c.Graph.ArXivIDs[i])
+	}
+}
+
+// communities runs the communities command, which stores the communities of
+// coauthors and writes their members as CSV
+func communities(args []string) {
+	fs := flag.NewFlagSet("communities", flag.ExitOnError)
+	method := fs.String("method", analytics.Louvain, "detection method among "+strings.Join(analytics.CommunityMethods, ", "))
+	fs.IntVar(&analytics.MinCommunitySize, "min-size", analytics.MinCommunitySize, "size of the smallest stored community")
+	output := fs.String("o", "", "output file of the members, the standard output when empty")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s communities [flags]\n\nFlags:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	needDgraph("communities")
+
+	d, dg, err := databases.NewClient()
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	defer d.Close()
+
+	found, _, err := analytics.RunCommunities(*method, dg)
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	w, closeOutput := createOutput(*output)
+	defer closeOutput()
+	out := csv.NewWriter(w)
+	out.Write([]string{"community", "label", "size", "author"})
+	for i, c := range found {
+		for _, member := range c.Members {
+			out.Write([]string{fmt.Sprint(i), c.Label, fmt.Sprint(c.Size), member.Name})
+		}
+	}
+	out.Flush()
+	if err = out.Error(); err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+}
+
+// modelTopics runs the topics command, which stores the topics of the
+// articles and writes their prevalence by month as CSV
+func modelTopics(args []string) {
+	fs := flag.NewFlagSet("topics", flag.ExitOnError)
+	k := fs.Int("k", topics.Topics, "number of topics")
+	fs.IntVar(&topics.Iterations, "iterations", topics.Iterations, "number of iterations of the training")
+	fs.Float64Var(&topics.MinWeight, "min-weight", topics.MinWeight, "weight under which a topic is not linked to an article")
+	output := fs.String("o", "", "output file of the report, the standard output when empty")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s topics [flags]\n\nFlags:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	needDgraph("topics")
+
+	d, dg, err := databases.NewClient()
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	defer d.Close()
+
+	report, err := topics.Run(*k, dg)
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	w, closeOutput := createOutput(*output)
+	defer closeOutput()
+	out := csv.NewWriter(w)
+	out.WriteAll(report.Records())
+	if err = out.Error(); err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+}
+
+// schema runs the schema command, which migrates the schema of the database,
+// prints its version or prints the schema generated from the models
+func schema(args []string) {
+	fs := flag.NewFlagSet("schema", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only print the pending migrations and the schema changes")
+	to := fs.Int("to", migrations.Latest(), "version to migrate to")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s schema [flags] migrate|version|generate\n\nFlags:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	// The schema of the models does not need the database
+	if fs.Arg(0) == "generate" {
+		generated, err := models.GenerateSchema(models.Types...)
+		if err != nil {
+			logger.Logger.Fatal(err.Error())
+		}
+		fmt.Print(generated)
+		return
+	}
+	needDgraph("schema")
+
+	d, dg, err := databases.NewClient()
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	defer d.Close()
+
+	switch fs.Arg(0) {
+	case "migrate":
+		err = migrations.Migrate(*to, *dryRun, os.Stdout, dg)
+	case "version":
+		var version int
+		version, err = migrations.Version(dg)
+		if err == nil {
+			fmt.Printf("Schema version %d, latest %d\n", version, migrations.Latest())
+		}
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
 }
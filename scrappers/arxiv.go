@@ -1,16 +1,21 @@
 package scrappers
 
 import (
-	"encoding/json"
+	"bytes"
 	"fmt"
+	"net/http"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"pandor/archives"
 	"pandor/databases"
+	"pandor/downloads"
 	"pandor/logger"
 	"pandor/models"
+	"pandor/storages"
 
 	"github.com/gocolly/colly/v2"
 	"github.com/gocolly/colly/v2/queue"
@@ -23,52 +28,63 @@ var Domain = "https://export.arxiv.org"
 // TempDir is the directory to store temporary files
 var TempDir = "./tmp/"
 
+// ArchiveDir is the directory, inside TempDir, where the raw pages are kept
+var ArchiveDir = "archive"
+
+// PDFStorage is where the PDFs of the articles are downloaded. They are
+// not downloaded when it is nil.
+var PDFStorage storages.Storage
+
+// PDFKey returns the storage key of the PDF of an article
+func PDFKey(arXivID string) string {
+	return "pdf/" + arXivID + ".pdf"
+}
+
+// saveArticle resolves the UIDs of the authors and categories of a parsed
+// article and stores it
+func saveArticle(article models.Article, g databases.Graph) error {
+	for i := range article.Authors {
+		if article.Authors[i].Name == "" {
+			continue
+		}
+		uid, err := g.AuthorUID(article.Authors[i].Name)
+		if err == nil {
+			article.Authors[i].UID = uid
+		}
+	}
+	for i := range article.Categories {
+		uid, err := g.CategoryUID(article.Categories[i].Code)
+		if err == nil {
+			article.Categories[i].UID = uid
+		}
+	}
+	_, err := g.AddArticle(article)
+	return err
+}
+
 // ExtractNameFromURL extracts the name of an author from its URL
 func ExtractNameFromURL(url string) (string, error) {
-	re := regexp.MustCompile(`^.*\+`)
+	re := regexp.MustCompile(`^.*au:\+`)
 	if !re.MatchString(url) {
-		re = regexp.MustCompile(`javascript`)
-		if !re.MatchString(url) {
-			logger.Logger.Fatal(fmt.Sprintf("Prefix not Found: %s ", url))
-		}
 		return "", fmt.Errorf("Prefix not Found: %s ", url)
 	}
 	prefix := re.FindString(url)
+	name := strings.Replace(url, prefix, "", 1)
 	re = regexp.MustCompile(`/.*$`)
-	if !re.MatchString(url) {
-		re = regexp.MustCompile(`javascript`)
-		if !re.MatchString(url) {
-			logger.Logger.Fatal(fmt.Sprintf("Prefix not Found: %s ", url))
-		}
-		return "", fmt.Errorf("Prefix not Found: %s ", url)
+	suffix := re.FindString(name)
+	name = strings.Replace(name, suffix, "", 1)
+	if name == "" {
+		return "", fmt.Errorf("Name not Found: %s ", url)
 	}
-	url = strings.Replace(url, prefix, "", 1)
-	suffix := re.FindString(url)
-	url = strings.Replace(url, suffix, "", 1)
 
-	return url, nil
+	return name, nil
 }
 
-// LaunchArXiv creates an ArXiv web crawler and runs it
-func LaunchArXiv() {
+// LaunchArXiv creates an ArXiv web crawler and runs it, the articles are
+// stored in g
+func LaunchArXiv(g databases.Graph) {
 	url := Domain + "/abs/"
 
-	conn, dg, err := databases.NewClient()
-	if err != nil {
-		logger.Logger.Fatal(err.Error())
-	}
-	defer conn.Close()
-
-	// err = databases.DropAll(dg)
-	// if err != nil {
-	// 	logger.Logger.Error(err.Error())
-	// }
-
-	err = databases.LoadSchema(models.Schema, dg)
-	if err != nil {
-		logger.Logger.Fatal(err.Error())
-	}
-
 	// create a request queue with 2 consumer threads
 	q, err := queue.New(
 		4, // Number of consumer threads
@@ -78,118 +94,116 @@ func LaunchArXiv() {
 		logger.Logger.Fatal(fmt.Sprintf("can't initialize queue: %v", err))
 	}
 
+	c := newArXivCollector(g)
+
+	// Start scraping
+	for i := 8; i < 21; i++ {
+		for j := 1; j < 13; j++ {
+			// Add URLs to the queue
+			q.AddURL(fmt.Sprintf("%s%02d%02d.00001", url, i, j))
+		}
+	}
+	q.Run(c)
+	// Wait until threads are finished
+	c.Wait()
+}
+
+// RetryDeadLetters fetches again the pages which failed permanently. A
+// dead letter is only removed once its page is scraped, the pages failing
+// again replace theirs, so that an interrupted retry loses none. Each page
+// gets MaxAttempts more attempts, added to the recorded ones.
+func RetryDeadLetters(g databases.Graph) {
+	dls, err := LoadDeadLetters()
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	logger.Logger.Info(fmt.Sprintf("Retrying %d dead letters", len(dls)))
+
+	c := newArXivCollector(g)
+	for _, dl := range dls {
+		// Only the failed page is fetched, not the articles following it
+		ctx := colly.NewContext()
+		ctx.Put("nofollow", true)
+		ctx.Put("previous:"+dl.URL, dl.Attempts)
+		err = c.Request("GET", dl.URL, nil, ctx, nil)
+		if err != nil {
+			logger.Logger.Error(fmt.Sprintf("Retry of %s failed: %v", dl.URL, err))
+			err = AddDeadLetter(NewDeadLetter(dl.URL, 0, err, dl.Attempts))
+			if err != nil {
+				logger.Logger.Error(err.Error())
+			}
+		}
+	}
+	c.Wait()
+}
+
+// newArXivCollector builds the collector parsing arXiv abstract pages into
+// g
+func newArXivCollector(g databases.Graph) *colly.Collector {
 	// Instantiate default collector
 	c := colly.NewCollector(
 		// colly.Debugger(&debug.LogDebugger{}),
 		// Visit only domains: export.arxiv.org
 		colly.AllowedDomains("export.arxiv.org"),
+		colly.UserAgent(UserAgent),
 		colly.Async(true),
 	)
+	c.IgnoreRobotsTxt = !RespectRobotsTxt
 
-	// Limit the maximum parallelism to 2
-	// This is necessary if the goroutines are dynamically
-	// created to control the limit of simultaneous requests.
-	//
-	// Parallelism can be controlled also by spawning fixed
-	// number of go routines.
+	// Keep a single connection per host, the pace of the requests
+	// is set by the limiter in OnRequest
 	c.Limit(&colly.LimitRule{
 		DomainGlob:  "*",
-		RandomDelay: 1 * time.Second,
-		Parallelism: 8,
+		Parallelism: 1,
 	})
+	limiter := NewHostLimiter(RequestsPerSecond, Burst)
+	archive := archives.New(filepath.Join(TempDir, ArchiveDir))
 
-	c.OnHTML(`div[id=abs]`, func(e *colly.HTMLElement) {
+	var downloader *downloads.Manager
+	if PDFStorage != nil {
+		downloader = NewDownloader(PDFStorage, limiter, "application/pdf")
+	}
 
-		conn, dg, err := databases.NewClient()
+	c.OnHTML(`div[id=abs]`, func(e *colly.HTMLElement) {
+		article, err := ParseAbstractPage(bytes.NewReader(e.Response.Body), e.Request.URL.String())
 		if err != nil {
 			logger.Logger.Error(err.Error())
+			return
 		}
-		defer conn.Close()
-
-		article := models.Article{}
+		article.CrawledAt = time.Now().UTC()
 
-		article.HTMLResponse = string(e.Response.Body)
-
-		article.MetaURL = e.Request.URL.String()
-		re := regexp.MustCompile(`\d{4}.(\d{5}|\d{4})`)
-		if !re.MatchString(article.MetaURL) {
-			logger.Logger.Error(fmt.Sprintf("No ID Matched for %s", article.MetaURL))
-		}
-		article.ArXivID = re.FindString(article.MetaURL)
-
-		crawlingTime := time.Now().UTC()
-		article.CrawledAt = crawlingTime
-
-		article.Title = strings.SplitAfterN(e.ChildText(`h1.title`), "\n", 2)[1]
-		article.UID = models.FormatUID(article.Title)
-		article.DType = []string{"Article"}
-
-		article.Abstract = strings.SplitAfterN(
-			e.ChildText(`blockquote.abstract`),
-			" ",
-			2)[1]
-
-		// Authors
-		authorsURL := e.ChildAttrs(`div.authors a`, `href`)
-		article.Authors = make([]models.Author, len(authorsURL))
-		for author := 0; author < len(authorsURL); author++ {
-			authorURL := authorsURL[author]
-			name, err := ExtractNameFromURL(authorURL)
-			if err != nil {
-				continue
-			}
-			uid, err := databases.GetAuthorUID(name, dg)
-			if err != nil {
-				article.Authors[author].UID = models.FormatUID(name)
-			} else {
-				article.Authors[author].UID = uid
-			}
-			article.Authors[author].URL = Domain + authorURL
-			article.Authors[author].Name = name
-			article.Authors[author].DType = []string{"Author"}
+		// The raw page is kept outside of Dgraph, only its reference is stored
+		article.ArchiveRef, err = archive.Put(e.Response.Body)
+		if err != nil {
+			logger.Logger.Error(fmt.Sprintf("Archive Error: %v", err))
 		}
 
-		// SubmissionDate
-		SubmissionDateStr := e.ChildText(`div.dateline`)
-		re = regexp.MustCompile(`\d{2}\s\w{3}\s\d{4}`)
-		var SubmissionDateT time.Time
-		if re.MatchString(SubmissionDateStr) {
-			SubmissionDateStrFmted := re.FindString(SubmissionDateStr)
-			SubmissionDateT, err = time.Parse("2 Jan 2006", SubmissionDateStrFmted)
-			if err == nil {
-				article.SubmissionDate = SubmissionDateT
+		if downloader != nil && article.PDFURL != "" {
+			res, err := downloader.Download(article.PDFURL, PDFKey(article.ArXivID))
+			if err != nil {
+				logger.Logger.Error(fmt.Sprintf("FILE Error: %v", err))
 			} else {
-				logger.Logger.Error(fmt.Sprintf("SubmissionDate Parsing Error: %v", err))
+				article.PDFKey = res.Key
+				article.PDFChecksum = "sha256:" + res.SHA256
 			}
 		}
 
-		// Article Links
-		if attr, ok := e.DOM.Find(`div.full-text li a`).First().Attr(`href`); ok {
-			article.PDFURL = Domain + attr
-		}
-		// re = regexp.MustCompile(`\d{4}.((\d{5}$)|(\d{4}$))`)
-		// filePath := re.FindString(article.PDFURL) + ".pdf"
-		// err = utils.DownloadAndSaveToDir(article.PDFURL, filePath, TempDir)
-		// if err != nil {
-		// 	logger.Logger.Error(fmt.Sprintf("FILE Error: %v", err))
-		// }
-
-		if attr, ok := e.DOM.Find(`div.full-text li a`).Last().Attr(`href`); ok {
-			article.OtherFormatURL = Domain + attr
-		}
-
-		_, err = databases.AddArticle(article, dg)
+		err = saveArticle(article, g)
 		if err != nil {
 			logger.Logger.Error(err.Error())
 		}
 
+		lastAuthor := ""
+		if len(article.Authors) > 0 {
+			lastAuthor = article.Authors[len(article.Authors)-1].Name
+		}
 		logger.Logger.Debug("New Article",
 			zap.String("URL:", article.MetaURL),
 			zap.Time("CrawledAt:", article.CrawledAt),
 			zap.String("Title:", article.Title),
 			zap.String("Abstract:", article.Abstract),
 			zap.Int("Nb Authors:", len(article.Authors)),
-			zap.String("Last Author:", article.Authors[len(article.Authors)-1].Name),
+			zap.String("Last Author:", lastAuthor),
 			zap.Time("Submission Date:", article.SubmissionDate),
 			zap.String("PDF:", article.PDFURL),
 			zap.String("Format:", article.OtherFormatURL),
@@ -198,6 +212,7 @@ func LaunchArXiv() {
 
 	// Before making a request print "Visiting ..."
 	c.OnRequest(func(r *colly.Request) {
+		limiter.Wait(r.URL.Host)
 		r.Ctx.Put("url", r.URL.String())
 		logger.Logger.Info(fmt.Sprintf("Visiting %s", r.URL.String()))
 	})
@@ -206,17 +221,53 @@ func LaunchArXiv() {
 		logger.Logger.Error(fmt.Sprintf("Request URL: %s failed with response: %v", r.Request.URL, r),
 			zap.String("Error:", fmt.Sprintf("%v", err)),
 		)
+		URL := r.Request.URL.String()
+		// The context is shared with the requests visited from this one
+		key := "attempt:" + URL
+		attempt, _ := r.Ctx.GetAny(key).(int)
+		attempt++
+		if !Retryable(r.StatusCode) || attempt >= MaxAttempts {
+			// A retried dead letter keeps the count of its earlier attempts
+			previous, _ := r.Ctx.GetAny("previous:" + URL).(int)
+			logger.Logger.Error(fmt.Sprintf("Giving up on %s after %d attempts", URL, previous+attempt))
+			err = AddDeadLetter(NewDeadLetter(URL, r.StatusCode, err, previous+attempt))
+			if err != nil {
+				logger.Logger.Error(err.Error())
+			}
+			return
+		}
+		r.Ctx.Put(key, attempt)
+
+		if ThrottlingStatus(r.StatusCode) {
+			var header http.Header
+			if r.Headers != nil {
+				header = *r.Headers
+			}
+			delay := limiter.Backoff(r.Request.URL.Host, header, attempt-1)
+			logger.Logger.Warn(fmt.Sprintf("Throttled by %s, pausing for %v", r.Request.URL.Host, delay),
+				zap.Int("Status:", r.StatusCode),
+			)
+		} else {
+			delay := RetryDelay(attempt)
+			logger.Logger.Warn(fmt.Sprintf("Retrying %s in %v", URL, delay),
+				zap.Int("Attempt:", attempt),
+			)
+			time.Sleep(delay)
+		}
+		if err := r.Request.Retry(); err != nil {
+			logger.Logger.Error(fmt.Sprintf("Retry of %s failed: %v", URL, err))
+		}
 	})
 	// Called after OnHTML
 	c.OnScraped(func(r *colly.Response) {
-
-		conn, dg, err := databases.NewClient()
-		if err != nil {
-			logger.Logger.Error(err.Error())
-		}
-		defer conn.Close()
-
 		logger.Logger.Info(fmt.Sprintf("Finished %s", r.Request.URL))
+		if nofollow, _ := r.Ctx.GetAny("nofollow").(bool); nofollow {
+			// A retried dead letter succeeded
+			if err := RemoveDeadLetter(r.Request.URL.String()); err != nil {
+				logger.Logger.Error(err.Error())
+			}
+			return
+		}
 		URL := r.Request.URL.String()
 
 		Base := regexp.MustCompile(`.*\d{4}\.`)
@@ -242,43 +293,15 @@ func LaunchArXiv() {
 			logger.Logger.Error(fmt.Sprintf("Error: %v", err))
 		}
 
-		type Root struct {
-			Articles []models.Article `json:"getuid"`
+		// Skip the articles already crawled, numbered on 5 or 4 digits. The
+		// cited papers stored with their ID only are crawled.
+		stored := func(arXivID string) bool {
+			_, err := g.CrawledArticleUID(arXivID)
+			return err == nil
 		}
-		var root Root
-
 		for {
 			ArticleNumber++
-			variables := map[string]string{"$id": fmt.Sprintf("%s%05d", URLDate, ArticleNumber)}
-			query := `query GetUID($id: string){
-									getuid(func: eq(arxivid, $id)){
-										uid
-								  }
-								}`
-			resp, err := databases.QueryWithVars(query, variables, dg)
-			if err != nil {
-				logger.Logger.Fatal(err.Error())
-			}
-			err = json.Unmarshal(resp.Json, &root)
-			if err != nil {
-				logger.Logger.Fatal(err.Error())
-			}
-
-			if len(root.Articles) != 0 {
-				continue
-			}
-
-			variables = map[string]string{"$id": fmt.Sprintf("%s%04d", URLDate, ArticleNumber)}
-			resp, err = databases.QueryWithVars(query, variables, dg)
-			if err != nil {
-				logger.Logger.Fatal(err.Error())
-			}
-			err = json.Unmarshal(resp.Json, &root)
-			if err != nil {
-				logger.Logger.Fatal(err.Error())
-			}
-
-			if len(root.Articles) == 0 {
+			if !stored(fmt.Sprintf("%s%05d", URLDate, ArticleNumber)) && !stored(fmt.Sprintf("%s%04d", URLDate, ArticleNumber)) {
 				break
 			}
 		}
@@ -289,14 +312,5 @@ func LaunchArXiv() {
 		}
 	})
 
-	// Start scraping
-	for i := 8; i < 21; i++ {
-		for j := 1; j < 13; j++ {
-			// Add URLs to the queue
-			q.AddURL(fmt.Sprintf("%s%02d%02d.00001", url, i, j))
-		}
-	}
-	q.Run(c)
-	// Wait until threads are finished
-	c.Wait()
+	return c
 }
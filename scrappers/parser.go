@@ -0,0 +1,136 @@
+package scrappers
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"pandor/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// arXivIDPattern matches both the current identifiers (0801.0001,
+// 1501.00001) and the old ones (hep-th/9901001, math.AG/0101001)
+var arXivIDPattern = regexp.MustCompile(`\d{4}\.\d{4,5}|[a-z\-]+(\.[A-Z]{2})?/\d{7}`)
+
+// datePattern matches the dates of the dateline, such as "3 Jan 2008"
+var datePattern = regexp.MustCompile(`\d{1,2}\s\w{3}\s\d{4}`)
+
+// subjectPattern matches a subject of the metadata, such as
+// "Astrophysics of Galaxies (astro-ph.GA)"
+var subjectPattern = regexp.MustCompile(`^(.*?)\s*\(([a-zA-Z\-]+(?:\.[A-Za-z\-]+)?)\)$`)
+
+// ExtractArXivID returns the identifier contained in a URL, without version
+func ExtractArXivID(url string) (string, error) {
+	id := arXivIDPattern.FindString(url)
+	if id == "" {
+		return "", fmt.Errorf("No ID Matched for %s", url)
+	}
+	return id, nil
+}
+
+// ParseAbstractPage extracts an Article from an arXiv abstract page. It
+// does not access the network nor the database: the UIDs of the authors
+// are left to the caller, as well as the crawling metadata.
+func ParseAbstractPage(r io.Reader, url string) (models.Article, error) {
+	article := models.Article{}
+
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return article, err
+	}
+	abs := doc.Find(`div[id=abs]`).First()
+	if abs.Length() == 0 {
+		return article, fmt.Errorf("No abstract found in %s", url)
+	}
+	// descriptorText drops the "Title:" or "Abstract:" label of a field
+	descriptorText := func(selector string) string {
+		s := abs.Find(selector).First().Clone()
+		s.Find(`span.descriptor`).Remove()
+		return strings.TrimSpace(s.Text())
+	}
+
+	// The identifier declared by the page is preferred to the one of the
+	// URL, which may be padded with a leading zero
+	article.MetaURL = url
+	if id, ok := doc.Find(`meta[name=citation_arxiv_id]`).Attr(`content`); ok && id != "" {
+		article.ArXivID = id
+	} else {
+		article.ArXivID, err = ExtractArXivID(url)
+		if err != nil {
+			return article, err
+		}
+	}
+
+	article.Title = strings.Join(strings.Fields(descriptorText(`h1.title`)), " ")
+	if article.Title == "" {
+		return article, fmt.Errorf("No title found in %s", url)
+	}
+	article.UID = models.FormatUID(article.Title)
+	article.DType = []string{"Article"}
+
+	article.Abstract = descriptorText(`blockquote.abstract`)
+
+	// Authors
+	abs.Find(`div.authors a`).Each(func(_ int, s *goquery.Selection) {
+		href, ok := s.Attr(`href`)
+		if !ok || strings.HasPrefix(href, "javascript") {
+			return
+		}
+		name, err := ExtractNameFromURL(href)
+		if err != nil {
+			// Links which do not embed the name, fall back on the text
+			name = strings.TrimSpace(s.Text())
+		}
+		if name == "" {
+			return
+		}
+		if !strings.HasPrefix(href, "http") {
+			href = Domain + href
+		}
+		article.Authors = append(article.Authors, models.Author{
+			UID:   models.FormatUID(name),
+			Name:  name,
+			URL:   href,
+			DType: []string{"Author"},
+		})
+	})
+
+	// Categories, the primary one first
+	for _, subject := range strings.Split(abs.Find(`td.subjects`).Text(), ";") {
+		match := subjectPattern.FindStringSubmatch(strings.TrimSpace(subject))
+		if match == nil {
+			continue
+		}
+		article.Categories = append(article.Categories, models.Category{
+			UID:   models.FormatUID(match[2]),
+			Code:  match[2],
+			Name:  match[1],
+			DType: []string{"Category"},
+		})
+	}
+
+	// SubmissionDate is the date of the first version
+	if date := datePattern.FindString(abs.Find(`div.dateline`).Text()); date != "" {
+		article.SubmissionDate, err = time.Parse("2 Jan 2006", date)
+		if err != nil {
+			return article, fmt.Errorf("SubmissionDate Parsing Error: %v", err)
+		}
+	}
+
+	// Article Links, absent for withdrawn articles
+	doc.Find(`div.full-text li a`).Each(func(_ int, s *goquery.Selection) {
+		href, _ := s.Attr(`href`)
+		switch {
+		case article.PDFURL == "" && strings.HasPrefix(href, "/pdf/"):
+			article.PDFURL = Domain + href
+		case article.OtherFormatURL == "" && strings.HasPrefix(href, "/format/"):
+			article.OtherFormatURL = Domain + href
+		}
+	})
+
+	return article, nil
+}
@@ -0,0 +1,76 @@
+package scrappers
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// Each fixture is the abstract page of an article, the URL it was crawled
+// from and the expected Article in a golden JSON file
+var fixtures = map[string]string{
+	"0801.0002":      "https://export.arxiv.org/abs/0801.00002",
+	"0801.0010":      "https://export.arxiv.org/abs/0801.00010",
+	"0802.0100":      "https://export.arxiv.org/abs/0802.0100",
+	"hep-th_9901001": "https://export.arxiv.org/abs/hep-th/9901001",
+	"1501.00001":     "https://export.arxiv.org/abs/1501.00001",
+}
+
+func TestParseAbstractPage(t *testing.T) {
+	for name, url := range fixtures {
+		f, err := os.Open(filepath.Join("testdata", "abs", name+".html"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		article, err := ParseAbstractPage(f, url)
+		f.Close()
+		if err != nil {
+			log.Fatalf("%s: %v", name, err)
+		}
+
+		got, err := json.MarshalIndent(article, "", "\t")
+		if err != nil {
+			log.Fatal(err)
+		}
+		golden := filepath.Join("testdata", "abs", name+".json")
+		if *update {
+			err = ioutil.WriteFile(golden, append(got, '\n'), 0644)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if strings.TrimSpace(string(got)) != strings.TrimSpace(string(want)) {
+			log.Fatalf("%s: wrong article\n%s\ninstead of\n%s", name, got, want)
+		}
+	}
+}
+
+func TestParseAbstractPageErrors(t *testing.T) {
+	cases := []struct {
+		name, page, url, err string
+	}{
+		{"no abstract", `<html><body><h1>Article not found</h1></body></html>`,
+			"https://export.arxiv.org/abs/0801.0001", "No abstract found"},
+		{"no title", `<html><body><div id="abs"><h1 class="title"></h1></div></body></html>`,
+			"https://export.arxiv.org/abs/0801.0001", "No title found"},
+		{"no ID", `<html><body><div id="abs"><h1 class="title">Title</h1></div></body></html>`,
+			"https://export.arxiv.org/abs/", "No ID Matched"},
+	}
+	for _, c := range cases {
+		_, err := ParseAbstractPage(strings.NewReader(c.page), c.url)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			log.Fatalf("%s: got error %v instead of %q", c.name, err, c.err)
+		}
+	}
+}
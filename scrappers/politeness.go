@@ -0,0 +1,216 @@
+package scrappers
+
+import (
+	"math"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"pandor/downloads"
+	"pandor/storages"
+)
+
+// UserAgent is sent with every request. arXiv asks crawlers to identify
+// themselves with a way to contact their operator.
+var UserAgent = "pandor/0.1 (+https://github.com/thibaultdalmon/pandor)"
+
+// RequestsPerSecond is the sustained request rate allowed per host.
+// arXiv asks for no more than one request every three seconds.
+var RequestsPerSecond = 1.0 / 3.0
+
+// Burst is the number of requests that may be sent back to back to a host
+var Burst = 1
+
+// RespectRobotsTxt makes the collector honor the robots.txt of each host
+var RespectRobotsTxt = true
+
+// MaxBackoff bounds the pause applied to a host after a throttling response
+var MaxBackoff = 10 * time.Minute
+
+// ThrottlingStatus reports whether a status code means the host asks us to
+// slow down
+func ThrottlingStatus(status int) bool {
+	switch status {
+	case http.StatusForbidden, http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	}
+	return false
+}
+
+// ParseRetryAfter parses the value of a Retry-After header, given either as
+// a number of seconds or as an HTTP date
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if date.Before(now) {
+		return 0, true
+	}
+	return date.Sub(now), true
+}
+
+// TokenBucket is a token-bucket rate limiter which can also be paused
+type TokenBucket struct {
+	mu          sync.Mutex
+	rate        float64
+	capacity    float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+	now         func() time.Time
+}
+
+// NewTokenBucket builds a full bucket refilled at rate tokens per second
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:     rate,
+		capacity: float64(burst),
+		tokens:   float64(burst),
+		now:      time.Now,
+	}
+}
+
+// Reserve takes a token and returns how long the caller has to wait before
+// using it
+func (b *TokenBucket) Reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if !b.last.IsZero() && b.rate > 0 {
+		b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+	b.tokens--
+
+	var wait time.Duration
+	if b.tokens < 0 {
+		if b.rate <= 0 {
+			b.tokens = 0
+		} else {
+			wait = time.Duration(-b.tokens / b.rate * float64(time.Second))
+		}
+	}
+	if pause := b.pausedUntil.Sub(now); pause > wait {
+		wait = pause
+	}
+	return wait
+}
+
+// Wait blocks until a token is available
+func (b *TokenBucket) Wait() {
+	if wait := b.Reserve(); wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+// PauseFor prevents any token from being used before d has elapsed
+func (b *TokenBucket) PauseFor(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	until := b.now().Add(d)
+	if until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+}
+
+// HostLimiter keeps one TokenBucket per host
+type HostLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   int
+	buckets map[string]*TokenBucket
+}
+
+// NewHostLimiter builds a HostLimiter allowing rate requests per second
+// to every host
+func NewHostLimiter(rate float64, burst int) *HostLimiter {
+	return &HostLimiter{
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[string]*TokenBucket),
+	}
+}
+
+// Bucket returns the TokenBucket of a host
+func (l *HostLimiter) Bucket(host string) *TokenBucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[host]
+	if !ok {
+		b = NewTokenBucket(l.rate, l.burst)
+		l.buckets[host] = b
+	}
+	return b
+}
+
+// Wait blocks until a request to host is allowed
+func (l *HostLimiter) Wait(host string) {
+	l.Bucket(host).Wait()
+}
+
+// Backoff pauses a host after a throttling response. The Retry-After
+// header is used when present, otherwise the pause doubles with every
+// attempt, starting from the refill interval of the bucket.
+func (l *HostLimiter) Backoff(host string, header http.Header, attempt int) time.Duration {
+	delay, ok := time.Duration(0), false
+	if header != nil {
+		delay, ok = ParseRetryAfter(header.Get("Retry-After"), time.Now())
+	}
+	if !ok {
+		base := time.Second
+		if l.rate > 0 {
+			base = time.Duration(float64(time.Second) / l.rate)
+		}
+		delay = base
+		for i := 0; i < attempt && delay < MaxBackoff; i++ {
+			delay *= 2
+		}
+	}
+	if delay > MaxBackoff {
+		delay = MaxBackoff
+	}
+	l.Bucket(host).PauseFor(delay)
+	return delay
+}
+
+// politeTransport makes the requests sent outside of the collector, such
+// as the PDF downloads, wait for the limiter of their host
+type politeTransport struct {
+	limiter *HostLimiter
+	base    http.RoundTripper
+}
+
+func (t politeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.limiter.Wait(req.URL.Host)
+	return t.base.RoundTrip(req)
+}
+
+// NewDownloader builds a download manager sharing the limiter of the
+// crawler and identified by UserAgent
+func NewDownloader(storage storages.Storage, limiter *HostLimiter, contentTypes ...string) *downloads.Manager {
+	m := downloads.NewManager(storage, filepath.Join(TempDir, "downloads"))
+	m.Client.Transport = politeTransport{limiter: limiter, base: http.DefaultTransport}
+	m.UserAgent = UserAgent
+	m.ContentTypes = contentTypes
+	return m
+}
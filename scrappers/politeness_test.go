@@ -0,0 +1,63 @@
+package scrappers
+
+import (
+	"log"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2020, 3, 9, 8, 0, 0, 0, time.UTC)
+
+	d, ok := ParseRetryAfter("120", now)
+	if !ok || d != 2*time.Minute {
+		log.Fatalf("Wrong delay: %v instead of 2m0s", d)
+	}
+
+	d, ok = ParseRetryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now)
+	if !ok || d != 30*time.Second {
+		log.Fatalf("Wrong delay: %v instead of 30s", d)
+	}
+
+	if _, ok = ParseRetryAfter("soon", now); ok {
+		log.Fatal("An invalid Retry-After has been parsed")
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Date(2020, 3, 9, 8, 0, 0, 0, time.UTC)
+	b := NewTokenBucket(0.5, 2)
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if wait := b.Reserve(); wait != 0 {
+			log.Fatalf("Request %d of the burst waits %v", i, wait)
+		}
+	}
+	if wait := b.Reserve(); wait != 2*time.Second {
+		log.Fatalf("Wrong wait: %v instead of 2s", wait)
+	}
+
+	now = now.Add(10 * time.Second)
+	b.PauseFor(time.Minute)
+	if wait := b.Reserve(); wait != time.Minute {
+		log.Fatalf("Wrong wait: %v instead of 1m0s", wait)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	l := NewHostLimiter(1, 1)
+
+	header := http.Header{}
+	header.Set("Retry-After", "7")
+	if d := l.Backoff("export.arxiv.org", header, 3); d != 7*time.Second {
+		log.Fatalf("Retry-After ignored: %v instead of 7s", d)
+	}
+	if d := l.Backoff("export.arxiv.org", nil, 3); d != 8*time.Second {
+		log.Fatalf("Wrong backoff: %v instead of 8s", d)
+	}
+	if d := l.Backoff("export.arxiv.org", nil, 100); d != MaxBackoff {
+		log.Fatalf("Backoff not bounded: %v", d)
+	}
+}
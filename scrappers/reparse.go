@@ -0,0 +1,119 @@
+package scrappers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"pandor/archives"
+	"pandor/databases"
+	"pandor/logger"
+	"pandor/models"
+)
+
+// ReparsePageSize is the number of articles loaded at once by Reparse
+var ReparsePageSize = 100
+
+// storedPage returns the raw page of an article, from the archive or from
+// the legacy htmlresponse predicate
+func storedPage(article models.Article, archive *archives.Archive) ([]byte, error) {
+	if article.ArchiveRef != "" {
+		return archive.Get(article.ArchiveRef)
+	}
+	if article.HTMLResponse != "" {
+		return []byte(article.HTMLResponse), nil
+	}
+	return nil, fmt.Errorf("No stored page for %s", article.ArXivID)
+}
+
+// ReparseArticle parses again the stored page of an article and updates it.
+// Pages still stored in htmlresponse are moved to the archive.
+func ReparseArticle(stored models.Article, archive *archives.Archive, g databases.Graph) error {
+	body, err := storedPage(stored, archive)
+	if err != nil {
+		return err
+	}
+	article, err := ParseAbstractPage(bytes.NewReader(body), stored.MetaURL)
+	if err != nil {
+		return err
+	}
+	article.UID = stored.UID
+	article.CrawledAt = stored.CrawledAt
+	article.ArchiveRef, err = archive.Put(body)
+	if err != nil {
+		return err
+	}
+
+	// Edges are sets in Dgraph, the stale ones have to be removed first
+	predicates := []string{"authors", "categories"}
+	if stored.HTMLResponse != "" {
+		predicates = append(predicates, "htmlresponse")
+	}
+	err = g.DeleteEdges(stored.UID, predicates)
+	if err != nil {
+		return err
+	}
+	return saveArticle(article, g)
+}
+
+// Reparse runs the parser on every stored page and updates the articles,
+// without fetching anything from arXiv
+func Reparse() {
+	conn, dg, err := databases.NewClient()
+	if err != nil {
+		logger.Logger.Fatal(err.Error())
+	}
+	defer conn.Close()
+
+	archive := archives.New(filepath.Join(TempDir, ArchiveDir))
+	g := databases.NewDgraphStore(dg)
+
+	type Root struct {
+		Articles []models.Article `json:"articles"`
+	}
+
+	query := `query Reparse($first: int, $after: string){
+		articles(func: type(Article), first: $first, after: $after)
+			@filter(has(archiveref) OR has(htmlresponse)){
+			uid
+			arxivid
+			metaurl
+			crawledat
+			archiveref
+			htmlresponse
+		}
+	}`
+	after := "0x0"
+	count, failed := 0, 0
+	for {
+		variables := map[string]string{
+			"$first": fmt.Sprintf("%d", ReparsePageSize),
+			"$after": after,
+		}
+		resp, err := databases.QueryWithVars(query, variables, dg)
+		if err != nil {
+			logger.Logger.Fatal(err.Error())
+		}
+		var root Root
+		err = json.Unmarshal(resp.Json, &root)
+		if err != nil {
+			logger.Logger.Fatal(err.Error())
+		}
+		if len(root.Articles) == 0 {
+			break
+		}
+
+		for _, stored := range root.Articles {
+			err = ReparseArticle(stored, archive, g)
+			if err != nil {
+				failed++
+				logger.Logger.Error(fmt.Sprintf("Reparse of %s failed: %v", stored.ArXivID, err))
+				continue
+			}
+			count++
+		}
+		after = root.Articles[len(root.Articles)-1].UID
+	}
+	logger.Logger.Info(fmt.Sprintf("Reparsed %d articles, %d failures", count, failed))
+}
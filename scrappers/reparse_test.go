@@ -0,0 +1,63 @@
+package scrappers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"pandor/archives"
+	"pandor/databases"
+	"pandor/models"
+)
+
+func TestReparseArticle(t *testing.T) {
+	page, err := ioutil.ReadFile(filepath.Join("testdata", "abs", "0801.0002.html"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	archive := archives.New(dir)
+
+	// An article crawled before the archive, with a stale author
+	g := databases.NewMemoryStore()
+	uid, err := g.AddArticle(models.Article{
+		ArXivID:      "0801.0002",
+		Title:        "Globular clusters in the outer halo of M31: the survey",
+		MetaURL:      fixtures["0801.0002"],
+		HTMLResponse: string(page),
+		Authors:      []models.Author{{Name: "Nobody"}},
+		DType:        []string{"Article"},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	stored := models.Article{UID: uid, ArXivID: "0801.0002", MetaURL: fixtures["0801.0002"], HTMLResponse: string(page)}
+
+	// Reparsing twice gives the same authors
+	for i := 0; i < 2; i++ {
+		err = ReparseArticle(stored, archive, g)
+		if err != nil {
+			log.Fatal(err)
+		}
+		stored.HTMLResponse = ""
+		stored.ArchiveRef = archives.Ref(page)
+	}
+	parsed, err := ParseAbstractPage(bytes.NewReader(page), fixtures["0801.0002"])
+	if err != nil {
+		log.Fatal(err)
+	}
+	article, err := g.Article("0801.0002")
+	if err != nil || article.UID != uid || len(article.Authors) != len(parsed.Authors) {
+		log.Fatalf("Wrong reparsed article %+v %v", article, err)
+	}
+	if stale, err := g.AuthorArticles("Nobody", 0, 10); err != nil || len(stale) != 0 {
+		log.Fatalf("The stale author should be removed, got %v %v", stale, err)
+	}
+}
@@ -0,0 +1,166 @@
+package scrappers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"pandor/utils"
+)
+
+// MaxAttempts is the number of times a page is fetched before it is
+// moved to the dead letters
+var MaxAttempts = 5
+
+// RetryBaseDelay is the delay before the first retry of a failed fetch
+var RetryBaseDelay = 2 * time.Second
+
+// RetryMaxDelay bounds the delay between two attempts
+var RetryMaxDelay = 5 * time.Minute
+
+// DeadLetterFile lists the pages which failed permanently
+var DeadLetterFile = "deadletters.json"
+
+// Retryable reports whether a failed fetch may succeed later. Client
+// errors such as 404 are permanent, while server errors, timeouts and
+// network errors (status 0) are transient.
+func Retryable(status int) bool {
+	switch {
+	case status == 0:
+		return true
+	case ThrottlingStatus(status):
+		return true
+	case status == http.StatusRequestTimeout:
+		return true
+	case status >= 500:
+		return status != http.StatusNotImplemented
+	}
+	return false
+}
+
+// RetryDelay returns the delay before a given attempt, growing
+// exponentially with full jitter
+func RetryDelay(attempt int) time.Duration {
+	delay := RetryBaseDelay
+	for i := 1; i < attempt && delay < RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > RetryMaxDelay {
+		delay = RetryMaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay))) + 1
+}
+
+// DeadLetter is a page which could not be fetched
+type DeadLetter struct {
+	ArXivID  string    `json:"arxivid,omitempty"`
+	URL      string    `json:"url"`
+	Status   int       `json:"status,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Attempts int       `json:"attempts"`
+	FailedAt time.Time `json:"failedat"`
+}
+
+var deadLetterMutex sync.Mutex
+
+// NewDeadLetter builds the DeadLetter of a URL
+func NewDeadLetter(url string, status int, err error, attempts int) DeadLetter {
+	dl := DeadLetter{
+		URL:      url,
+		Status:   status,
+		Attempts: attempts,
+		FailedAt: time.Now().UTC(),
+	}
+	dl.ArXivID = arXivIDPattern.FindString(url)
+	if err != nil {
+		dl.Error = err.Error()
+	}
+	return dl
+}
+
+func deadLetterPath() string {
+	return filepath.Join(TempDir, DeadLetterFile)
+}
+
+// LoadDeadLetters reads the dead letters
+func LoadDeadLetters() ([]DeadLetter, error) {
+	deadLetterMutex.Lock()
+	defer deadLetterMutex.Unlock()
+
+	return readDeadLetters()
+}
+
+func readDeadLetters() ([]DeadLetter, error) {
+	data, err := ioutil.ReadFile(deadLetterPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var dls []DeadLetter
+	err = json.Unmarshal(data, &dls)
+	return dls, err
+}
+
+func writeDeadLetters(dls []DeadLetter) error {
+	err := utils.BuildDir(TempDir)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(dls, "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp := deadLetterPath() + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, deadLetterPath())
+}
+
+// AddDeadLetter records a page which failed permanently, replacing any
+// previous failure of the same URL
+func AddDeadLetter(dl DeadLetter) error {
+	deadLetterMutex.Lock()
+	defer deadLetterMutex.Unlock()
+
+	dls, err := readDeadLetters()
+	if err != nil {
+		return err
+	}
+	for i := range dls {
+		if dls[i].URL == dl.URL {
+			dls[i] = dl
+			return writeDeadLetters(dls)
+		}
+	}
+	return writeDeadLetters(append(dls, dl))
+}
+
+// RemoveDeadLetter removes the dead letter of a URL, once its page was
+// fetched again
+func RemoveDeadLetter(url string) error {
+	deadLetterMutex.Lock()
+	defer deadLetterMutex.Unlock()
+
+	dls, err := readDeadLetters()
+	if err != nil {
+		return err
+	}
+	for i := range dls {
+		if dls[i].URL == url {
+			return writeDeadLetters(append(dls[:i], dls[i+1:]...))
+		}
+	}
+	return nil
+}
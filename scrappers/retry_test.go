@@ -0,0 +1,84 @@
+package scrappers
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRetryable(t *testing.T) {
+	for _, status := range []int{0, 403, 408, 429, 500, 502, 503} {
+		if !Retryable(status) {
+			log.Fatalf("Status %d should be retried", status)
+		}
+	}
+	for _, status := range []int{400, 401, 404, 410, 501} {
+		if Retryable(status) {
+			log.Fatalf("Status %d should not be retried", status)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempt := 1; attempt < 20; attempt++ {
+		d := RetryDelay(attempt)
+		if d <= 0 || d > RetryMaxDelay {
+			log.Fatalf("Wrong delay for attempt %d: %v", attempt, d)
+		}
+	}
+	if d := RetryDelay(1); d > RetryBaseDelay {
+		log.Fatalf("First delay %v exceeds %v", d, RetryBaseDelay)
+	}
+}
+
+func TestDeadLetters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pandor")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(d string) { TempDir = d }(TempDir)
+	TempDir = dir
+
+	url := "https://export.arxiv.org/abs/0801.00042"
+	err = AddDeadLetter(NewDeadLetter(url, 404, errors.New("Not Found"), 1))
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = AddDeadLetter(NewDeadLetter(url, 503, errors.New("Service Unavailable"), 5))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dls, err := LoadDeadLetters()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(dls) != 1 || dls[0].ArXivID != "0801.00042" || dls[0].Status != 503 {
+		log.Fatalf("Wrong dead letters: %+v", dls)
+	}
+	if time.Since(dls[0].FailedAt) > time.Minute {
+		log.Fatalf("Wrong failure date: %v", dls[0].FailedAt)
+	}
+
+	// Loading keeps the dead letters until they are retried successfully
+	other := "https://export.arxiv.org/abs/0801.00043"
+	err = AddDeadLetter(NewDeadLetter(other, 500, errors.New("Internal Server Error"), 5))
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = RemoveDeadLetter(url)
+	if err != nil {
+		log.Fatal(err)
+	}
+	dls, err = LoadDeadLetters()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(dls) != 1 || dls[0].URL != other || dls[0].Attempts != 5 {
+		log.Fatalf("Only the retried dead letter should be removed: %+v", dls)
+	}
+}
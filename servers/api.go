@@ -0,0 +1,281 @@
+package servers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"pandor/databases"
+	"pandor/logger"
+)
+
+// Pagination of the lists
+var (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// MaxDepth bounds the number of citations followed by the depth parameter
+var MaxDepth = 5
+
+// Page is the body of the responses holding a list
+type Page struct {
+	Offset int         `json:"offset"`
+	Limit  int         `json:"limit"`
+	Items  interface{} `json:"items"`
+}
+
+// apiError is the body of the error responses
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// API serves the knowledge graph as JSON:
+//
+//	GET /articles/{arxivid}
+//	GET /articles/{arxivid}/citations
+//	GET /articles/{arxivid}/references
+//	GET /authors/{name}
+//	GET /authors/{name}/articles
+//	GET /authors/{name}/coauthors
+//	GET /authors/{name}/community
+//	GET /search?q=...&from=2008-01-01&to=...&category=...&author=...
+//
+// Lists are paginated with the offset and limit parameters. The old arXiv
+// identifiers contain a slash and are accepted as is, as hep-th/9901001.
+// When the Store is a databases.Traversal, the citations and references
+// take a depth parameter and follow that many citations.
+type API struct {
+	Store databases.Store
+	mux   *http.ServeMux
+}
+
+// NewAPI builds the API of a Store
+func NewAPI(store databases.Store) *API {
+	api := &API{Store: store, mux: http.NewServeMux()}
+	api.mux.HandleFunc("/articles/", api.articles)
+	api.mux.HandleFunc("/authors/", api.authors)
+	api.mux.HandleFunc("/search", api.search)
+	return api
+}
+
+// Serve listens on addr and serves the API of a Store, with the GraphQL
+// endpoint on /graphql
+func Serve(addr string, store databases.Store) error {
+	mux := http.NewServeMux()
+	mux.Handle("/graphql", NewGraphQL(store))
+	mux.Handle("/", NewAPI(store))
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+	logger.Logger.Info(fmt.Sprintf("Serving the API on %s", addr))
+	return server.ListenAndServe()
+}
+
+func (api *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("Method not allowed"))
+		return
+	}
+	api.mux.ServeHTTP(w, r)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		logger.Logger.Error(fmt.Sprintf("Response encoding failed: %v", err))
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, apiError{Error: err.Error()})
+}
+
+// writeResult answers with v, or with the status matching err
+func writeResult(w http.ResponseWriter, v interface{}, err error) {
+	var invalid *databases.ValidationError
+	switch {
+	case err == databases.ErrNotFound:
+		writeError(w, http.StatusNotFound, err)
+	case errors.As(err, &invalid):
+		writeError(w, http.StatusBadRequest, err)
+	case err != nil:
+		logger.Logger.Error(err.Error())
+		writeError(w, http.StatusInternalServerError, errors.New("Internal error"))
+	default:
+		writeJSON(w, http.StatusOK, v)
+	}
+}
+
+// pagination reads the offset and limit parameters
+func pagination(r *http.Request) (int, int, error) {
+	offset, limit := 0, DefaultLimit
+	var err error
+	if v := r.URL.Query().Get("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("Invalid offset %q", v)
+		}
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return 0, 0, fmt.Errorf("Invalid limit %q", v)
+		}
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	return offset, limit, nil
+}
+
+// splitResource splits the path after prefix into an identifier and one of
+// the sub-resources, the identifier may contain slashes
+func splitResource(path, prefix string, subResources ...string) (string, string) {
+	id := strings.Trim(strings.TrimPrefix(path, prefix), "/")
+	for _, sub := range subResources {
+		if strings.HasSuffix(id, "/"+sub) {
+			return strings.TrimSuffix(id, "/"+sub), sub
+		}
+	}
+	return id, ""
+}
+
+// list answers with a page of the list returned by fetch
+func list(w http.ResponseWriter, r *http.Request, fetch func(offset, limit int) (interface{}, error)) {
+	offset, limit, err := pagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	items, err := fetch(offset, limit)
+	writeResult(w, Page{Offset: offset, Limit: limit, Items: items}, err)
+}
+
+func (api *API) articles(w http.ResponseWriter, r *http.Request) {
+	id, sub := splitResource(r.URL.Path, "/articles/", "citations", "references")
+	if id == "" {
+		writeError(w, http.StatusNotFound, databases.ErrNotFound)
+		return
+	}
+	switch sub {
+	case "":
+		article, err := api.Store.Article(id)
+		writeResult(w, article, err)
+	case "citations":
+		if r.URL.Query().Get("depth") != "" {
+			api.within(w, r, id, false)
+			return
+		}
+		list(w, r, func(offset, limit int) (interface{}, error) {
+			return api.Store.Citations(id, offset, limit)
+		})
+	case "references":
+		if r.URL.Query().Get("depth") != "" {
+			api.within(w, r, id, true)
+			return
+		}
+		list(w, r, func(offset, limit int) (interface{}, error) {
+			return api.Store.References(id, offset, limit)
+		})
+	}
+}
+
+// within answers with a page of the articles cited by an article, or
+// citing it, through at most depth citations
+func (api *API) within(w http.ResponseWriter, r *http.Request, id string, references bool) {
+	traversal, ok := api.Store.(databases.Traversal)
+	if !ok {
+		writeError(w, http.StatusBadRequest, errors.New("The depth is not supported by this backend"))
+		return
+	}
+	v := r.URL.Query().Get("depth")
+	depth, err := strconv.Atoi(v)
+	if err != nil || depth <= 0 || depth > MaxDepth {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("Invalid depth %q, at most %d", v, MaxDepth))
+		return
+	}
+	list(w, r, func(offset, limit int) (interface{}, error) {
+		var reached []databases.ReachedArticle
+		if references {
+			reached, err = traversal.ReferencesWithin(id, depth)
+		} else {
+			reached, err = traversal.CitationsWithin(id, depth)
+		}
+		if offset > len(reached) {
+			offset = len(reached)
+		}
+		if offset+limit < len(reached) {
+			reached = reached[:offset+limit]
+		}
+		return reached[offset:], err
+	})
+}
+
+func (api *API) authors(w http.ResponseWriter, r *http.Request) {
+	name, sub := splitResource(r.URL.Path, "/authors/", "articles", "coauthors", "community")
+	if name == "" {
+		writeError(w, http.StatusNotFound, databases.ErrNotFound)
+		return
+	}
+	switch sub {
+	case "":
+		author, err := api.Store.Author(name)
+		writeResult(w, author, err)
+	case "articles":
+		list(w, r, func(offset, limit int) (interface{}, error) {
+			return api.Store.AuthorArticles(name, offset, limit)
+		})
+	case "coauthors":
+		list(w, r, func(offset, limit int) (interface{}, error) {
+			return api.Store.Coauthors(name, offset, limit)
+		})
+	case "community":
+		community, err := api.Store.AuthorCommunity(name)
+		writeResult(w, community, err)
+	}
+}
+
+func (api *API) search(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := strings.TrimSpace(params.Get("q"))
+	if query == "" {
+		writeError(w, http.StatusBadRequest, errors.New("Missing q parameter"))
+		return
+	}
+	filters := databases.SearchFilters{
+		Category: params.Get("category"),
+		Author:   params.Get("author"),
+	}
+	var err error
+	filters.Offset, filters.Limit, err = pagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if v := params.Get("from"); v != "" {
+		if filters.From, err = time.Parse("2006-01-02", v); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("Invalid from date %q", v))
+			return
+		}
+	}
+	if v := params.Get("to"); v != "" {
+		if filters.To, err = time.Parse("2006-01-02", v); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("Invalid to date %q", v))
+			return
+		}
+		filters.To = filters.To.Add(24*time.Hour - time.Nanosecond)
+	}
+	results, err := api.Store.Search(query, filters)
+	writeResult(w, Page{Offset: filters.Offset, Limit: filters.Limit, Items: results}, err)
+}
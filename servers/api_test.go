@@ -0,0 +1,303 @@
+package servers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"pandor/databases"
+	"pandor/logger"
+	"pandor/models"
+)
+
+// fakeStore serves a fixed graph of three articles
+type fakeStore struct {
+	articles    map[string]models.Article
+	filters     databases.SearchFilters
+	linked      []string // calls of Linked, as edge:uids
+	communities []string // calls of AuthorCommunities, as uids
+	mu          sync.Mutex
+}
+
+func newFakeStore() *fakeStore {
+	lewis := models.Author{UID: "0x1", Name: "Lewis_G", CitationCount: 12, HIndex: 3, I10Index: 1}
+	huxor := models.Author{UID: "0x2", Name: "Huxor_A"}
+	return &fakeStore{articles: map[string]models.Article{
+		"0801.0002":      {UID: "0x10", ArXivID: "0801.0002", Title: "Dwarf galaxies", Authors: []models.Author{lewis, huxor}},
+		"0801.0003":      {UID: "0x11", ArXivID: "0801.0003", Title: "Streams", Authors: []models.Author{lewis}},
+		"hep-th/9901001": {UID: "0x12", ArXivID: "hep-th/9901001", Title: "Strings"},
+	}}
+}
+
+func (s *fakeStore) Article(id string) (models.Article, error) {
+	article, ok := s.articles[id]
+	if !ok {
+		return article, databases.ErrNotFound
+	}
+	return article, nil
+}
+
+func (s *fakeStore) Citations(id string, offset, limit int) ([]models.Article, error) {
+	if id == "broken" {
+		return nil, errors.New("connection refused")
+	}
+	if _, ok := s.articles[id]; !ok {
+		return nil, databases.ErrNotFound
+	}
+	all := []models.Article{s.articles["0801.0003"], s.articles["hep-th/9901001"]}
+	if offset >= len(all) {
+		return nil, nil
+	}
+	all = all[offset:]
+	if limit < len(all) {
+		all = all[:limit]
+	}
+	return all, nil
+}
+
+func (s *fakeStore) References(id string, offset, limit int) ([]models.Article, error) {
+	return nil, databases.ErrNotFound
+}
+
+func (s *fakeStore) Author(name string) (models.Author, error) {
+	if name != "Lewis_G" {
+		return models.Author{}, databases.ErrNotFound
+	}
+	return models.Author{UID: "0x1", Name: name}, nil
+}
+
+func (s *fakeStore) AuthorArticles(name string, offset, limit int) ([]models.Article, error) {
+	return []models.Article{s.articles["0801.0002"], s.articles["0801.0003"]}, nil
+}
+
+func (s *fakeStore) Coauthors(name string, offset, limit int) ([]databases.Coauthor, error) {
+	articles, _ := s.AuthorArticles(name, 0, 0)
+	return databases.CountCoauthors("0x1", articles), nil
+}
+
+func (s *fakeStore) AuthorCommunity(name string) (models.Community, error) {
+	if name != "Lewis_G" {
+		return models.Community{}, databases.ErrNotFound
+	}
+	return models.Community{UID: "0x30", Label: "Lewis_G", Size: 2, Method: "louvain", Members: []models.Author{
+		{UID: "0x1", Name: "Lewis_G", CitationCount: 12, HIndex: 3, I10Index: 1}, {UID: "0x2", Name: "Huxor_A"}}}, nil
+}
+
+// AuthorCommunities gives the community of Lewis_G to its members
+func (s *fakeStore) AuthorCommunities(uids []string) (map[string]models.Community, error) {
+	sorted := append([]string{}, uids...)
+	sort.Strings(sorted)
+	s.mu.Lock()
+	s.communities = append(s.communities, strings.Join(sorted, ","))
+	s.mu.Unlock()
+	community, _ := s.AuthorCommunity("Lewis_G")
+	communities := make(map[string]models.Community)
+	for _, uid := range uids {
+		for _, member := range community.Members {
+			if member.UID == uid {
+				communities[uid] = community
+			}
+		}
+	}
+	return communities, nil
+}
+
+func (s *fakeStore) Search(query string, filters databases.SearchFilters) ([]databases.SearchResult, error) {
+	s.filters = filters
+	return []databases.SearchResult{{Article: s.articles["0801.0002"], Score: 1}}, nil
+}
+
+func (s *fakeStore) Articles(filters databases.SearchFilters) ([]models.Article, error) {
+	s.filters = filters
+	return []models.Article{s.articles["0801.0002"], s.articles["0801.0003"]}, nil
+}
+
+func (s *fakeStore) Category(code string) (models.Category, error) {
+	return models.Category{}, databases.ErrNotFound
+}
+
+// Linked links 0801.0002 to 0801.0003 and 0801.0003 to hep-th/9901001,
+// both by authors and by citations
+func (s *fakeStore) Linked(edge string, uids []string) (map[string][]models.Article, error) {
+	sorted := append([]string{}, uids...)
+	sort.Strings(sorted)
+	s.mu.Lock()
+	s.linked = append(s.linked, edge+":"+strings.Join(sorted, ","))
+	s.mu.Unlock()
+	linked := make(map[string][]models.Article)
+	for _, uid := range uids {
+		switch uid {
+		case "0x1":
+			linked[uid] = []models.Article{s.articles["0801.0002"], s.articles["0801.0003"]}
+		case "0x10":
+			linked[uid] = []models.Article{s.articles["0801.0003"]}
+		case "0x11":
+			linked[uid] = []models.Article{s.articles["hep-th/9901001"]}
+		}
+	}
+	return linked, nil
+}
+
+// get requests path and decodes the JSON response in v
+func get(server *httptest.Server, path string, v interface{}) int {
+	resp, err := http.Get(server.URL + path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "application/json" {
+		log.Fatalf("Unexpected content type %s for %s", resp.Header.Get("Content-Type"), path)
+	}
+	err = json.NewDecoder(resp.Body).Decode(v)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+func TestAPI(t *testing.T) {
+	logger.Logger = logger.InitLogger()
+	store := newFakeStore()
+	server := httptest.NewServer(NewAPI(store))
+	defer server.Close()
+
+	var article models.Article
+	if status := get(server, "/articles/0801.0002", &article); status != http.StatusOK || article.Title != "Dwarf galaxies" {
+		log.Fatalf("Wrong article %d %v", status, article)
+	}
+	if status := get(server, "/articles/hep-th/9901001", &article); status != http.StatusOK || article.Title != "Strings" {
+		log.Fatalf("Wrong old-style article %d %v", status, article)
+	}
+	var e apiError
+	if status := get(server, "/articles/0000.0000", &e); status != http.StatusNotFound || e.Error == "" {
+		log.Fatalf("Expected a 404, got %d %v", status, e)
+	}
+
+	var citations struct {
+		Offset int              `json:"offset"`
+		Limit  int              `json:"limit"`
+		Items  []models.Article `json:"items"`
+	}
+	status := get(server, "/articles/0801.0002/citations?offset=1&limit=1", &citations)
+	if status != http.StatusOK || citations.Offset != 1 || len(citations.Items) != 1 ||
+		citations.Items[0].ArXivID != "hep-th/9901001" {
+		log.Fatalf("Wrong citations %d %v", status, citations)
+	}
+	if status := get(server, "/articles/0801.0002/citations?limit=-1", &e); status != http.StatusBadRequest {
+		log.Fatalf("Expected a 400, got %d", status)
+	}
+	if status := get(server, "/articles/broken/citations", &e); status != http.StatusInternalServerError ||
+		e.Error != "Internal error" {
+		log.Fatalf("Expected a 500 hiding the error, got %d %v", status, e)
+	}
+
+	var coauthors struct {
+		Items []databases.Coauthor `json:"items"`
+	}
+	status = get(server, "/authors/Lewis_G/coauthors", &coauthors)
+	if status != http.StatusOK || len(coauthors.Items) != 1 ||
+		coauthors.Items[0].Name != "Huxor_A" || coauthors.Items[0].Shared != 1 {
+		log.Fatalf("Wrong coauthors %d %v", status, coauthors)
+	}
+	var community models.Community
+	status = get(server, "/authors/Lewis_G/community", &community)
+	if status != http.StatusOK || community.Label != "Lewis_G" || len(community.Members) != 2 {
+		log.Fatalf("Wrong community %d %v", status, community)
+	}
+	var author models.Author
+	if status := get(server, "/authors/Doe_J", &author); status != http.StatusNotFound {
+		log.Fatalf("Expected a 404, got %d", status)
+	}
+}
+
+func TestSearchAPI(t *testing.T) {
+	logger.Logger = logger.InitLogger()
+	store := newFakeStore()
+	server := httptest.NewServer(NewAPI(store))
+	defer server.Close()
+
+	var results struct {
+		Limit int                      `json:"limit"`
+		Items []databases.SearchResult `json:"items"`
+	}
+	status := get(server, "/search?q=dwarf&category=astro-ph&from=2008-01-01&limit=500", &results)
+	if status != http.StatusOK || len(results.Items) != 1 || results.Limit != MaxLimit {
+		log.Fatalf("Wrong search results %d %v", status, results)
+	}
+	if store.filters.Category != "astro-ph" || store.filters.From.Year() != 2008 {
+		log.Fatalf("Wrong filters %v", store.filters)
+	}
+
+	var e apiError
+	if status := get(server, "/search", &e); status != http.StatusBadRequest {
+		log.Fatalf("Expected a 400 without query, got %d", status)
+	}
+	if status := get(server, "/search?q=dwarf&from=yesterday", &e); status != http.StatusBadRequest {
+		log.Fatalf("Expected a 400 with an invalid date, got %d", status)
+	}
+
+	// The stores validate the category
+	memory := httptest.NewServer(NewAPI(databases.NewMemoryStore()))
+	defer memory.Close()
+	status = get(memory, "/search?q=dwarf&category=astro-ph/GA", &e)
+	if status != http.StatusBadRequest || e.Error != `Invalid category "astro-ph/GA"` {
+		log.Fatalf("Expected a 400 with an invalid category, got %d %v", status, e)
+	}
+
+	resp, err := http.Post(server.URL+"/search?q=dwarf", "application/json", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		log.Fatalf("Expected a 405, got %d", resp.StatusCode)
+	}
+}
+
+func TestTraversalAPI(t *testing.T) {
+	logger.Logger = logger.InitLogger()
+	store, err := databases.OpenSQLStore("sqlite", ":memory:")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+	// a cites b, b cites c
+	err = store.Load([]models.Article{
+		{ArXivID: "a", Title: "A", CitedPapers: []models.Article{{ArXivID: "b"}}},
+		{ArXivID: "b", Title: "B", CitedPapers: []models.Article{{ArXivID: "c"}}},
+		{ArXivID: "c", Title: "C"},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	server := httptest.NewServer(NewAPI(store))
+	defer server.Close()
+
+	var reached struct {
+		Items []databases.ReachedArticle `json:"items"`
+	}
+	status := get(server, "/articles/a/references?depth=2", &reached)
+	if status != http.StatusOK || len(reached.Items) != 2 || reached.Items[1].ArXivID != "c" || reached.Items[1].Depth != 2 {
+		log.Fatalf("Wrong references %d %v", status, reached)
+	}
+	status = get(server, "/articles/c/citations?depth=2&offset=1", &reached)
+	if status != http.StatusOK || len(reached.Items) != 1 || reached.Items[0].ArXivID != "a" {
+		log.Fatalf("Wrong citations %d %v", status, reached)
+	}
+
+	var e apiError
+	if status := get(server, "/articles/a/references?depth=0", &e); status != http.StatusBadRequest {
+		log.Fatalf("Expected a 400 with an invalid depth, got %d", status)
+	}
+	fake := httptest.NewServer(NewAPI(newFakeStore()))
+	defer fake.Close()
+	if status := get(fake, "/articles/0801.0002/citations?depth=2", &e); status != http.StatusBadRequest {
+		log.Fatalf("Expected a 400 without traversal, got %d", status)
+	}
+}
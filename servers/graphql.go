@@ -0,0 +1,431 @@
+package servers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"pandor/databases"
+	"pandor/models"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+// GraphQLSchema describes the graph served by the GraphQL endpoint
+const GraphQLSchema = `
+schema {
+	query: Query
+}
+
+scalar Time
+
+type Query {
+	article(arxivid: String!): Article
+	author(name: String!): Author
+	category(code: String!): Category
+	articles(author: String, category: String, from: Time, to: Time, first: Int = 20, offset: Int = 0): [Article!]!
+	search(query: String!, author: String, category: String, from: Time, to: Time, first: Int = 20, offset: Int = 0): [SearchResult!]!
+}
+
+type Article {
+	uid: ID!
+	arxivid: String!
+	title: String!
+	abstract: String
+	submissiondate: Time
+	pdfurl: String
+	metaurl: String
+	authors: [Author!]!
+	categories: [Category!]!
+	citedpapers(first: Int = 20, offset: Int = 0): [Article!]!
+	citations(first: Int = 20, offset: Int = 0): [Article!]!
+	citationcount: Int!
+	citationvelocity: Float!
+	pagerank: Float!
+}
+
+type Author {
+	uid: ID!
+	name: String!
+	url: String
+	citationcount: Int!
+	hindex: Int!
+	i10index: Int!
+	community: Community
+	articles(first: Int = 20, offset: Int = 0): [Article!]!
+}
+
+type Community {
+	uid: ID!
+	label: String!
+	size: Int!
+	method: String!
+	members: [Author!]!
+}
+
+type Category {
+	uid: ID!
+	code: String!
+	name: String!
+	articles(first: Int = 20, offset: Int = 0): [Article!]!
+}
+
+type SearchResult {
+	score: Float!
+	article: Article!
+}
+`
+
+// GraphQL limits
+var (
+	GraphQLMaxDepth       = 10
+	GraphQLMaxParallelism = 50
+)
+
+// GraphQL serves the graph on a GraphQL endpoint. The nested lists are
+// loaded in batches with Store.Linked and Store.AuthorCommunities.
+type GraphQL struct {
+	Store  databases.Store
+	schema *graphql.Schema
+}
+
+// NewGraphQL builds the GraphQL endpoint of a Store
+func NewGraphQL(store databases.Store) *GraphQL {
+	return &GraphQL{
+		Store: store,
+		schema: graphql.MustParseSchema(GraphQLSchema, &queryResolver{store: store},
+			graphql.MaxDepth(GraphQLMaxDepth), graphql.MaxParallelism(GraphQLMaxParallelism)),
+	}
+}
+
+// graphQLRequest is the body of a GraphQL request
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+func (g *GraphQL) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req graphQLRequest
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if v := r.URL.Query().Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				writeError(w, http.StatusBadRequest, errors.New("Invalid variables"))
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("Invalid JSON body"))
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("Method not allowed"))
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), loadersKey{}, newLoaders(g.Store))
+	writeJSON(w, http.StatusOK, g.schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+}
+
+// loadersKey is the context key of the loaders of a request
+type loadersKey struct{}
+
+// communityLoader is the loader of Store.AuthorCommunities
+const communityLoader = "community"
+
+// newLoaders builds a loader for each edge of Store.Linked and one for the
+// communities of the authors
+func newLoaders(store databases.Store) map[string]*loader {
+	loaders := make(map[string]*loader)
+	for _, edge := range []string{
+		databases.AuthorArticlesEdge,
+		databases.CategoryArticlesEdge,
+		databases.ReferencesEdge,
+		databases.CitationsEdge,
+	} {
+		edge := edge
+		loaders[edge] = newLoader(func(keys []string) (map[string]interface{}, error) {
+			linked, err := store.Linked(edge, keys)
+			values := make(map[string]interface{}, len(linked))
+			for uid, articles := range linked {
+				values[uid] = articles
+			}
+			return values, err
+		})
+	}
+	loaders[communityLoader] = newLoader(func(keys []string) (map[string]interface{}, error) {
+		communities, err := store.AuthorCommunities(keys)
+		values := make(map[string]interface{}, len(communities))
+		for uid, community := range communities {
+			values[uid] = community
+		}
+		return values, err
+	})
+	return loaders
+}
+
+// loadLinked returns a page of the articles linked to a node by an edge
+func loadLinked(ctx context.Context, edge, uid string, args pageArgs) ([]*articleResolver, error) {
+	loaders, _ := ctx.Value(loadersKey{}).(map[string]*loader)
+	if loaders == nil {
+		return nil, errors.New("No loader in the context")
+	}
+	value, err := loaders[edge].Load(uid)
+	if err != nil {
+		return nil, err
+	}
+	articles, _ := value.([]models.Article)
+	offset, first := int(args.Offset), int(args.First)
+	if offset < 0 || first < 0 {
+		return nil, errors.New("Negative pagination")
+	}
+	if offset >= len(articles) {
+		return []*articleResolver{}, nil
+	}
+	articles = articles[offset:]
+	if first < len(articles) {
+		articles = articles[:first]
+	}
+	return articleResolvers(articles), nil
+}
+
+type pageArgs struct {
+	First  int32
+	Offset int32
+}
+
+type filterArgs struct {
+	Author   *string
+	Category *string
+	From     *graphql.Time
+	To       *graphql.Time
+	First    int32
+	Offset   int32
+}
+
+func (args filterArgs) filters() (databases.SearchFilters, error) {
+	filters := databases.SearchFilters{Offset: int(args.Offset), Limit: int(args.First)}
+	if filters.Offset < 0 || filters.Limit < 0 {
+		return filters, errors.New("Negative pagination")
+	}
+	if filters.Limit > MaxLimit {
+		filters.Limit = MaxLimit
+	}
+	if args.Author != nil {
+		filters.Author = *args.Author
+	}
+	if args.Category != nil {
+		filters.Category = *args.Category
+	}
+	if args.From != nil {
+		filters.From = args.From.Time
+	}
+	if args.To != nil {
+		filters.To = args.To.Time
+	}
+	return filters, nil
+}
+
+// optional returns nil for the empty strings
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+type queryResolver struct {
+	store databases.Store
+}
+
+func (q *queryResolver) Article(args struct{ ArXivID string }) (*articleResolver, error) {
+	article, err := q.store.Article(args.ArXivID)
+	if err == databases.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &articleResolver{article}, nil
+}
+
+func (q *queryResolver) Author(args struct{ Name string }) (*authorResolver, error) {
+	author, err := q.store.Author(args.Name)
+	if err == databases.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authorResolver{author}, nil
+}
+
+func (q *queryResolver) Category(args struct{ Code string }) (*categoryResolver, error) {
+	category, err := q.store.Category(args.Code)
+	if err == databases.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &categoryResolver{category}, nil
+}
+
+func (q *queryResolver) Articles(args filterArgs) ([]*articleResolver, error) {
+	filters, err := args.filters()
+	if err != nil {
+		return nil, err
+	}
+	articles, err := q.store.Articles(filters)
+	if err != nil {
+		return nil, err
+	}
+	return articleResolvers(articles), nil
+}
+
+func (q *queryResolver) Search(args struct {
+	Query string
+	filterArgs
+}) ([]*searchResultResolver, error) {
+	filters, err := args.filters()
+	if err != nil {
+		return nil, err
+	}
+	results, err := q.store.Search(args.Query, filters)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*searchResultResolver, len(results))
+	for i := range results {
+		resolvers[i] = &searchResultResolver{results[i]}
+	}
+	return resolvers, nil
+}
+
+type articleResolver struct {
+	a models.Article
+}
+
+func articleResolvers(articles []models.Article) []*articleResolver {
+	resolvers := make([]*articleResolver, len(articles))
+	for i := range articles {
+		resolvers[i] = &articleResolver{articles[i]}
+	}
+	return resolvers
+}
+
+func (r *articleResolver) UID() graphql.ID           { return graphql.ID(r.a.UID) }
+func (r *articleResolver) ArXivID() string           { return r.a.ArXivID }
+func (r *articleResolver) Title() string             { return r.a.Title }
+func (r *articleResolver) Abstract() *string         { return optional(r.a.Abstract) }
+func (r *articleResolver) PDFURL() *string           { return optional(r.a.PDFURL) }
+func (r *articleResolver) MetaURL() *string          { return optional(r.a.MetaURL) }
+func (r *articleResolver) CitationCount() int32      { return int32(r.a.CitationCount) }
+func (r *articleResolver) CitationVelocity() float64 { return r.a.CitationVelocity }
+func (r *articleResolver) PageRank() float64         { return r.a.PageRank }
+func (r *articleResolver) SubmissionDate() *graphql.Time {
+	if r.a.SubmissionDate.IsZero() {
+		return nil
+	}
+	return &graphql.Time{Time: r.a.SubmissionDate}
+}
+
+func (r *articleResolver) Authors() []*authorResolver {
+	resolvers := make([]*authorResolver, len(r.a.Authors))
+	for i := range r.a.Authors {
+		resolvers[i] = &authorResolver{r.a.Authors[i]}
+	}
+	return resolvers
+}
+
+func (r *articleResolver) Categories() []*categoryResolver {
+	resolvers := make([]*categoryResolver, len(r.a.Categories))
+	for i := range r.a.Categories {
+		resolvers[i] = &categoryResolver{r.a.Categories[i]}
+	}
+	return resolvers
+}
+
+func (r *articleResolver) CitedPapers(ctx context.Context, args pageArgs) ([]*articleResolver, error) {
+	return loadLinked(ctx, databases.ReferencesEdge, r.a.UID, args)
+}
+
+func (r *articleResolver) Citations(ctx context.Context, args pageArgs) ([]*articleResolver, error) {
+	return loadLinked(ctx, databases.CitationsEdge, r.a.UID, args)
+}
+
+type authorResolver struct {
+	a models.Author
+}
+
+func (r *authorResolver) UID() graphql.ID      { return graphql.ID(r.a.UID) }
+func (r *authorResolver) Name() string         { return r.a.Name }
+func (r *authorResolver) URL() *string         { return optional(r.a.URL) }
+func (r *authorResolver) CitationCount() int32 { return int32(r.a.CitationCount) }
+func (r *authorResolver) HIndex() int32        { return int32(r.a.HIndex) }
+func (r *authorResolver) I10Index() int32      { return int32(r.a.I10Index) }
+
+func (r *authorResolver) Articles(ctx context.Context, args pageArgs) ([]*articleResolver, error) {
+	return loadLinked(ctx, databases.AuthorArticlesEdge, r.a.UID, args)
+}
+
+func (r *authorResolver) Community(ctx context.Context) (*communityResolver, error) {
+	loaders, _ := ctx.Value(loadersKey{}).(map[string]*loader)
+	if loaders == nil {
+		return nil, errors.New("No loader in the context")
+	}
+	value, err := loaders[communityLoader].Load(r.a.UID)
+	if err != nil {
+		return nil, err
+	}
+	community, ok := value.(models.Community)
+	if !ok {
+		return nil, nil
+	}
+	return &communityResolver{community}, nil
+}
+
+type communityResolver struct {
+	c models.Community
+}
+
+func (r *communityResolver) UID() graphql.ID { return graphql.ID(r.c.UID) }
+func (r *communityResolver) Label() string   { return r.c.Label }
+func (r *communityResolver) Size() int32     { return int32(r.c.Size) }
+func (r *communityResolver) Method() string  { return r.c.Method }
+
+func (r *communityResolver) Members() []*authorResolver {
+	resolvers := make([]*authorResolver, len(r.c.Members))
+	for i := range r.c.Members {
+		resolvers[i] = &authorResolver{r.c.Members[i]}
+	}
+	return resolvers
+}
+
+type categoryResolver struct {
+	c models.Category
+}
+
+func (r *categoryResolver) UID() graphql.ID { return graphql.ID(r.c.UID) }
+func (r *categoryResolver) Code() string    { return r.c.Code }
+func (r *categoryResolver) Name() string    { return r.c.Name }
+
+func (r *categoryResolver) Articles(ctx context.Context, args pageArgs) ([]*articleResolver, error) {
+	return loadLinked(ctx, databases.CategoryArticlesEdge, r.c.UID, args)
+}
+
+type searchResultResolver struct {
+	r databases.SearchResult
+}
+
+func (r *searchResultResolver) Score() float64 { return r.r.Score }
+
+func (r *searchResultResolver) Article() *articleResolver {
+	return &articleResolver{r.r.Article}
+}
@@ -0,0 +1,189 @@
+package servers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"pandor/logger"
+)
+
+// postGraphQL sends a query and decodes the response in v
+func postGraphQL(server *httptest.Server, query string, v interface{}) {
+	body, _ := json.Marshal(graphQLRequest{Query: query})
+	resp, err := http.Post(server.URL+"/graphql", "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Fatalf("Unexpected status %d", resp.StatusCode)
+	}
+	err = json.NewDecoder(resp.Body).Decode(v)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func TestGraphQL(t *testing.T) {
+	logger.Logger = logger.InitLogger()
+	BatchWait = 50 * time.Millisecond
+	store := newFakeStore()
+	mux := http.NewServeMux()
+	mux.Handle("/graphql", NewGraphQL(store))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var resp struct {
+		Data struct {
+			Author struct {
+				Name      string
+				Community struct {
+					Label   string
+					Size    int
+					Members []struct{ Name string }
+				}
+				Articles []struct {
+					ArXivID string
+					Authors []struct{ Name string }
+					Cited   []struct{ ArXivID string } `json:"citedpapers"`
+				}
+			}
+		}
+		Errors []struct{ Message string }
+	}
+	postGraphQL(server, `{
+		author(name: "Lewis_G") {
+			name
+			community { label size members { name } }
+			articles {
+				arxivid
+				authors { name }
+				citedpapers { arxivid }
+			}
+		}
+	}`, &resp)
+	if len(resp.Errors) > 0 {
+		log.Fatalf("Unexpected errors %v", resp.Errors)
+	}
+	if c := resp.Data.Author.Community; c.Label != "Lewis_G" || c.Size != 2 || c.Members[1].Name != "Huxor_A" {
+		log.Fatalf("Wrong community %v", c)
+	}
+	articles := resp.Data.Author.Articles
+	if len(articles) != 2 || articles[0].ArXivID != "0801.0002" || len(articles[0].Authors) != 2 {
+		log.Fatalf("Wrong articles %v", articles)
+	}
+	if len(articles[1].Cited) != 1 || articles[1].Cited[0].ArXivID != "hep-th/9901001" {
+		log.Fatalf("Wrong cited papers %v", articles[1].Cited)
+	}
+	// The cited papers of both articles are loaded at once
+	calls := strings.Join(store.linked, " ")
+	if calls != "~authors:0x1 citedpapers:0x10,0x11" {
+		log.Fatalf("Unexpected loads %s", calls)
+	}
+}
+
+func TestGraphQLFilters(t *testing.T) {
+	logger.Logger = logger.InitLogger()
+	store := newFakeStore()
+	server := httptest.NewServer(NewGraphQL(store))
+	defer server.Close()
+
+	var resp struct {
+		Data struct {
+			Articles []struct{ Title string }
+			Missing  *struct{ Title string }
+		}
+		Errors []struct{ Message string }
+	}
+	postGraphQL(server, `{
+		articles(category: "astro-ph", from: "2008-01-01T00:00:00Z", first: 500) { title }
+		missing: article(arxivid: "0000.0000") { title }
+	}`, &resp)
+	if len(resp.Errors) > 0 {
+		log.Fatalf("Unexpected errors %v", resp.Errors)
+	}
+	if len(resp.Data.Articles) != 2 || resp.Data.Missing != nil {
+		log.Fatalf("Wrong data %v", resp.Data)
+	}
+	if store.filters.Category != "astro-ph" || store.filters.From.Year() != 2008 || store.filters.Limit != MaxLimit {
+		log.Fatalf("Wrong filters %v", store.filters)
+	}
+
+	var search struct {
+		Data struct {
+			Search []struct {
+				Score   float64
+				Article struct{ Title string }
+			}
+		}
+		Errors []struct{ Message string }
+	}
+	postGraphQL(server, `{ search(query: "dwarf", author: "Lewis_G") { score article { title } } }`, &search)
+	if len(search.Errors) > 0 || len(search.Data.Search) != 1 || search.Data.Search[0].Article.Title != "Dwarf galaxies" {
+		log.Fatalf("Wrong search %v", search)
+	}
+	if store.filters.Author != "Lewis_G" || store.filters.Limit != 20 {
+		log.Fatalf("Wrong search filters %v", store.filters)
+	}
+
+	// The nested authors come with their metrics
+	type metrics struct {
+		Name          string
+		CitationCount int
+		HIndex        int
+		I10Index      int
+	}
+	var nested struct {
+		Data struct {
+			Article struct{ Authors []metrics }
+			Author  struct {
+				Community struct{ Members []metrics }
+			}
+		}
+		Errors []struct{ Message string }
+	}
+	postGraphQL(server, `{
+		article(arxivid: "0801.0002") { authors { name citationcount hindex i10index } }
+		author(name: "Lewis_G") { community { members { name citationcount hindex i10index } } }
+	}`, &nested)
+	if len(nested.Errors) > 0 {
+		log.Fatalf("Unexpected errors %v", nested.Errors)
+	}
+	lewis := metrics{Name: "Lewis_G", CitationCount: 12, HIndex: 3, I10Index: 1}
+	if authors := nested.Data.Article.Authors; len(authors) != 2 || authors[0] != lewis {
+		log.Fatalf("Wrong authors of the article %v", authors)
+	}
+	if members := nested.Data.Author.Community.Members; len(members) != 2 || members[0] != lewis {
+		log.Fatalf("Wrong members of the community %v", members)
+	}
+
+	// The communities of the authors of a list are loaded at once
+	store.communities = nil
+	var communities struct {
+		Data struct {
+			Article struct {
+				Authors []struct{ Community struct{ Label string } }
+			}
+		}
+		Errors []struct{ Message string }
+	}
+	postGraphQL(server, `{ article(arxivid: "0801.0002") { authors { community { label } } } }`, &communities)
+	authors := communities.Data.Article.Authors
+	if len(communities.Errors) > 0 || len(authors) != 2 || authors[1].Community.Label != "Lewis_G" {
+		log.Fatalf("Wrong communities of the authors %v", communities)
+	}
+	if calls := strings.Join(store.communities, " "); calls != "0x1,0x2" {
+		log.Fatalf("Unexpected community loads %s", calls)
+	}
+
+	postGraphQL(server, `{ article(arxivid: "0801.0002") { unknown } }`, &resp)
+	if len(resp.Errors) == 0 {
+		log.Fatal("Unknown fields should fail")
+	}
+}
@@ -0,0 +1,64 @@
+package servers
+
+import (
+	"sync"
+	"time"
+)
+
+// BatchWait is the time a loader waits for other keys before querying
+var BatchWait = 2 * time.Millisecond
+
+// batch is a set of keys loaded by a single call
+type batch struct {
+	keys    []string
+	done    chan struct{}
+	results map[string]interface{}
+	err     error
+}
+
+// loader batches and caches the loads of the values of nodes, such as the
+// articles linked to them.
+// GraphQL resolves the fields of a list concurrently: the keys requested
+// within BatchWait are fetched together, which avoids one query per node.
+// A loader lives for a single request.
+type loader struct {
+	fetch func(keys []string) (map[string]interface{}, error)
+
+	mu      sync.Mutex
+	pending *batch
+	batches map[string]*batch
+}
+
+func newLoader(fetch func(keys []string) (map[string]interface{}, error)) *loader {
+	return &loader{fetch: fetch, batches: make(map[string]*batch)}
+}
+
+// Load returns the value of the node with key, nil when it has none
+func (l *loader) Load(key string) (interface{}, error) {
+	l.mu.Lock()
+	b, ok := l.batches[key]
+	if !ok {
+		if l.pending == nil {
+			l.pending = &batch{done: make(chan struct{})}
+			time.AfterFunc(BatchWait, l.dispatch)
+		}
+		b = l.pending
+		b.keys = append(b.keys, key)
+		l.batches[key] = b
+	}
+	l.mu.Unlock()
+
+	<-b.done
+	return b.results[key], b.err
+}
+
+// dispatch fetches the pending batch
+func (l *loader) dispatch() {
+	l.mu.Lock()
+	b := l.pending
+	l.pending = nil
+	l.mu.Unlock()
+
+	b.results, b.err = l.fetch(b.keys)
+	close(b.done)
+}
@@ -0,0 +1,93 @@
+package storages
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pandor/utils"
+)
+
+// Local is a Storage keeping files in a directory
+type Local struct {
+	Dir string
+}
+
+// NewLocal builds a Local storage in dir
+func NewLocal(dir string) *Local {
+	return &Local{Dir: dir}
+}
+
+// Path returns the file holding a key
+func (l *Local) Path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if key == "" || strings.HasSuffix(key, "/") || clean == "/" {
+		return "", fmt.Errorf("Invalid key: %s", key)
+	}
+	return filepath.Join(l.Dir, filepath.FromSlash(clean)), nil
+}
+
+// Put writes the content to a temporary file renamed once complete, so that
+// a key never holds a partial file
+func (l *Local) Put(key string, r io.ReadSeeker) error {
+	path, err := l.Path(key)
+	if err != nil {
+		return err
+	}
+	err = utils.BuildDir(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get opens the file of a key
+func (l *Local) Get(key string) (io.ReadCloser, error) {
+	path, err := l.Path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Exists tells whether the file of a key exists
+func (l *Local) Exists(key string) (bool, error) {
+	path, err := l.Path(key)
+	if err != nil {
+		return false, err
+	}
+	return utils.Exists(path)
+}
+
+// Delete removes the file of a key
+func (l *Local) Delete(key string) error {
+	path, err := l.Path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,208 @@
+package storages
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// S3 is a Storage backed by a bucket of an S3-compatible API (AWS, MinIO,
+// Ceph...). Requests use path-style addressing and are signed with AWS
+// Signature Version 4.
+type S3 struct {
+	Endpoint  string // such as https://s3.eu-west-1.amazonaws.com
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	Client    *http.Client
+	now       func() time.Time
+}
+
+// NewS3 builds an S3 storage
+func NewS3(endpoint, bucket, region, accessKey, secretKey string) *S3 {
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3{
+		Endpoint:  strings.TrimSuffix(endpoint, "/"),
+		Bucket:    bucket,
+		Region:    region,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		Client:    &http.Client{Timeout: 10 * time.Minute},
+		now:       time.Now,
+	}
+}
+
+// S3Error is an unexpected response of the API
+type S3Error struct {
+	Method     string
+	Key        string
+	StatusCode int
+	Body       string
+}
+
+func (e *S3Error) Error() string {
+	return fmt.Sprintf("S3 %s %s failed with status %d: %s", e.Method, e.Key, e.StatusCode, e.Body)
+}
+
+// escapePath encodes a path as expected by Signature Version 4: every byte
+// but the unreserved characters and the slashes is percent-encoded
+func escapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// sign adds the Signature Version 4 headers to a request
+func (s *S3) sign(req *http.Request, payloadHash string) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signedHeaders, signature))
+}
+
+// do sends a signed request about a key
+func (s *S3) do(method, key string, body io.ReadSeeker) (*http.Response, error) {
+	u, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = "/" + s.Bucket + "/" + key
+	u.RawPath = escapePath(u.Path)
+
+	payloadHash := hex.EncodeToString(sha256.New().Sum(nil))
+	var size int64
+	if body != nil {
+		h := sha256.New()
+		size, err = io.Copy(h, body)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = body.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		payloadHash = hex.EncodeToString(h.Sum(nil))
+	}
+
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Body = ioutil.NopCloser(body)
+		req.ContentLength = size
+	}
+	s.sign(req, payloadHash)
+	return s.Client.Do(req)
+}
+
+func (s *S3) check(resp *http.Response, method, key string) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &S3Error{Method: method, Key: key, StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return nil
+}
+
+// Put uploads the content of a key
+func (s *S3) Put(key string, r io.ReadSeeker) error {
+	resp, err := s.do(http.MethodPut, key, r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return s.check(resp, http.MethodPut, key)
+}
+
+// Get downloads the content of a key
+func (s *S3) Get(key string) (io.ReadCloser, error) {
+	resp, err := s.do(http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.check(resp, http.MethodGet, key); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Exists sends a HEAD request about a key
+func (s *S3) Exists(key string) (bool, error) {
+	resp, err := s.do(http.MethodHead, key, nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	err = s.check(resp, http.MethodHead, key)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Delete removes a key
+func (s *S3) Delete(key string) error {
+	resp, err := s.do(http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return s.check(resp, http.MethodDelete, key)
+}
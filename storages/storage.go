@@ -0,0 +1,21 @@
+package storages
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when a key is not in a Storage
+var ErrNotFound = errors.New("Key not found")
+
+// Storage keeps files under keys such as "pdf/0801.0001.pdf"
+type Storage interface {
+	// Put stores the content of r under key, replacing any previous content
+	Put(key string, r io.ReadSeeker) error
+	// Get returns a reader over the content of key
+	Get(key string) (io.ReadCloser, error)
+	// Exists tells whether key is stored
+	Exists(key string) (bool, error)
+	// Delete removes key
+	Delete(key string) error
+}
@@ -0,0 +1,105 @@
+package storages
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 is a minimal stand-in for an S3-compatible API
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=key/") ||
+		!strings.Contains(auth, "/eu-west-1/s3/aws4_request") {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := ioutil.ReadAll(r.Body)
+		sum := sha256.Sum256(body)
+		if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+			http.Error(w, "XAmzContentSHA256Mismatch", http.StatusBadRequest)
+			return
+		}
+		f.objects[r.URL.Path] = body
+	case http.MethodGet, http.MethodHead:
+		body, ok := f.objects[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(body)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func testStorage(s Storage) {
+	content := []byte("%PDF-1.4 content")
+	err := s.Put("pdf/hep-th/9901001 v1.pdf", bytes.NewReader(content))
+	if err != nil {
+		log.Fatal(err)
+	}
+	exists, err := s.Exists("pdf/hep-th/9901001 v1.pdf")
+	if err != nil || !exists {
+		log.Fatalf("Stored key not found: %v", err)
+	}
+	rc, err := s.Get("pdf/hep-th/9901001 v1.pdf")
+	if err != nil {
+		log.Fatal(err)
+	}
+	stored, _ := ioutil.ReadAll(rc)
+	rc.Close()
+	if !bytes.Equal(stored, content) {
+		log.Fatalf("Wrong content: %s", stored)
+	}
+	err = s.Delete("pdf/hep-th/9901001 v1.pdf")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err = s.Get("pdf/hep-th/9901001 v1.pdf"); err != ErrNotFound {
+		log.Fatalf("Wrong error for a deleted key: %v", err)
+	}
+}
+
+func TestLocal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pandor")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testStorage(NewLocal(dir))
+	if _, err = NewLocal(dir).Path(""); err == nil {
+		log.Fatal("An empty key has been accepted")
+	}
+}
+
+func TestS3(t *testing.T) {
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	testStorage(NewS3(server.URL, "papers", "eu-west-1", "key", "secret"))
+	if _, ok := fake.objects["/papers/pdf/hep-th/9901001 v1.pdf"]; ok {
+		log.Fatal("Deleted object still stored")
+	}
+}
@@ -0,0 +1,229 @@
+package topics
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"pandor/databases"
+	"pandor/embeddings"
+	"pandor/logger"
+	"pandor/models"
+
+	"github.com/dgraph-io/dgo/v2"
+)
+
+// PageSize is the number of articles loaded at once
+var PageSize = 500
+
+// Topics is the default number of topics
+var Topics = 20
+
+// TopTermsCount is the number of terms describing a topic
+var TopTermsCount = 8
+
+// MinWeight is the weight under which a topic is not linked to an article
+var MinWeight = 0.05
+
+// Document is an article of the training
+type Document struct {
+	UID            string
+	ArXivID        string
+	SubmissionDate time.Time
+	Text           string
+	Topics         []float64 // distribution over the topics
+}
+
+// Load returns the articles which have an abstract
+func Load(dg *dgo.Dgraph) ([]Document, error) {
+	type Root struct {
+		Articles []models.Article `json:"articles"`
+	}
+
+	query := `query Topics($first: int, $after: string){
+		articles(func: type(Article), first: $first, after: $after) @filter(has(abstract)){
+			uid
+			arxivid
+			title
+			abstract
+			submissiondate
+		}
+	}`
+	var docs []Document
+	after := "0x0"
+	for {
+		variables := map[string]string{
+			"$first": fmt.Sprintf("%d", PageSize),
+			"$after": after,
+		}
+		resp, err := databases.QueryWithVars(query, variables, dg)
+		if err != nil {
+			return nil, err
+		}
+		var root Root
+		err = json.Unmarshal(resp.Json, &root)
+		if err != nil {
+			return nil, err
+		}
+		if len(root.Articles) == 0 {
+			break
+		}
+		for _, article := range root.Articles {
+			docs = append(docs, Document{
+				UID:            article.UID,
+				ArXivID:        article.ArXivID,
+				SubmissionDate: article.SubmissionDate,
+				Text:           embeddings.Text(article),
+			})
+		}
+		after = root.Articles[len(root.Articles)-1].UID
+	}
+	return docs, nil
+}
+
+// Fit trains a model of k topics on the documents and sets their topic
+// distributions
+func Fit(docs []Document, k int) (*Model, error) {
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		texts[i] = doc.Text
+	}
+	m, distributions, err := Train(texts, k)
+	if err != nil {
+		return nil, err
+	}
+	for i := range docs {
+		docs[i].Topics = distributions[i]
+	}
+	return m, nil
+}
+
+// Report is the prevalence of the topics by month of submission, the mean
+// of the distributions of the articles of the month
+type Report struct {
+	Topics     []string    // top terms of each topic
+	Months     []string    // as 2008-01, in order
+	Articles   []int       // number of articles of each month
+	Prevalence [][]float64 // by month and topic
+}
+
+// NewReport computes the prevalence of the topics of a model in the
+// documents, the ones without a submission date are ignored
+func NewReport(m *Model, docs []Document) *Report {
+	r := &Report{}
+	for t := range m.Topics {
+		r.Topics = append(r.Topics, strings.Join(m.TopTerms(t, TopTermsCount), " "))
+	}
+	sums := make(map[string][]float64)
+	counts := make(map[string]int)
+	for _, doc := range docs {
+		if doc.SubmissionDate.IsZero() || doc.Topics == nil {
+			continue
+		}
+		month := doc.SubmissionDate.UTC().Format("2006-01")
+		if _, ok := sums[month]; !ok {
+			sums[month] = make([]float64, len(m.Topics))
+			r.Months = append(r.Months, month)
+		}
+		counts[month]++
+		for t, p := range doc.Topics {
+			sums[month][t] += p
+		}
+	}
+	sort.Strings(r.Months)
+	for _, month := range r.Months {
+		prevalence := sums[month]
+		for t := range prevalence {
+			prevalence[t] /= float64(counts[month])
+		}
+		r.Articles = append(r.Articles, counts[month])
+		r.Prevalence = append(r.Prevalence, prevalence)
+	}
+	return r
+}
+
+// Records returns the report as CSV records, a month per row and a topic
+// per column
+func (r *Report) Records() [][]string {
+	header := append([]string{"month", "articles"}, r.Topics...)
+	records := [][]string{header}
+	for i, month := range r.Months {
+		record := []string{month, fmt.Sprint(r.Articles[i])}
+		for _, p := range r.Prevalence[i] {
+			record = append(record, fmt.Sprintf("%.4f", p))
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// Links returns the topics of a document weighing at least MinWeight, with
+// the UIDs of the topics
+func Links(doc Document, topicUIDs []string) []models.Topic {
+	var links []models.Topic
+	for t, p := range doc.Topics {
+		if p >= MinWeight {
+			links = append(links, models.Topic{UID: topicUIDs[t], Weight: p})
+		}
+	}
+	return links
+}
+
+// articleTopics sets the topics of an article. A models.Article would also
+// write its zero dates.
+type articleTopics struct {
+	UID    string         `json:"uid"`
+	Topics []models.Topic `json:"topics"`
+}
+
+// topicValues returns the topics of the model and the links of the
+// documents to them, which refer to the topics by blank nodes
+func topicValues(m *Model, docs []Document) []interface{} {
+	values := make([]interface{}, 0, len(m.Topics)+len(docs))
+	topicUIDs := make([]string, len(m.Topics))
+	for t := range m.Topics {
+		topicUIDs[t] = fmt.Sprintf("_:topic%d", t)
+		values = append(values, models.Topic{
+			UID:    topicUIDs[t],
+			Number: t + 1,
+			Terms:  strings.Join(m.TopTerms(t, TopTermsCount), ", "),
+			DType:  []string{"Topic"},
+		})
+	}
+	for _, doc := range docs {
+		if links := Links(doc, topicUIDs); len(links) > 0 {
+			values = append(values, articleTopics{UID: doc.UID, Topics: links})
+		}
+	}
+	return values
+}
+
+// Store replaces the stored topics by the ones of the model and links the
+// documents to their topics, in a single transaction
+func Store(m *Model, docs []Document, dg *dgo.Dgraph) error {
+	_, err := databases.ReplaceType("Topic", []string{"topics"}, topicValues(m, docs), dg)
+	return err
+}
+
+// Run trains k topics on the stored abstracts, stores them and returns the
+// report of their prevalence
+func Run(k int, dg *dgo.Dgraph) (*Report, error) {
+	start := time.Now()
+	docs, err := Load(dg)
+	if err != nil {
+		return nil, err
+	}
+	m, err := Fit(docs, k)
+	if err != nil {
+		return nil, err
+	}
+	err = Store(m, docs, dg)
+	if err != nil {
+		return nil, err
+	}
+	logger.Logger.Info(fmt.Sprintf("Modeled %d topics over %d articles in %v",
+		len(m.Topics), len(docs), time.Since(start).Round(time.Millisecond)))
+	return NewReport(m, docs), nil
+}
@@ -0,0 +1,216 @@
+package topics
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"pandor/embeddings"
+)
+
+// Parameters of the training
+var (
+	// Iterations of the multiplicative updates
+	Iterations = 200
+	// InferIterations of the updates of the topics of a new text
+	InferIterations = 50
+	// Seed of the initial factors, so that the training is reproducible
+	Seed = int64(1)
+)
+
+// epsilon avoids divisions by zero in the updates
+const epsilon = 1e-12
+
+// Model factorizes the TF-IDF matrix of the documents in the product of
+// their topic weights and the term weights of the topics (non-negative
+// matrix factorization)
+type Model struct {
+	Terms  []string
+	Index  map[string]int
+	IDF    []float64
+	Topics [][]float64 // the term weights of each topic
+}
+
+// Train factorizes the documents in k topics with the multiplicative
+// updates of Lee and Seung. It returns the model and the topic
+// distribution of each document.
+func Train(docs []string, k int) (*Model, [][]float64, error) {
+	tokens := make([][]string, len(docs))
+	for i, doc := range docs {
+		tokens[i] = embeddings.Tokenize(doc)
+	}
+	m := &Model{}
+	m.Index, m.IDF = embeddings.Vocabulary(tokens)
+	if len(m.Index) == 0 {
+		return nil, nil, fmt.Errorf("Empty vocabulary with %d documents", len(docs))
+	}
+	if k <= 0 {
+		return nil, nil, fmt.Errorf("Invalid number of topics %d", k)
+	}
+	m.Terms = make([]string, len(m.Index))
+	for t, i := range m.Index {
+		m.Terms[i] = t
+	}
+	rows := make([]embeddings.Sparse, len(docs))
+	total := 0.0
+	for d := range docs {
+		rows[d] = embeddings.TFIDF(tokens[d], m.Index, m.IDF)
+		for _, x := range rows[d].Value {
+			total += x
+		}
+	}
+
+	// Random factors whose product has the mean of the matrix
+	random := rand.New(rand.NewSource(Seed))
+	scale := math.Sqrt(total / float64(len(docs)*len(m.Terms)*k))
+	w := randomMatrix(random, len(docs), k, scale)
+	m.Topics = randomMatrix(random, k, len(m.Terms), scale)
+
+	for it := 0; it < Iterations; it++ {
+		m.updateTopics(rows, w)
+		hht := m.hht()
+		for d := range rows {
+			m.updateWeights(rows[d], w[d], hht)
+		}
+	}
+
+	distributions := make([][]float64, len(docs))
+	for d := range w {
+		distributions[d] = distribution(w[d])
+	}
+	return m, distributions, nil
+}
+
+func randomMatrix(random *rand.Rand, rows, columns int, scale float64) [][]float64 {
+	matrix := make([][]float64, rows)
+	for i := range matrix {
+		matrix[i] = make([]float64, columns)
+		for j := range matrix[i] {
+			matrix[i][j] = scale * (0.5 + random.Float64())
+		}
+	}
+	return matrix
+}
+
+// updateTopics applies H = H * (Wt X) / (Wt W H)
+func (m *Model) updateTopics(rows []embeddings.Sparse, w [][]float64) {
+	k, terms := len(m.Topics), len(m.Terms)
+	numerator := make([][]float64, k)
+	for t := range numerator {
+		numerator[t] = make([]float64, terms)
+	}
+	for d, row := range rows {
+		for j, i := range row.Index {
+			for t := 0; t < k; t++ {
+				numerator[t][i] += w[d][t] * row.Value[j]
+			}
+		}
+	}
+	wtw := make([][]float64, k)
+	for a := range wtw {
+		wtw[a] = make([]float64, k)
+		for _, weights := range w {
+			for b := range wtw[a] {
+				wtw[a][b] += weights[a] * weights[b]
+			}
+		}
+	}
+	for t := 0; t < k; t++ {
+		for i := 0; i < terms; i++ {
+			denominator := 0.0
+			for b := 0; b < k; b++ {
+				denominator += wtw[t][b] * m.Topics[b][i]
+			}
+			m.Topics[t][i] *= numerator[t][i] / (denominator + epsilon)
+		}
+	}
+}
+
+// hht returns H Ht, the products of the topics
+func (m *Model) hht() [][]float64 {
+	k := len(m.Topics)
+	products := make([][]float64, k)
+	for a := range products {
+		products[a] = make([]float64, k)
+		for b := range products[a] {
+			for i, x := range m.Topics[a] {
+				products[a][b] += x * m.Topics[b][i]
+			}
+		}
+	}
+	return products
+}
+
+// updateWeights applies W = W * (X Ht) / (W H Ht) to the topic weights of
+// a document
+func (m *Model) updateWeights(row embeddings.Sparse, weights []float64, hht [][]float64) {
+	k := len(m.Topics)
+	numerator := make([]float64, k)
+	for t := 0; t < k; t++ {
+		for j, i := range row.Index {
+			numerator[t] += row.Value[j] * m.Topics[t][i]
+		}
+	}
+	updated := make([]float64, k)
+	for t := 0; t < k; t++ {
+		denominator := 0.0
+		for b := 0; b < k; b++ {
+			denominator += weights[b] * hht[b][t]
+		}
+		updated[t] = weights[t] * numerator[t] / (denominator + epsilon)
+	}
+	copy(weights, updated)
+}
+
+// distribution scales weights to sum to 1, all zero stays zero
+func distribution(weights []float64) []float64 {
+	sum := 0.0
+	for _, x := range weights {
+		sum += x
+	}
+	p := make([]float64, len(weights))
+	if sum == 0 {
+		return p
+	}
+	for t, x := range weights {
+		p[t] = x / sum
+	}
+	return p
+}
+
+// Infer returns the topic distribution of a new text, with the topics
+// fixed
+func (m *Model) Infer(text string) []float64 {
+	row := embeddings.TFIDF(embeddings.Tokenize(text), m.Index, m.IDF)
+	weights := make([]float64, len(m.Topics))
+	if len(row.Index) == 0 {
+		return weights
+	}
+	for t := range weights {
+		weights[t] = 1 / float64(len(weights))
+	}
+	hht := m.hht()
+	for it := 0; it < InferIterations; it++ {
+		m.updateWeights(row, weights, hht)
+	}
+	return distribution(weights)
+}
+
+// TopTerms returns the n terms weighing most in a topic
+func (m *Model) TopTerms(topic, n int) []string {
+	order := make([]int, len(m.Terms))
+	for i := range order {
+		order[i] = i
+	}
+	weights := m.Topics[topic]
+	sort.SliceStable(order, func(a, b int) bool { return weights[order[a]] > weights[order[b]] })
+	if len(order) > n {
+		order = order[:n]
+	}
+	terms := make([]string, len(order))
+	for i, j := range order {
+		terms[i] = m.Terms[j]
+	}
+	return terms
+}
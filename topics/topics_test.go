@@ -0,0 +1,125 @@
+package topics
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"pandor/models"
+)
+
+// corpus is made of two themes, galaxies in 2008 and qubits in 2009
+func corpus() []Document {
+	galaxies := []string{
+		"Dwarf galaxies orbit the halo of the Milky Way",
+		"Stellar streams trace the accretion of dwarf galaxies in the halo",
+		"The halo of Andromeda hosts faint dwarf galaxies and streams",
+		"Tidal streams of stars from disrupted galaxies",
+		"Star formation in dwarf galaxies of the Local Group",
+		"Stars and dark matter in the halo of galaxies",
+	}
+	qubits := []string{
+		"Superconducting qubits with long coherence times",
+		"Entanglement of trapped ion qubits for quantum computing",
+		"Quantum error correction protects the coherence of qubits",
+		"Quantum gates between superconducting qubits",
+		"Entanglement distillation for quantum networks",
+		"Decoherence of qubits coupled to a quantum bath",
+	}
+	var docs []Document
+	for i, text := range galaxies {
+		docs = append(docs, Document{ArXivID: fmt.Sprintf("g%d", i), Text: text,
+			SubmissionDate: time.Date(2008, time.Month(1+i%2), 10, 0, 0, 0, 0, time.UTC)})
+	}
+	for i, text := range qubits {
+		docs = append(docs, Document{ArXivID: fmt.Sprintf("q%d", i), Text: text,
+			SubmissionDate: time.Date(2009, 3, 10, 0, 0, 0, 0, time.UTC)})
+	}
+	return docs
+}
+
+// dominant returns the topic weighing most in a distribution
+func dominant(distribution []float64) int {
+	best := 0
+	for t, p := range distribution {
+		if p > distribution[best] {
+			best = t
+		}
+	}
+	return best
+}
+
+func TestFit(t *testing.T) {
+	docs := corpus()
+	m, err := Fit(docs, 2)
+	if err != nil {
+		log.Fatal(err)
+	}
+	galaxies, qubits := dominant(docs[0].Topics), dominant(docs[6].Topics)
+	if galaxies == qubits {
+		log.Fatalf("The themes share a topic %v %v", docs[0].Topics, docs[6].Topics)
+	}
+	for _, doc := range docs {
+		expected := galaxies
+		if strings.HasPrefix(doc.ArXivID, "q") {
+			expected = qubits
+		}
+		if dominant(doc.Topics) != expected {
+			log.Fatalf("Wrong topics of %s %v", doc.ArXivID, doc.Topics)
+		}
+		sum := 0.0
+		for _, p := range doc.Topics {
+			sum += p
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			log.Fatalf("The topics of %s sum to %f", doc.ArXivID, sum)
+		}
+	}
+	if terms := strings.Join(m.TopTerms(qubits, 3), " "); !strings.Contains(terms, "qubits") {
+		log.Fatalf("Wrong top terms %s", terms)
+	}
+	if dominant(m.Infer("coherence of superconducting qubits")) != qubits {
+		log.Fatal("A text on qubits should be inferred in their topic")
+	}
+	if p := m.Infer("nothing known"); p[0] != 0 || p[1] != 0 {
+		log.Fatalf("Unknown words have no topic %v", p)
+	}
+	if _, err := Fit(docs, 0); err == nil {
+		log.Fatal("Zero topics should fail")
+	}
+}
+
+func TestReport(t *testing.T) {
+	docs := corpus()
+	m, err := Fit(docs, 2)
+	if err != nil {
+		log.Fatal(err)
+	}
+	docs = append(docs, Document{ArXivID: "undated", Topics: []float64{1, 0}})
+	r := NewReport(m, docs)
+	if strings.Join(r.Months, ",") != "2008-01,2008-02,2009-03" || fmt.Sprint(r.Articles) != "[3 3 6]" {
+		log.Fatalf("Wrong months %v %v", r.Months, r.Articles)
+	}
+	qubits := dominant(docs[6].Topics)
+	if r.Prevalence[2][qubits] < 0.8 || r.Prevalence[0][qubits] > 0.2 {
+		log.Fatalf("Wrong prevalence %v", r.Prevalence)
+	}
+	records := r.Records()
+	if len(records) != 4 || len(records[0]) != 4 || records[0][0] != "month" || records[3][1] != "6" {
+		log.Fatalf("Wrong records %v", records)
+	}
+
+	links := Links(Document{Topics: []float64{0.97, 0.03}}, []string{"0x1", "0x2"})
+	if len(links) != 1 || links[0].UID != "0x1" || links[0].Weight != 0.97 {
+		log.Fatalf("Wrong links %v", links)
+	}
+	// The links refer to the new topics in the same mutation
+	values := topicValues(m, docs[:1])
+	if len(values) != 3 || values[0].(models.Topic).UID != "_:topic0" ||
+		values[2].(articleTopics).Topics[0].UID != fmt.Sprintf("_:topic%d", dominant(docs[0].Topics)) {
+		log.Fatalf("Wrong values %v", values)
+	}
+}
@@ -54,6 +54,9 @@ func BuildDir(dir string) error {
 }
 
 // DownloadAndSaveToDir is an helper function to save the result of a GET Request
+//
+// Deprecated: it neither checks the response nor overwrites existing files,
+// use downloads.Manager instead.
 func DownloadAndSaveToDir(url, file, dir string) error {
 
 	out, err := BuildFile(dir, file)
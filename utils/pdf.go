@@ -1,7 +1,7 @@
 package utils
 
 import (
-	"fmt"
+	"errors"
 
 	"code.sajari.com/docconv"
 )
@@ -9,6 +9,11 @@ import (
 // PDFtoTXT converts a PDF to a string
 func PDFtoTXT(inputPath string) (*docconv.Response, error) {
 	res, err := docconv.ConvertPath(inputPath)
-	fmt.Println(res.Error)
-	return res, err
+	if err != nil {
+		return nil, err
+	}
+	if res.Error != "" {
+		return res, errors.New(res.Error)
+	}
+	return res, nil
 }